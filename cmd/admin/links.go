@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/storage"
+)
+
+// linkRecord is one line of the JSON lines export, a link as exported by GET
+// /url/export plus its owner. Owners are exported by email so that dumps can
+// be imported into another database, workspaces keep their id.
+type linkRecord struct {
+	url.Link
+	Owner string `json:"owner,omitempty"`
+}
+
+func (a *admin) transferLinks(fs *flag.FlagSet, args []string) error {
+	to := fs.String("to", "", "email of the new owner")
+	alias := fs.String("alias", "", "alias to transfer")
+	from := fs.String("from", "", "transfer every link of this user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" || (*alias == "") == (*from == "") {
+		return errors.New("-to and exactly one of -alias or -from are required")
+	}
+	newOwner, err := a.storage.GetUserByEmail(*to)
+	if err != nil {
+		return fmt.Errorf("new owner: %w", err)
+	}
+	if *alias != "" {
+		if err := a.storage.TransferURL(*alias, newOwner.Id); err != nil {
+			return err
+		}
+		a.log.Info("link transferred", "alias", *alias, "to", newOwner.Email)
+		return nil
+	}
+	oldOwner, err := a.storage.GetUserByEmail(*from)
+	if err != nil {
+		return fmt.Errorf("old owner: %w", err)
+	}
+	n, err := a.storage.TransferAllURLs(oldOwner.Id, newOwner.Id)
+	if err != nil {
+		return err
+	}
+	a.log.Info("links transferred", "count", n, "from", oldOwner.Email, "to", newOwner.Email)
+	return nil
+}
+
+func (a *admin) deleteLinks(fs *flag.FlagSet, args []string) error {
+	pattern := fs.String("pattern", "", "GLOB pattern of aliases to delete, e.g. 'promo-*'")
+	dryRun := fs.Bool("dry-run", false, "only list the matching aliases")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pattern == "" {
+		return errors.New("-pattern is required")
+	}
+	if *dryRun {
+		links, err := a.storage.ListURLsByPattern(*pattern)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			fmt.Printf("%s\t%s\n", l.Alias, l.Url)
+		}
+		a.log.Info("dry run, nothing deleted", "matched", len(links))
+		return nil
+	}
+	n, err := a.storage.DeleteURLsByPattern(*pattern)
+	if err != nil {
+		return err
+	}
+	a.log.Info("links deleted", "count", n, "pattern", *pattern)
+	return nil
+}
+
+func (a *admin) exportLinks(fs *flag.FlagSet, args []string) error {
+	file := fs.String("file", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	links, err := a.storage.ListAllURLs()
+	if err != nil {
+		return err
+	}
+	owners := map[int64]string{}
+	enc := json.NewEncoder(w)
+	for _, l := range links {
+		rec := linkRecord{Link: url.NewLink(l)}
+		if l.UserId != 0 {
+			email, ok := owners[l.UserId]
+			if !ok {
+				user, err := a.storage.GetUserByID(l.UserId)
+				if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+					return err
+				}
+				if user != nil {
+					email = user.Email
+				}
+				owners[l.UserId] = email
+			}
+			rec.Owner = email
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	a.log.Info("links exported", "count", len(links))
+	return nil
+}
+
+func (a *admin) importLinks(fs *flag.FlagSet, args []string) error {
+	file := fs.String("file", "-", "input file, - for stdin")
+	skipExisting := fs.Bool("skip-existing", false, "skip aliases that already exist instead of failing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	owners := map[string]int64{}
+	var imported, skipped int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec linkRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := rec.Validate(a.log); err != nil {
+			return fmt.Errorf("line %d: alias %s: %w", line, rec.Alias, err)
+		}
+		hash, err := urlnorm.Hash(rec.URL)
+		if err != nil {
+			return fmt.Errorf("line %d: alias %s: %w", line, rec.Alias, err)
+		}
+		link := rec.Model()
+		link.UrlHash = hash
+		if rec.Owner != "" {
+			id, ok := owners[rec.Owner]
+			if !ok {
+				user, err := a.storage.GetUserByEmail(rec.Owner)
+				if err != nil {
+					return fmt.Errorf("line %d: owner %s: %w", line, rec.Owner, err)
+				}
+				id = user.Id
+				owners[rec.Owner] = id
+			}
+			link.UserId = id
+		}
+		if link.WorkspaceId != 0 {
+			if link.UserId == 0 {
+				return fmt.Errorf("line %d: alias %s: workspace links need an owner", line, rec.Alias)
+			}
+			if _, err := a.storage.WorkspaceRole(link.WorkspaceId, link.UserId); err != nil {
+				return fmt.Errorf("line %d: alias %s: workspace %d: %w", line, rec.Alias, link.WorkspaceId, err)
+			}
+		}
+		_, err = a.storage.SaveURL(link)
+		if errors.Is(err, storage.ErrUrlExists) && *skipExisting {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: alias %s: %w", line, rec.Alias, err)
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	a.log.Info("links imported", "imported", imported, "skipped", skipped)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Operates directly on the storage configured by CONFIG_PATH.
+
+Commands:
+  user-create          -email EMAIL -password PASSWORD [-role ROLE]
+  user-set-role        -email EMAIL -role admin|member|read-only
+  user-disable         -email EMAIL
+  user-enable          -email EMAIL
+  user-reset-password  -email EMAIL [-password PASSWORD]
+  links-transfer       -to EMAIL (-alias ALIAS | -from EMAIL)
+  links-delete         -pattern GLOB [-dry-run]
+  links-export         [-file PATH]
+  links-import         [-file PATH] [-skip-existing]
+`
+
+type admin struct {
+	log       *slog.Logger
+	storage   *sqlite.Storage
+	passwords *password.Hasher
+	policy    *password.Policy
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	a := &admin{
+		log: slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+	commands := map[string]func(*flag.FlagSet, []string) error{
+		"user-create":         a.createUser,
+		"user-disable":        a.disableUser(true),
+		"user-enable":         a.disableUser(false),
+		"user-set-role":       a.setRole,
+		"user-reset-password": a.resetPassword,
+		"links-transfer":      a.transferLinks,
+		"links-delete":        a.deleteLinks,
+		"links-export":        a.exportLinks,
+		"links-import":        a.importLinks,
+	}
+
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.MustLoad()
+	storage, err := sqlite.New(cfg.StoragePath)
+	if err != nil {
+		a.log.Error("failed to init storage", "err", err)
+		os.Exit(1)
+	}
+	a.storage = storage
+	a.passwords, a.policy, err = password.New(cfg.Auth.Password)
+	if err != nil {
+		a.log.Error("failed to init passwords", "err", err)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := cmd(fs, os.Args[2:]); err != nil {
+		a.log.Error("command failed", "command", name, "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"url-shortener/internal/models"
+)
+
+func (a *admin) createUser(fs *flag.FlagSet, args []string) error {
+	email := fs.String("email", "", "email of the new user")
+	password := fs.String("password", "", "password of the new user")
+	role := fs.String("role", models.RoleMember, "role of the new user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *password == "" {
+		return errors.New("-email and -password are required")
+	}
+	if !models.ValidRole(*role) {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+	if err := a.policy.Check(*password); err != nil {
+		return err
+	}
+	hash, err := a.passwords.Hash(*password)
+	if err != nil {
+		return err
+	}
+	id, err := a.storage.SaveUser(models.User{Email: *email, Password: hash, Role: *role, EmailVerified: true})
+	if err != nil {
+		return err
+	}
+	a.log.Info("user created", "id", id, "email", *email, "role", *role)
+	return nil
+}
+
+func (a *admin) disableUser(disabled bool) func(*flag.FlagSet, []string) error {
+	return func(fs *flag.FlagSet, args []string) error {
+		email := fs.String("email", "", "email of the user")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		user, err := a.storage.GetUserByEmail(*email)
+		if err != nil {
+			return err
+		}
+		if err := a.storage.SetUserDisabled(user.Id, disabled); err != nil {
+			return err
+		}
+		a.log.Info("user updated", "id", user.Id, "email", user.Email, "disabled", disabled)
+		return nil
+	}
+}
+
+func (a *admin) setRole(fs *flag.FlagSet, args []string) error {
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "", "new role of the user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !models.ValidRole(*role) {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+	user, err := a.storage.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+	if err := a.storage.SetUserRole(user.Id, *role); err != nil {
+		return err
+	}
+	a.log.Info("user updated", "id", user.Id, "email", user.Email, "role", *role)
+	return nil
+}
+
+func (a *admin) resetPassword(fs *flag.FlagSet, args []string) error {
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "new password, generated and printed when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	user, err := a.storage.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+	generated := *password == ""
+	if generated {
+		*password = rand.Text()
+	} else if err := a.policy.Check(*password); err != nil {
+		return err
+	}
+	hash, err := a.passwords.Hash(*password)
+	if err != nil {
+		return err
+	}
+	if err := a.storage.UpdatePassword(user.Id, hash); err != nil {
+		return err
+	}
+	a.log.Info("password reset", "id", user.Id, "email", user.Email)
+	if generated {
+		fmt.Println(*password)
+	}
+	return nil
+}
@@ -4,46 +4,141 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage/sqlite"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `Usage: migrator [flags] [command]
+
+Commands:
+  up [N]     apply all or the next N pending migrations (default)
+  down N     roll back the last N migrations
+  goto V     migrate up or down to version V
+  force V    set version V without running migrations, clears the dirty flag
+  version    print the current version
+  status     list applied and pending migrations
+
+Defaults are read from the config file in CONFIG_PATH when it is set.
+
+Flags:
+`
+
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
 	var storagePath, migrationsPath, migrationsTable string
+	var verbose, dryRun bool
 
-	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
-	flag.StringVar(&migrationsPath, "migrations-path", "", "path to migrations")
-	flag.StringVar(&migrationsTable, "migrations-table", "migrations", "name of migrations table")
-	flag.Parse()
+	defaults := config.Config{Migrations: config.Migrations{Table: "migrations"}}
+	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to load config:", err)
+			return exitError
+		}
+		defaults = *cfg
+	}
+
+	fs := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&storagePath, "storage-path", defaults.StoragePath, "path to storage")
+	fs.StringVar(&migrationsPath, "migrations-path", defaults.Migrations.Path, "path to migrations, the embedded ones when empty")
+	fs.StringVar(&migrationsTable, "migrations-table", defaults.Migrations.Table, "name of migrations table")
+	fs.BoolVar(&verbose, "verbose", false, "log every migration step")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the migrations that would run without applying them")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
 
 	if storagePath == "" {
-		panic("storage-path is required")
+		fmt.Fprintln(os.Stderr, "storage-path is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	command, arg := "up", ""
+	if fs.NArg() > 0 {
+		command = fs.Arg(0)
 	}
-	if migrationsPath == "" {
-		panic("migrations-path is required")
+	if fs.NArg() > 1 {
+		arg = fs.Arg(1)
+	}
+	if fs.NArg() > 2 {
+		fs.Usage()
+		return exitUsage
 	}
 
-	m, err := migrate.New(
-		"file://"+migrationsPath,
+	openSource := sqlite.EmbeddedSource
+	if migrationsPath != "" {
+		openSource = func() (source.Driver, error) {
+			return source.Open("file://" + migrationsPath)
+		}
+	}
+	src, err := openSource()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open migrations:", err)
+		return exitError
+	}
+	m, err := migrate.NewWithSourceInstance(
+		"migrations",
+		src,
 		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", storagePath, migrationsTable),
 	)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "failed to init migrations:", err)
+		return exitError
 	}
+	defer m.Close()
+	m.Log = &Log{verbose: verbose}
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("no migrations to apply")
-
-			return
+	p := &planner{m: m, openSource: openSource}
+	if err := p.execute(command, arg, dryRun); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintln(os.Stderr, err)
+			fs.Usage()
+			return exitUsage
 		}
-
-		panic(err)
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitError
 	}
+	return exitOK
+}
+
+type usageError string
 
-	fmt.Println("migrations applied")
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func parseArg(command, arg string, optional bool) (int, error) {
+	if arg == "" && optional {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return 0, usageError(fmt.Sprintf("%s needs a non-negative number, got %q", command, arg))
+	}
+	return n, nil
 }
 
 // Log represents the logger
@@ -53,10 +148,13 @@ type Log struct {
 
 // Printf prints out formatted string into a log
 func (l *Log) Printf(format string, v ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
 	fmt.Printf(format, v...)
 }
 
 // Verbose shows if verbose print enabled
 func (l *Log) Verbose() bool {
-	return false
+	return l.verbose
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+type migration struct {
+	version uint
+	name    string
+}
+
+type planner struct {
+	m          *migrate.Migrate
+	openSource func() (source.Driver, error)
+}
+
+func (p *planner) execute(command, arg string, dryRun bool) error {
+	switch command {
+	case "up":
+		n, err := parseArg(command, arg, true)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return p.printPlan(func(current uint, all []migration) []migration {
+				pending := after(current, all)
+				if n > 0 && n < len(pending) {
+					pending = pending[:n]
+				}
+				return pending
+			}, "up")
+		}
+		if n > 0 {
+			return p.report(p.m.Steps(n), "migrations applied")
+		}
+		return p.report(p.m.Up(), "migrations applied")
+	case "down":
+		n, err := parseArg(command, arg, false)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return usageError("down needs the number of migrations to roll back")
+		}
+		if dryRun {
+			return p.printPlan(func(current uint, all []migration) []migration {
+				applied := reversed(upTo(current, all))
+				if n < len(applied) {
+					applied = applied[:n]
+				}
+				return applied
+			}, "down")
+		}
+		return p.report(p.m.Steps(-n), "migrations rolled back")
+	case "goto":
+		v, err := parseArg(command, arg, false)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return p.printGoto(uint(v))
+		}
+		if v == 0 {
+			// there is no migration with version 0 to migrate to, roll back everything instead
+			return p.report(p.m.Down(), "migrations rolled back")
+		}
+		return p.report(p.m.Migrate(uint(v)), fmt.Sprintf("migrated to version %d", v))
+	case "force":
+		v, err := parseArg(command, arg, false)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Printf("would force version %d\n", v)
+			return nil
+		}
+		return p.report(p.m.Force(v), fmt.Sprintf("forced version %d", v))
+	case "version":
+		version, dirty, err := p.m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Print(version)
+		if dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Println()
+		return nil
+	case "status":
+		return p.printStatus()
+	default:
+		return usageError(fmt.Sprintf("unknown command %q", command))
+	}
+}
+
+func (p *planner) report(err error, done string) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(done)
+	return nil
+}
+
+// current returns the applied version, 0 when nothing has been applied.
+func (p *planner) current() (uint, bool, error) {
+	version, dirty, err := p.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (p *planner) migrations() ([]migration, error) {
+	src, err := p.openSource()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var all []migration
+	version, err := src.First()
+	for err == nil {
+		name := ""
+		if r, identifier, readErr := src.ReadUp(version); readErr == nil {
+			r.Close()
+			name = identifier
+		}
+		all = append(all, migration{version: version, name: name})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].version < all[j].version })
+	return all, nil
+}
+
+func (p *planner) printStatus() error {
+	current, dirty, err := p.current()
+	if err != nil {
+		return err
+	}
+	all, err := p.migrations()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE")
+	for _, mg := range all {
+		state := "pending"
+		if mg.version <= current {
+			state = "applied"
+		}
+		if mg.version == current && dirty {
+			state = "dirty"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", mg.version, mg.name, state)
+	}
+	return tw.Flush()
+}
+
+func (p *planner) printPlan(pick func(current uint, all []migration) []migration, direction string) error {
+	current, dirty, err := p.current()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and use force", current)
+	}
+	all, err := p.migrations()
+	if err != nil {
+		return err
+	}
+	planned := pick(current, all)
+	if len(planned) == 0 {
+		fmt.Println("no migrations to apply")
+		return nil
+	}
+	for _, mg := range planned {
+		fmt.Printf("would apply %s %d %s\n", direction, mg.version, mg.name)
+	}
+	return nil
+}
+
+func (p *planner) printGoto(target uint) error {
+	current, _, err := p.current()
+	if err != nil {
+		return err
+	}
+	if target >= current {
+		return p.printPlan(func(current uint, all []migration) []migration {
+			return upTo(target, after(current, all))
+		}, "up")
+	}
+	return p.printPlan(func(current uint, all []migration) []migration {
+		return reversed(after(target, upTo(current, all)))
+	}, "down")
+}
+
+func after(version uint, all []migration) []migration {
+	var res []migration
+	for _, mg := range all {
+		if mg.version > version {
+			res = append(res, mg)
+		}
+	}
+	return res
+}
+
+func upTo(version uint, all []migration) []migration {
+	var res []migration
+	for _, mg := range all {
+		if mg.version <= version {
+			res = append(res, mg)
+		}
+	}
+	return res
+}
+
+func reversed(all []migration) []migration {
+	res := make([]migration, len(all))
+	for i, mg := range all {
+		res[len(all)-1-i] = mg
+	}
+	return res
+}
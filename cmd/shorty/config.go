@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// credentials are persisted between invocations so that only login asks for
+// a password.
+type credentials struct {
+	Server string `json:"server"`
+	Email  string `json:"email"`
+	Token  string `json:"token"`
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".shorty.json"
+	}
+	return filepath.Join(home, ".shorty", "config.json")
+}
+
+func loadCredentials(path string) (*credentials, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &credentials{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var creds credentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+func saveCredentials(path string, creds *credentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
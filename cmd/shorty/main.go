@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/pkg/client"
+)
+
+const defaultServer = "http://localhost:8080"
+
+const usage = `Usage: shorty [flags] <command> [args]
+
+Commands:
+  login -email EMAIL [-password PASSWORD]
+  shorten <url> [-alias ALIAS]
+  rm <alias>
+  ls
+  stats <alias>
+  resolve <alias>
+
+Flags:
+`
+
+type app struct {
+	configPath string
+	creds      *credentials
+	client     *client.Client
+	out        printer
+	stdin      io.Reader
+	stderr     io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("shorty", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	var server, output, configPath string
+	fs.StringVar(&server, "server", os.Getenv("SHORTY_SERVER"), "base URL of the shortener (default "+defaultServer+")")
+	fs.StringVar(&output, "o", outputTable, "output format: table or json")
+	fs.StringVar(&configPath, "config", defaultConfigPath(), "path to the credentials file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (output != outputTable && output != outputJSON) {
+		fs.Usage()
+		return 2
+	}
+
+	creds, err := loadCredentials(configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "failed to read config:", err)
+		return 1
+	}
+	if server == "" {
+		server = creds.Server
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	creds.Server = server
+
+	a := &app{
+		configPath: configPath,
+		creds:      creds,
+		client:     client.New(server, client.WithToken(creds.Token)),
+		out:        printer{w: stdout, format: output},
+		stdin:      stdin,
+		stderr:     stderr,
+	}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	commands := map[string]func(context.Context, []string) error{
+		"login":   a.login,
+		"shorten": a.shorten,
+		"rm":      a.remove,
+		"ls":      a.list,
+		"stats":   a.stats,
+		"resolve": a.resolve,
+	}
+	handler, ok := commands[cmd]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", cmd)
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := handler(ctx, cmdArgs); err != nil {
+		if errors.Is(err, client.ErrUnauthorized) {
+			err = fmt.Errorf("%w, run `shorty login` first", err)
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := fs.String("email", a.creds.Email, "account email")
+	password := fs.String("password", "", "account password, read from stdin when empty")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+	if *password == "" {
+		var err error
+		if *password, err = a.readPassword(); err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+	}
+	if err := a.client.Login(ctx, *email, *password); err != nil {
+		return err
+	}
+	a.creds.Email = *email
+	a.creds.Token = a.client.Token()
+	if err := saveCredentials(a.configPath, a.creds); err != nil {
+		return fmt.Errorf("save credentials: %w", err)
+	}
+	return a.out.print(map[string]string{"email": *email, "server": a.creds.Server},
+		nil, [][]string{{"logged in as " + *email}})
+}
+
+// readPassword prompts for the password on stderr. Terminals do not echo
+// it, piped input is read up to the first newline.
+func (a *app) readPassword() (string, error) {
+	fmt.Fprint(a.stderr, "Password: ")
+	if f, ok := a.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(a.stderr)
+		return string(password), err
+	}
+	line, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (a *app) shorten(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	alias := fs.String("alias", "", "custom alias")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: shorten <url> [-alias ALIAS]")
+	}
+	saved, err := a.client.Shorten(ctx, positional[0], *alias)
+	if err != nil {
+		return err
+	}
+	shortURL := strings.TrimSuffix(a.creds.Server, "/") + "/" + saved
+	return a.out.print(map[string]string{"alias": saved, "short_url": shortURL},
+		nil, [][]string{{shortURL}})
+}
+
+func (a *app) remove(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: rm <alias>")
+	}
+	if err := a.client.Delete(ctx, args[0]); err != nil {
+		return err
+	}
+	return a.out.print(map[string]string{"deleted": args[0]}, nil, [][]string{{"deleted " + args[0]}})
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: ls")
+	}
+	links, err := a.client.List(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(links))
+	for _, l := range links {
+		rows = append(rows, []string{l.Alias, l.URL, strconv.FormatInt(l.Clicks, 10), l.CreatedAt.Format(time.RFC3339)})
+	}
+	return a.out.print(links, []string{"ALIAS", "URL", "CLICKS", "CREATED"}, rows)
+}
+
+func (a *app) stats(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: stats <alias>")
+	}
+	link, err := a.client.Stats(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(link, nil, [][]string{
+		{"alias", link.Alias},
+		{"url", link.URL},
+		{"clicks", strconv.FormatInt(link.Clicks, 10)},
+		{"created", link.CreatedAt.Format(time.RFC3339)},
+	})
+}
+
+func (a *app) resolve(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: resolve <alias>")
+	}
+	target, err := a.client.Resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(map[string]string{"alias": args[0], "url": target}, nil, [][]string{{target}})
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
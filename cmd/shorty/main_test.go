@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"url-shortener/internal/config"
+	http_server "url-shortener/internal/http-server"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/storage/sqlite"
+	"url-shortener/pkg/client"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+
+	repo, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	cfg := &config.Config{JwtSecret: "test-secret"}
+	handler, err := http_server.New(slog.New(custom_mocks.NewMockLogger()), cfg, repo)
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	srv := newTestServer(t)
+	require.NoError(t, client.New(srv.URL).Register(context.Background(), "user@example.com", "correct horse"))
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	shorty := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-server", srv.URL, "-config", configPath}, args...)
+		code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, _, stderr := shorty("", "ls")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "run `shorty login` first")
+
+	code, stdout, stderr := shorty("correct horse\n", "login", "-email", "user@example.com")
+	require.Equal(t, 0, code, stderr)
+	require.Contains(t, stderr, "Password: ")
+	require.Contains(t, stdout, "logged in as user@example.com")
+	creds, err := loadCredentials(configPath)
+	require.NoError(t, err)
+	require.NotEmpty(t, creds.Token)
+
+	code, stdout, stderr = shorty("", "shorten", "https://example.com/page", "-alias", "example")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, srv.URL+"/example\n", stdout)
+
+	code, stdout, stderr = shorty("", "resolve", "example")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, "https://example.com/page\n", stdout)
+
+	code, stdout, stderr = shorty("", "-o", "json", "ls")
+	require.Equal(t, 0, code, stderr)
+	var links []struct {
+		Alias string `json:"alias"`
+		URL   string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &links))
+	require.Len(t, links, 1)
+	require.Equal(t, "example", links[0].Alias)
+
+	code, stdout, stderr = shorty("", "stats", "example")
+	require.Equal(t, 0, code, stderr)
+	require.Contains(t, stdout, "clicks")
+
+	code, stdout, stderr = shorty("", "rm", "example")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, "deleted example\n", stdout)
+
+	code, _, stderr = shorty("", "rm", "example")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "error:")
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "No command", args: nil, want: "Usage: shorty"},
+		{name: "Unknown command", args: []string{"frobnicate"}, want: `unknown command "frobnicate"`},
+		{name: "Unknown output", args: []string{"-o", "yaml", "ls"}, want: "Usage: shorty"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-config", filepath.Join(t.TempDir(), "config.json")}, tc.args...)
+			require.Equal(t, 2, run(args, strings.NewReader(""), &stdout, &stderr))
+			require.Contains(t, stderr.String(), tc.want)
+			require.Empty(t, stdout.String())
+		})
+	}
+}
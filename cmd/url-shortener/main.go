@@ -23,6 +23,18 @@ func main() {
 	log.Info("Starting URL Shortener", slog.String("env", cfg.Env), slog.String("addr", cfg.Addr))
 	log.Debug("debug messages are enabled")
 
+	if cfg.AutoMigrate {
+		if err := sqlite.Migrate(cfg.StoragePath, cfg.Migrations.Table); err != nil {
+			log.Error("failed to apply migrations", "err", err)
+			os.Exit(1)
+		}
+		log.Info("migrations applied")
+	}
+	if err := sqlite.CheckSchema(cfg.StoragePath, cfg.Migrations.Table); err != nil {
+		log.Error("database schema not supported", "err", err)
+		os.Exit(1)
+	}
+
 	storage, err := sqlite.New(cfg.StoragePath)
 	if err != nil {
 		log.Error("failed to init storage", "err", err)
@@ -31,7 +43,11 @@ func main() {
 
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, syscall.SIGINT, syscall.SIGTERM)
-	srv := http_server.New(log, cfg, storage)
+	srv, err := http_server.New(log, cfg, storage)
+	if err != nil {
+		log.Error("failed to init server", "err", err)
+		os.Exit(1)
+	}
 	go func() {
 		if err = srv.Run(); err != nil {
 			log.Error("failed to start server", "err", err)
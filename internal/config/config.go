@@ -10,14 +10,216 @@ import (
 type Config struct {
 	Env         string `yaml:"env" env-default:"local"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
-	JwtSecret   string `yaml:"jwt_secret" env-required:"true"`
-	HTTPServer  `yaml:"http_server" env-required:"true"`
+	// JwtSecret signs tokens with HS256 unless JWT has keys. The service
+	// does not start without one of them.
+	JwtSecret   string `yaml:"jwt_secret" env:"JWT_SECRET"`
+	AutoMigrate bool   `yaml:"auto_migrate" env-default:"false"`
+	// DedupeURLs makes POST /url return the caller's existing alias for an
+	// already shortened URL unless the request says otherwise.
+	DedupeURLs bool `yaml:"dedupe_urls" env-default:"false"`
+	// GeoIPPath is a CSV country database used by country redirect rules,
+	// see geoip.Load. Country rules never match without it.
+	GeoIPPath  string `yaml:"geoip_path"`
+	HTTPServer `yaml:"http_server" env-required:"true"`
+	Migrations Migrations `yaml:"migrations"`
+	Alias      Alias      `yaml:"alias"`
+	Preview    Preview    `yaml:"preview"`
+	Metadata   Metadata   `yaml:"metadata"`
+	LinkCheck  LinkCheck  `yaml:"link_check"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	JWT        JWT        `yaml:"jwt"`
+	Auth       Auth       `yaml:"auth"`
+	Mail       Mail       `yaml:"mail"`
+}
+
+// JWT configures asymmetric signing of tokens, which other services can
+// verify with the public keys served at /.well-known/jwks.json.
+type JWT struct {
+	// Keys are PEM files of RSA or Ed25519 keys. Tokens are signed with the
+	// private key SigningKey and verified with any of the keys, public keys
+	// only verify. To rotate, add the new key, make it the signing key once
+	// other services fetched it, and remove the old one once its last token
+	// expired.
+	Keys       []JWTKey `yaml:"keys"`
+	SigningKey string   `yaml:"signing_key"`
+}
+
+type JWTKey struct {
+	// ID is the kid of the tokens signed with the key.
+	ID   string `yaml:"id"`
+	Path string `yaml:"path"`
+}
+
+// Auth configures the account flows.
+type Auth struct {
+	// RequireVerifiedEmail blocks creating links until the email of the
+	// account is verified.
+	RequireVerifiedEmail bool          `yaml:"require_verified_email" env-default:"false"`
+	VerifyTTL            time.Duration `yaml:"verify_ttl" env-default:"48h"`
+	ResetTTL             time.Duration `yaml:"reset_ttl" env-default:"1h"`
+	// BaseURL is the public URL of the service used in links sent by email.
+	BaseURL string `yaml:"base_url" env-default:"http://localhost:8080"`
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer string   `yaml:"totp_issuer" env-default:"url-shortener"`
+	Password   Password `yaml:"password"`
+	Lockout    Lockout  `yaml:"lockout"`
+	OIDC       OIDC     `yaml:"oidc"`
+}
+
+// OIDC configures single sign-on with an OpenID Connect provider. Users are
+// created on their first login.
+type OIDC struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// Issuer is the URL of the provider.
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL is the callback URL registered with the provider,
+	// BaseURL/auth/oidc/callback when empty.
+	RedirectURL string   `yaml:"redirect_url"`
+	Scopes      []string `yaml:"scopes" env-default:"openid,email,profile"`
+	// AllowedDomains restricts logins to emails of these domains, any domain
+	// is allowed when empty.
+	AllowedDomains []string `yaml:"allowed_domains"`
+}
+
+// Lockout configures the protection of logins against password guessing.
+type Lockout struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// DelayAfter failures of an account make further attempts wait Delay
+	// after the last failure, doubling with every failure up to MaxDelay.
+	DelayAfter int           `yaml:"delay_after" env-default:"3"`
+	Delay      time.Duration `yaml:"delay" env-default:"1s"`
+	MaxDelay   time.Duration `yaml:"max_delay" env-default:"1m"`
+	// LockAfter failures lock the account for LockDuration, IPLockAfter
+	// failures from one IP lock out the IP.
+	LockAfter    int           `yaml:"lock_after" env-default:"10"`
+	LockDuration time.Duration `yaml:"lock_duration" env-default:"15m"`
+	IPLockAfter  int           `yaml:"ip_lock_after" env-default:"50"`
+	// Window is how long failures are remembered.
+	Window time.Duration `yaml:"window" env-default:"1h"`
+}
+
+// Password configures the password policy and how passwords are hashed.
+type Password struct {
+	// MinLength is in characters, MaxLength in bytes. bcrypt does not accept
+	// more than 72 bytes.
+	MinLength     int  `yaml:"min_length" env-default:"10"`
+	MaxLength     int  `yaml:"max_length" env-default:"72"`
+	RequireUpper  bool `yaml:"require_upper" env-default:"false"`
+	RequireLower  bool `yaml:"require_lower" env-default:"false"`
+	RequireDigit  bool `yaml:"require_digit" env-default:"false"`
+	RequireSymbol bool `yaml:"require_symbol" env-default:"false"`
+	// CommonList is a file of common or breached passwords, one per line,
+	// that are rejected.
+	CommonList string `yaml:"common_list"`
+	// Hash is bcrypt or argon2id. Stored hashes made with another algorithm
+	// or other parameters are replaced when their user logs in.
+	Hash       string `yaml:"hash" env-default:"bcrypt"`
+	BcryptCost int    `yaml:"bcrypt_cost" env-default:"12"`
+	Argon2     Argon2 `yaml:"argon2"`
+}
+
+type Argon2 struct {
+	Time uint32 `yaml:"time" env-default:"3"`
+	// Memory is in KiB.
+	Memory  uint32 `yaml:"memory" env-default:"65536"`
+	Threads uint8  `yaml:"threads" env-default:"2"`
+}
+
+// Mail configures how emails are sent.
+type Mail struct {
+	// Driver is smtp, file or log. file writes .eml files into Dir and log
+	// writes the messages to the log, both are meant for local testing.
+	Driver string `yaml:"driver" env-default:"log"`
+	From   string `yaml:"from" env-default:"no-reply@localhost"`
+	Dir    string `yaml:"dir" env-default:"./storage/mail"`
+	SMTP   SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string        `yaml:"host" env-default:"localhost"`
+	Port     int           `yaml:"port" env-default:"587"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"30s"`
+}
+
+// Webhooks configures the delivery of link events to user endpoints.
+type Webhooks struct {
+	// Enabled publishes events. Webhooks can be managed while disabled.
+	Enabled      bool          `yaml:"enabled" env-default:"true"`
+	Workers      int           `yaml:"workers" env-default:"4"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"8"`
+	// Backoff is the delay after the first failure, it doubles with every
+	// further failure up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff" env-default:"30s"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"6h"`
+	// ClickThresholds are the click counts that send link.clicks.
+	ClickThresholds []int64 `yaml:"click_thresholds" env-default:"100,1000,10000"`
+}
+
+// LinkCheck configures the periodic dead-link checker.
+type LinkCheck struct {
+	Enabled     bool          `yaml:"enabled" env-default:"false"`
+	Interval    time.Duration `yaml:"interval" env-default:"24h"`
+	Concurrency int           `yaml:"concurrency" env-default:"4"`
+	// HostDelay is the minimum time between two requests to the same host.
+	HostDelay time.Duration `yaml:"host_delay" env-default:"1s"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"10s"`
+	// BrokenAfter is the number of failed checks in a row after which a link
+	// is listed by GET /url?broken=true.
+	BrokenAfter int `yaml:"broken_after" env-default:"3"`
+}
+
+// Metadata configures fetching titles and images of link destinations.
+type Metadata struct {
+	Enabled   bool          `yaml:"enabled" env-default:"true"`
+	Workers   int           `yaml:"workers" env-default:"2"`
+	QueueSize int           `yaml:"queue_size" env-default:"1000"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBytes  int64         `yaml:"max_bytes" env-default:"1048576"`
+}
+
+// Preview configures the pages shown instead of plain redirects.
+type Preview struct {
+	// Always shows the preview page for every link, not only for links
+	// created with preview or requested with /{alias}+ or ?preview=1.
+	Always bool `yaml:"always"`
+	// Interstitial warns before redirecting to hosts outside InternalDomains.
+	Interstitial bool `yaml:"interstitial"`
+	// InternalDomains are trusted hosts, subdomains included.
+	InternalDomains []string `yaml:"internal_domains"`
+}
+
+// Alias selects how aliases are generated when the client does not pick one.
+type Alias struct {
+	// Generator is one of random, sequential, hashids or words.
+	Generator     string `yaml:"generator" env-default:"random"`
+	Length        int    `yaml:"length" env-default:"8"`
+	Words         int    `yaml:"words" env-default:"3"`
+	Salt          string `yaml:"salt"`
+	MaxAttempts   int    `yaml:"max_attempts" env-default:"10"`
+	EscalateAfter int    `yaml:"escalate_after" env-default:"3"`
+}
+
+// Migrations configures the migrator. An empty Path uses the migrations
+// embedded into the binary.
+type Migrations struct {
+	Path  string `yaml:"path"`
+	Table string `yaml:"table" env-default:"migrations"`
 }
 
 type HTTPServer struct {
 	Addr        string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// TrustedProxies are the CIDRs or addresses of the reverse proxies
+	// whose X-Forwarded-For and X-Real-IP headers are believed. The socket
+	// address is the client address of every other request.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 func MustLoad() *Config {
@@ -29,9 +231,18 @@ func MustLoad() *Config {
 		log.Fatalf("config file does not exist: %s", configPath)
 	}
 
+	config, err := Load(configPath)
+	if err != nil {
+		log.Fatal("Error loading config: ", err)
+	}
+	return config
+}
+
+// Load reads the config file at configPath without exiting on errors.
+func Load(configPath string) (*Config, error) {
 	var config Config
 	if err := cleanenv.ReadConfig(configPath, &config); err != nil {
-		log.Fatal("Error loading config: ", err)
+		return nil, err
 	}
-	return &config
+	return &config, nil
 }
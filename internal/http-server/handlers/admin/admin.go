@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type User struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+type UsersResponse struct {
+	resp.Response
+	Users []User `json:"users"`
+}
+
+type UserResponse struct {
+	resp.Response
+	User User `json:"user"`
+}
+
+// UpdateUserRequest changes the fields that are set.
+type UpdateUserRequest struct {
+	Role     *string `json:"role,omitempty" validate:"omitempty,oneof=admin member read-only"`
+	Disabled *bool   `json:"disabled,omitempty"`
+}
+
+// Link is a link of any user.
+type Link struct {
+	Alias     string     `json:"alias"`
+	URL       string     `json:"url"`
+	UserID    int64      `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Clicks    int64      `json:"clicks"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type LinksResponse struct {
+	resp.Response
+	Links []Link `json:"links"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=UserLister
+type UserLister interface {
+	ListUsers() ([]models.User, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=UserUpdater
+type UserUpdater interface {
+	GetUserByID(id int64) (*models.User, error)
+	SetUserRole(userId int64, role string) error
+	SetUserDisabled(userId int64, disabled bool) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=LinkLister
+type LinkLister interface {
+	ListAllURLs() ([]models.UrlShortener, error)
+	ListURLs(userId int64) ([]models.UrlShortener, error)
+}
+
+func UsersHandler(log *slog.Logger, lister UserLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		users, err := lister.ListUsers()
+		if err != nil {
+			log.Error("failed to list users", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		res := make([]User, 0, len(users))
+		for _, u := range users {
+			res = append(res, newUser(u))
+		}
+		render.JSON(w, r, UsersResponse{Response: resp.OK(), Users: res})
+	}
+}
+
+// UpdateUserHandler changes the role of an account or disables it. Admins
+// cannot change their own account so that they do not lock themselves out.
+// Disabled users cannot log in, and tokens issued before are refused along
+// with them; a new role applies to those tokens as well.
+func UpdateUserHandler(log *slog.Logger, updater UserUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			resp.RenderError(w, r, http.StatusNotFound, "user not found")
+			return
+		}
+		var req UpdateUserRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", "err", err)
+			resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			resp.RenderValidationError(w, r, custom_validators.ValidationError(validateErr))
+			return
+		}
+		if id == claims.Id {
+			resp.RenderError(w, r, http.StatusBadRequest, "cannot change your own account")
+			return
+		}
+
+		user, err := updater.GetUserByID(id)
+		if err == nil && req.Role != nil {
+			err = updater.SetUserRole(id, *req.Role)
+			user.Role = *req.Role
+		}
+		if err == nil && req.Disabled != nil {
+			err = updater.SetUserDisabled(id, *req.Disabled)
+			user.Disabled = *req.Disabled
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			resp.RenderError(w, r, http.StatusNotFound, "user not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to update user", "user_id", id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("user updated", "user_id", id, "role", user.Role, "disabled", user.Disabled, "by", claims.Id)
+		render.JSON(w, r, UserResponse{Response: resp.OK(), User: newUser(*user)})
+	}
+}
+
+// LinksHandler lists the links of all users, or of the user given by the
+// user_id query parameter.
+func LinksHandler(log *slog.Logger, lister LinkLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		var links []models.UrlShortener
+		var err error
+		if v := r.URL.Query().Get("user_id"); v != "" {
+			userId, parseErr := strconv.ParseInt(v, 10, 64)
+			if parseErr != nil {
+				resp.RenderError(w, r, http.StatusBadRequest, "bad user_id")
+				return
+			}
+			links, err = lister.ListURLs(userId)
+		} else {
+			links, err = lister.ListAllURLs()
+		}
+		if err != nil {
+			log.Error("failed to list urls", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		res := make([]Link, 0, len(links))
+		for _, l := range links {
+			link := Link{Alias: l.Alias, URL: l.Url, UserID: l.UserId, CreatedAt: l.CreatedAt, Clicks: l.Clicks}
+			if !l.ExpiresAt.IsZero() {
+				link.ExpiresAt = &l.ExpiresAt
+			}
+			res = append(res, link)
+		}
+		render.JSON(w, r, LinksResponse{Response: resp.OK(), Links: res})
+	}
+}
+
+func newUser(u models.User) User {
+	role := u.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	return User{ID: u.Id, Email: u.Email, Role: role, Disabled: u.Disabled}
+}
@@ -0,0 +1,208 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/http-server/handlers/admin"
+	"url-shortener/internal/http-server/handlers/admin/mocks"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestUsersHandler(t *testing.T) {
+	listerMock := mocks.NewUserLister(t)
+	listerMock.On("ListUsers").Return([]models.User{
+		{Id: 1, Email: "admin@example.com", Password: []byte("hash"), Role: models.RoleAdmin},
+		{Id: 2, Email: "user@example.com", Password: []byte("hash"), Disabled: true},
+	}, nil).Once()
+
+	handler := admin.UsersHandler(slog.New(custom_mocks.NewMockLogger()), listerMock)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotContains(t, w.Body.String(), "password")
+
+	var resp admin.UsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, []admin.User{
+		{ID: 1, Email: "admin@example.com", Role: models.RoleAdmin},
+		{ID: 2, Email: "user@example.com", Role: models.RoleMember, Disabled: true},
+	}, resp.Users)
+}
+
+func TestUpdateUserHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		body      string
+		user      *models.User
+		mockError error
+		role      string
+		disabled  *bool
+		want      admin.User
+		respError string
+		respCode  int
+	}{
+		{
+			name:     "Change role",
+			id:       "2",
+			body:     `{"role": "read-only"}`,
+			user:     &models.User{Id: 2, Email: "user@example.com", Role: models.RoleMember},
+			role:     models.RoleReadOnly,
+			want:     admin.User{ID: 2, Email: "user@example.com", Role: models.RoleReadOnly},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Disable account",
+			id:       "2",
+			body:     `{"disabled": true}`,
+			user:     &models.User{Id: 2, Email: "user@example.com", Role: models.RoleMember},
+			disabled: boolPtr(true),
+			want:     admin.User{ID: 2, Email: "user@example.com", Role: models.RoleMember, Disabled: true},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Unknown role",
+			id:        "2",
+			body:      `{"role": "owner"}`,
+			respError: "field Role is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Own account",
+			id:        "1",
+			body:      `{"disabled": true}`,
+			respError: "cannot change your own account",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Unknown user",
+			id:        "9",
+			body:      `{"disabled": true}`,
+			mockError: storage.ErrUserNotFound,
+			respError: "user not found",
+			respCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			updaterMock := mocks.NewUserUpdater(t)
+			if tc.user != nil || tc.mockError != nil {
+				id, _ := strconv.ParseInt(tc.id, 10, 64)
+				updaterMock.On("GetUserByID", id).Return(tc.user, tc.mockError).Once()
+			}
+			if tc.role != "" {
+				updaterMock.On("SetUserRole", int64(2), tc.role).Return(nil).Once()
+			}
+			if tc.disabled != nil {
+				updaterMock.On("SetUserDisabled", int64(2), *tc.disabled).Return(nil).Once()
+			}
+
+			handler := admin.UpdateUserHandler(slog.New(custom_mocks.NewMockLogger()), updaterMock)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/admin/users/"+tc.id, bytes.NewReader([]byte(tc.body)))
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("id", tc.id)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+			ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1, Role: models.RoleAdmin})
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, w.Code)
+
+			var resp admin.UserResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.want, resp.User)
+			}
+		})
+	}
+}
+
+func TestLinksHandler(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	links := []models.UrlShortener{
+		{Alias: "docs", Url: "https://example.com/docs", UserId: 2, CreatedAt: created, Clicks: 4},
+		{Alias: "sale", Url: "https://example.com/sale", UserId: 3, CreatedAt: created, ExpiresAt: created.Add(time.Hour)},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		mock      func(*mocks.LinkLister)
+		want      []string
+		respError string
+		respCode  int
+	}{
+		{
+			name:     "All links",
+			mock:     func(m *mocks.LinkLister) { m.On("ListAllURLs").Return(links, nil).Once() },
+			want:     []string{"docs", "sale"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Links of one user",
+			query:    "?user_id=2",
+			mock:     func(m *mocks.LinkLister) { m.On("ListURLs", int64(2)).Return(links[:1], nil).Once() },
+			want:     []string{"docs"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Bad user id",
+			query:     "?user_id=two",
+			mock:      func(m *mocks.LinkLister) {},
+			respError: "bad user_id",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			listerMock := mocks.NewLinkLister(t)
+			tc.mock(listerMock)
+			handler := admin.LinksHandler(slog.New(custom_mocks.NewMockLogger()), listerMock)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/links"+tc.query, nil))
+
+			require.Equal(t, tc.respCode, w.Code)
+
+			var resp admin.LinksResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			var aliases []string
+			for _, l := range resp.Links {
+				aliases = append(aliases, l.Alias)
+			}
+			require.Equal(t, tc.want, aliases)
+		})
+	}
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -5,12 +5,16 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
-	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 	resp "url-shortener/internal/lib/api/response"
 	custom_validators "url-shortener/internal/lib/custom-validators"
 	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/lib/password"
 	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
@@ -20,29 +24,39 @@ type Request struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// Response carries an access token, or for accounts with 2FA a challenge
+// token for POST /login/2fa.
 type Response struct {
-	Token string `json:"token"`
+	Token          string `json:"token,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
 }
 
 type UserRepo interface {
 	SaveUser(user models.User) (int64, error)
 	GetUserByEmail(email string) (*models.User, error)
+	UpdatePassword(userId int64, password []byte) error
 }
 
-func RegisterHandler(log *slog.Logger, repo UserRepo) http.HandlerFunc {
+// RegisterHandler creates an account with an unverified email and, unless
+// emails is nil, mails a verification link. The password must satisfy policy.
+func RegisterHandler(log *slog.Logger, repo UserRepo, tokens jwt_helper.TokenIssuer, passwords *password.Hasher, policy *password.Policy, emails *Emails) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		reqId := middleware.GetReqID(r.Context())
-		log = log.With("request_id", reqId)
+		log := log.With("request_id", reqId)
 
 		req, err := validateRequest(r, log)
 		if req == nil {
-			render.JSON(w, r, resp.Error(err.Error()))
+			resp.RenderValidationError(w, r, err)
 			return
 		}
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.MinCost)
+		if err := policy.Check(req.Password); err != nil {
+			resp.RenderError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		hashedPassword, err := passwords.Hash(req.Password)
 		if err != nil {
 			log.Error("failed to hash password", "err", err)
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
 			return
 		}
 		user := models.User{
@@ -53,59 +67,150 @@ func RegisterHandler(log *slog.Logger, repo UserRepo) http.HandlerFunc {
 		if err != nil {
 			log.Error("error while saving user", "err", err)
 			if errors.Is(err, storage.ErrUserExists) {
-				render.JSON(w, r, resp.Error("user already exists"))
+				resp.RenderError(w, r, http.StatusConflict, "user already exists")
 				return
 			}
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
 			return
 		}
 		log.Info("user registered successfully", "user", user)
 		user.Id = uid
-		token, err := jwt_helper.NewToken(user)
+		if emails != nil {
+			// the account works without verification, the link can be resent
+			if err := emails.SendVerification(r.Context(), user); err != nil {
+				log.Error("failed to send verification email", "user_id", uid, "err", err)
+			}
+		}
+		token, err := tokens.NewToken(user)
 		if err != nil {
 			log.Error("failed to generate token", "err", err)
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
 		}
 		render.JSON(w, r, Response{Token: token})
 	}
 }
 
-func LoginHandler(log *slog.Logger, repo UserRepo) http.HandlerFunc {
+// LoginRepo looks up accounts and records login attempts.
+type LoginRepo interface {
+	UserRepo
+	SaveLoginEvent(event models.LoginEvent) error
+}
+
+// LoginHandler issues a token for valid credentials, or a challenge token
+// for TwoFactorLoginHandler if the account has 2FA. Passwords hashed with
+// outdated parameters are hashed again with the current ones. Every attempt
+// is recorded, and unless guard is nil attempts after too many failures are
+// refused with 429 until the delay or lockout is over.
+func LoginHandler(log *slog.Logger, repo LoginRepo, tokens jwt_helper.TokenIssuer, passwords *password.Hasher, guard *lockout.Guard) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		reqId := middleware.GetReqID(r.Context())
-		log = log.With("request_id", reqId)
+		log := log.With("request_id", reqId)
 
 		req, err := validateRequest(r, log)
 		if req == nil {
-			render.JSON(w, r, resp.Error(err.Error()))
+			resp.RenderValidationError(w, r, err)
 			return
 		}
+		event := newLoginEvent(r, req.Email)
+		record := func(userId int64, result string) {
+			event.UserId, event.Result = userId, result
+			if err := repo.SaveLoginEvent(event); err != nil {
+				log.Error("failed to record login", "err", err)
+			}
+		}
+
+		if guard != nil {
+			wait, locked, err := guard.Wait(req.Email, event.IP, event.CreatedAt)
+			if err != nil {
+				log.Error("failed to count failed logins", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if wait > 0 {
+				log.Info("login throttled", "ip", event.IP, "wait", wait, "locked", locked)
+				// shown to the owner of the account in GET /me/sessions
+				var userId int64
+				if user, err := repo.GetUserByEmail(req.Email); err == nil {
+					userId = user.Id
+				}
+				record(userId, models.LoginThrottled)
+				renderThrottled(w, r, wait, locked)
+				return
+			}
+		}
+
 		user, err := repo.GetUserByEmail(req.Email)
 		if err != nil {
 			log.Error("error while getting user by email", "err", err)
 			if errors.Is(err, storage.ErrUserNotFound) {
-				render.JSON(w, r, resp.Error("bad credentials"))
+				record(0, models.LoginBadCredentials)
+				resp.RenderError(w, r, http.StatusUnauthorized, "bad credentials")
 				return
 			}
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
 			return
 		}
-		if err = bcrypt.CompareHashAndPassword(user.Password, []byte(req.Password)); err != nil {
+		if err = passwords.Verify(user.Password, req.Password); err != nil {
 			log.Info("bad credentials", "err", err)
-			render.JSON(w, r, resp.Error("bad credentials"))
+			record(user.Id, models.LoginBadCredentials)
+			resp.RenderError(w, r, http.StatusUnauthorized, "bad credentials")
+			return
+		}
+		if user.Disabled {
+			log.Info("login to disabled account", "user_id", user.Id)
+			record(user.Id, models.LoginDisabled)
+			resp.RenderError(w, r, http.StatusForbidden, "account disabled")
 			return
 		}
+		if passwords.NeedsRehash(user.Password) {
+			// the login goes on with the old hash
+			hash, err := passwords.Hash(req.Password)
+			if err == nil {
+				err = repo.UpdatePassword(user.Id, hash)
+			}
+			if err != nil {
+				log.Error("failed to rehash password", "user_id", user.Id, "err", err)
+			} else {
+				log.Info("password rehashed", "user_id", user.Id)
+			}
+		}
 
-		token, err := jwt_helper.NewToken(*user)
+		if user.TOTPEnabled {
+			challenge, err := tokens.NewChallengeToken(*user)
+			if err != nil {
+				log.Error("failed to generate challenge token", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			record(user.Id, models.LoginTwoFactor)
+			log.Info("2fa required", "user_id", user.Id)
+			render.JSON(w, r, Response{ChallengeToken: challenge})
+			return
+		}
+
+		token, err := tokens.NewToken(*user)
 		if err != nil {
 			log.Error("failed to generate token", "err", err)
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
 		}
-		log.Info("user login successfully", "user", user)
+		record(user.Id, models.LoginSuccess)
+		log.Info("user login successfully", "user_id", user.Id)
 		render.JSON(w, r, Response{Token: token})
 	}
 }
 
+// renderThrottled refuses a login attempt that has to wait.
+func renderThrottled(w http.ResponseWriter, r *http.Request, wait time.Duration, locked bool) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	if locked {
+		resp.RenderError(w, r, http.StatusTooManyRequests, "too many failed logins, temporarily locked")
+		return
+	}
+	resp.RenderError(w, r, http.StatusTooManyRequests, "too many failed logins, retry later")
+}
+
 func validateRequest(r *http.Request, log *slog.Logger) (*Request, error) {
 	var req Request
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
@@ -0,0 +1,249 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/http-server/handlers/auth"
+	"url-shortener/internal/http-server/handlers/auth/mocks"
+	"url-shortener/internal/http-server/middleware"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// memRepo keeps one user, its recovery code hashes and the login events in
+// memory.
+type memRepo struct {
+	user     *models.User
+	updates  int
+	events   []models.LoginEvent
+	recovery map[string]bool
+}
+
+func (r *memRepo) SaveLoginEvent(event models.LoginEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memRepo) LoginFailures(email string, since time.Time) (models.LoginFailures, error) {
+	var failures models.LoginFailures
+	for _, e := range r.events {
+		switch {
+		case e.Email != email:
+		case e.Result == models.LoginSuccess:
+			failures = models.LoginFailures{}
+		case e.Result == models.LoginBadCredentials && e.CreatedAt.After(since):
+			failures.Count++
+			failures.Last = e.CreatedAt
+		}
+	}
+	return failures, nil
+}
+
+func (r *memRepo) IPLoginFailures(ip string, since time.Time) (models.LoginFailures, error) {
+	var failures models.LoginFailures
+	for _, e := range r.events {
+		if e.IP == ip && e.Result == models.LoginBadCredentials && e.CreatedAt.After(since) {
+			failures.Count++
+			failures.Last = e.CreatedAt
+		}
+	}
+	return failures, nil
+}
+
+func (r *memRepo) results() []string {
+	var results []string
+	for _, e := range r.events {
+		results = append(results, e.Result)
+	}
+	return results
+}
+
+func (r *memRepo) SaveUser(user models.User) (int64, error) {
+	if r.user != nil {
+		return 0, storage.ErrUserExists
+	}
+	user.Id = 1
+	r.user = &user
+	return user.Id, nil
+}
+
+func (r *memRepo) GetUserByEmail(email string) (*models.User, error) {
+	if r.user == nil || r.user.Email != email {
+		return nil, storage.ErrUserNotFound
+	}
+	user := *r.user
+	return &user, nil
+}
+
+func (r *memRepo) UpdatePassword(userId int64, password []byte) error {
+	r.user.Password = password
+	r.updates++
+	return nil
+}
+
+func TestRegisterPolicy(t *testing.T) {
+	tokens := newTokens(t)
+	repo := &memRepo{}
+	handler := auth.RegisterHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, newHasher(t),
+		&password.Policy{RequireDigit: true, Common: map[string]struct{}{"password123": {}}}, nil)
+
+	cases := []struct {
+		name     string
+		password string
+		code     int
+	}{
+		{name: "too short", password: "secret1", code: http.StatusBadRequest},
+		{name: "no digit", password: "correct horse", code: http.StatusBadRequest},
+		{name: "common", password: "Password123", code: http.StatusBadRequest},
+		{name: "good", password: "correct horse 1", code: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := post(handler, "/register", `{"email": "user@example.com", "password": "`+tc.password+`"}`)
+			require.Equal(t, tc.code, w.Code, w.Body.String())
+		})
+	}
+	require.NoError(t, bcrypt.CompareHashAndPassword(repo.user.Password, []byte("correct horse 1")))
+}
+
+func TestLoginRehash(t *testing.T) {
+	tokens := newTokens(t)
+	old, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo := &memRepo{user: &models.User{Id: 1, Email: "user@example.com", Password: old}}
+
+	argon, err := password.NewHasher(password.Options{
+		Algorithm:     password.Argon2id,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	})
+	require.NoError(t, err)
+	handler := auth.LoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, argon, nil)
+
+	w := post(handler, "/login", `{"email": "user@example.com", "password": "wrong"}`)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, 0, repo.updates)
+
+	w = post(handler, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, 1, repo.updates)
+	require.False(t, argon.NeedsRehash(repo.user.Password))
+	require.NoError(t, argon.Verify(repo.user.Password, "correct horse"))
+
+	// the new hash is current, so the next login keeps it
+	w = post(handler, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, 1, repo.updates)
+}
+
+func TestLoginThrottled(t *testing.T) {
+	tokens := newTokens(t)
+	hasher := newHasher(t)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	repo := &memRepo{user: &models.User{Id: 1, Email: "user@example.com", Password: hash}}
+	guard := lockout.New(repo, lockout.Options{DelayAfter: 2, Delay: time.Hour, MaxDelay: time.Hour, LockAfter: 5})
+	handler := auth.LoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, hasher, guard)
+
+	for range 2 {
+		w := post(handler, "/login", `{"email": "user@example.com", "password": "wrong"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	w := post(handler, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusTooManyRequests, w.Code, "the right password waits as well")
+	require.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	w = post(handler, "/login", `{"email": "nobody@example.com", "password": "wrong"}`)
+	require.Equal(t, http.StatusUnauthorized, w.Code, "other accounts are not throttled")
+
+	require.Equal(t, []string{
+		models.LoginBadCredentials,
+		models.LoginBadCredentials,
+		models.LoginThrottled,
+		models.LoginBadCredentials,
+	}, repo.results())
+	require.Equal(t, int64(1), repo.events[2].UserId)
+	require.Zero(t, repo.events[3].UserId)
+	require.Equal(t, "192.0.2.1", repo.events[0].IP)
+}
+
+func TestLoginIPLockIgnoresForwardedFor(t *testing.T) {
+	tokens := newTokens(t)
+	hasher := newHasher(t)
+	repo := &memRepo{}
+	guard := lockout.New(repo, lockout.Options{DelayAfter: 100, LockAfter: 100, IPLockAfter: 2, LockDuration: time.Hour})
+	login := auth.LoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, hasher, guard)
+	handler := middleware.NewRealIPMW(nil)(login)
+
+	for i, forwarded := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email": "user@example.com", "password": "wrong"}`))
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if i < 2 {
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+			continue
+		}
+		require.Equal(t, http.StatusTooManyRequests, w.Code, "a spoofed address must not escape the ip lock")
+	}
+	for _, e := range repo.events {
+		require.Equal(t, "192.0.2.1", e.IP)
+	}
+}
+
+func TestLoginRecordsSuccess(t *testing.T) {
+	tokens := newTokens(t)
+	hasher := newHasher(t)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	repo := &memRepo{user: &models.User{Id: 1, Email: "user@example.com", Password: hash, Disabled: true}}
+	handler := auth.LoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, hasher, lockout.New(repo, lockout.Options{}))
+
+	w := post(handler, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	repo.user.Disabled = false
+	w = post(handler, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, []string{models.LoginDisabled, models.LoginSuccess}, repo.results())
+}
+
+func TestSessionsHandler(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	listerMock := mocks.NewLoginLister(t)
+	listerMock.On("ListLoginEvents", int64(7), auth.SessionsLimit).Return([]models.LoginEvent{
+		{Id: 2, UserId: 7, IP: "192.0.2.1", UserAgent: "curl/8.0", Result: models.LoginSuccess, CreatedAt: created},
+		{Id: 1, UserId: 7, IP: "192.0.2.2", Result: models.LoginBadCredentials, CreatedAt: created.Add(-time.Minute)},
+	}, nil).Once()
+
+	handler := auth.SessionsHandler(slog.New(custom_mocks.NewMockLogger()), listerMock)
+
+	req := httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+	req = req.WithContext(jwt_helper.WithClaims(req.Context(), &jwt_helper.UserClaims{Id: 7}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res auth.SessionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, []auth.Session{
+		{IP: "192.0.2.1", UserAgent: "curl/8.0", Result: models.LoginSuccess, CreatedAt: created},
+		{IP: "192.0.2.2", Result: models.LoginBadCredentials, CreatedAt: created.Add(-time.Minute)},
+	}, res.Sessions)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/sessions", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/mailer"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const (
+	DefaultVerifyTTL = 48 * time.Hour
+	DefaultResetTTL  = time.Hour
+)
+
+type ForgotRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type TokenSaver interface {
+	SaveUserToken(userId int64, purpose, tokenHash string, expiresAt time.Time) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=EmailVerifier
+type EmailVerifier interface {
+	ConsumeUserToken(purpose, tokenHash string, now time.Time) (int64, error)
+	SetEmailVerified(userId int64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=PasswordResetter
+type PasswordResetter interface {
+	ConsumeUserToken(purpose, tokenHash string, now time.Time) (int64, error)
+	UpdatePassword(userId int64, password []byte) error
+	SetEmailVerified(userId int64) error
+}
+
+type UserGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// Emails sends single-use tokens for email verification and password resets.
+// Only hashes of the tokens are stored.
+type Emails struct {
+	Mailer mailer.Mailer
+	Tokens TokenSaver
+	// BaseURL is the public URL of the service, used in verification links.
+	BaseURL string
+	// VerifyTTL and ResetTTL default to DefaultVerifyTTL and DefaultResetTTL.
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+}
+
+// SendVerification mails user a link that verifies their email.
+func (e *Emails) SendVerification(ctx context.Context, user models.User) error {
+	token, err := e.newToken(user.Id, models.TokenVerifyEmail, ttlOr(e.VerifyTTL, DefaultVerifyTTL))
+	if err != nil {
+		return err
+	}
+	link := strings.TrimSuffix(e.BaseURL, "/") + "/email/verify?token=" + url.QueryEscape(token)
+	return e.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Open the link below to verify your email address:\n\n%s\n\n"+
+			"The link expires in %s.\n", link, ttlOr(e.VerifyTTL, DefaultVerifyTTL)),
+	})
+}
+
+// SendReset mails user a token for POST /password/reset.
+func (e *Emails) SendReset(ctx context.Context, user models.User) error {
+	token, err := e.newToken(user.Id, models.TokenResetPassword, ttlOr(e.ResetTTL, DefaultResetTTL))
+	if err != nil {
+		return err
+	}
+	return e.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. Send this token with "+
+			"your new password to POST /password/reset:\n\n%s\n\nThe token expires in %s. "+
+			"Ignore this email if you did not ask for a reset.\n", token, ttlOr(e.ResetTTL, DefaultResetTTL)),
+	})
+}
+
+func (e *Emails) newToken(userId int64, purpose string, ttl time.Duration) (string, error) {
+	token := random.NewRandomString(32)
+	if err := e.Tokens.SaveUserToken(userId, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// VerifyEmailHandler verifies the email of the user a verification link was
+// sent to.
+func VerifyEmailHandler(log *slog.Logger, verifier EmailVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		userId, err := verifier.ConsumeUserToken(models.TokenVerifyEmail, hashToken(r.URL.Query().Get("token")), time.Now())
+		if err == nil {
+			err = verifier.SetEmailVerified(userId)
+		}
+		if errors.Is(err, storage.ErrTokenNotFound) || errors.Is(err, storage.ErrUserNotFound) {
+			resp.RenderError(w, r, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		if err != nil {
+			log.Error("failed to verify email", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("email verified", "user_id", userId)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// ResendVerificationHandler sends the caller a new verification link,
+// invalidating the previous one.
+func ResendVerificationHandler(log *slog.Logger, users UserGetter, emails *Emails) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		user, err := users.GetUserByID(claims.Id)
+		if err != nil {
+			log.Error("failed to get user", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if user.EmailVerified {
+			resp.RenderError(w, r, http.StatusBadRequest, "email already verified")
+			return
+		}
+		if err := emails.SendVerification(r.Context(), *user); err != nil {
+			log.Error("failed to send verification email", "user_id", user.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// ForgotPasswordHandler mails a password reset token to the account with the
+// given email. It answers the same whether the account exists or not.
+func ForgotPasswordHandler(log *slog.Logger, repo UserRepo, emails *Emails) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		var req ForgotRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+		user, err := repo.GetUserByEmail(req.Email)
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			log.Info("password reset for unknown email")
+		case err != nil:
+			log.Error("failed to get user", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		case user.Disabled:
+			log.Info("password reset for disabled account", "user_id", user.Id)
+		default:
+			if err := emails.SendReset(r.Context(), *user); err != nil {
+				log.Error("failed to send password reset email", "user_id", user.Id, "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			log.Info("password reset sent", "user_id", user.Id)
+		}
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// ResetPasswordHandler sets a new password with a token sent by
+// ForgotPasswordHandler. Receiving the token proves the email, so the email
+// is verified as well. The new password must satisfy policy.
+func ResetPasswordHandler(log *slog.Logger, resetter PasswordResetter, passwords *password.Hasher, policy *password.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		var req ResetRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+		if err := policy.Check(req.Password); err != nil {
+			resp.RenderError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		userId, err := resetter.ConsumeUserToken(models.TokenResetPassword, hashToken(req.Token), time.Now())
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			resp.RenderError(w, r, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		if err != nil {
+			log.Error("failed to consume reset token", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		hash, err := passwords.Hash(req.Password)
+		if err == nil {
+			err = resetter.UpdatePassword(userId, hash)
+		}
+		if err == nil {
+			err = resetter.SetEmailVerified(userId)
+		}
+		if err != nil {
+			log.Error("failed to reset password", "user_id", userId, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("password reset", "user_id", userId)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		log.Error("failed to decode request body", "err", err)
+		resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
+		return false
+	}
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		errors.As(err, &validateErr)
+		resp.RenderValidationError(w, r, custom_validators.ValidationError(validateErr))
+		return false
+	}
+	return true
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func ttlOr(ttl, def time.Duration) time.Duration {
+	if ttl <= 0 {
+		return def
+	}
+	return ttl
+}
@@ -0,0 +1,198 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/http-server/handlers/auth"
+	"url-shortener/internal/http-server/handlers/auth/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/mailer"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// outbox records sent messages.
+type outbox struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (o *outbox) Send(_ context.Context, msg mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// tokens stores token hashes in memory.
+type tokens struct {
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func (t *tokens) SaveUserToken(userId int64, purpose, tokenHash string, expiresAt time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hashes[purpose] = tokenHash
+	return nil
+}
+
+// userRepo serves one user by email.
+type userRepo struct {
+	user *models.User
+}
+
+func (r userRepo) SaveUser(models.User) (int64, error) {
+	return 1, nil
+}
+
+func (r userRepo) UpdatePassword(int64, []byte) error {
+	return nil
+}
+
+func (r userRepo) GetUserByEmail(email string) (*models.User, error) {
+	if r.user == nil || r.user.Email != email {
+		return nil, storage.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func newHasher(t *testing.T) *password.Hasher {
+	hasher, err := password.NewHasher(password.Options{BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+	return hasher
+}
+
+func newTokens(t *testing.T) *jwt_helper.Issuer {
+	tokens, err := jwt_helper.NewHMAC("test-secret")
+	require.NoError(t, err)
+	return tokens
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func post(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, bytes.NewReader([]byte(body))))
+	return w
+}
+
+func TestRegisterSendsVerification(t *testing.T) {
+	box := &outbox{}
+	store := &tokens{hashes: map[string]string{}}
+	emails := &auth.Emails{Mailer: box, Tokens: store, BaseURL: "https://sho.rt/"}
+
+	handler := auth.RegisterHandler(slog.New(custom_mocks.NewMockLogger()), userRepo{}, newTokens(t), newHasher(t), nil, emails)
+	w := post(handler, "/register", `{"email": "user@example.com", "password": "secret"}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, box.messages, 1)
+	require.Equal(t, "user@example.com", box.messages[0].To)
+	link := regexp.MustCompile(`https://sho\.rt/email/verify\?token=(\w+)`).FindStringSubmatch(box.messages[0].Body)
+	require.NotNil(t, link, box.messages[0].Body)
+	require.Equal(t, hash(link[1]), store.hashes[models.TokenVerifyEmail], "only the hash is stored")
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	verifierMock := mocks.NewEmailVerifier(t)
+	verifierMock.On("ConsumeUserToken", models.TokenVerifyEmail, hash("good"), mock.AnythingOfType("time.Time")).Return(int64(7), nil).Once()
+	verifierMock.On("SetEmailVerified", int64(7)).Return(nil).Once()
+	verifierMock.On("ConsumeUserToken", models.TokenVerifyEmail, hash("used"), mock.AnythingOfType("time.Time")).
+		Return(int64(0), storage.ErrTokenNotFound).Once()
+
+	handler := auth.VerifyEmailHandler(slog.New(custom_mocks.NewMockLogger()), verifierMock)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/email/verify?token=good", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/email/verify?token=used", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var res resp.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, "invalid or expired token", res.Error)
+}
+
+func TestForgotPasswordHandler(t *testing.T) {
+	cases := []struct {
+		name  string
+		email string
+		user  *models.User
+		sent  bool
+	}{
+		{name: "Known email", email: "user@example.com", user: &models.User{Id: 1, Email: "user@example.com"}, sent: true},
+		{name: "Unknown email", email: "nobody@example.com"},
+		{name: "Disabled account", email: "user@example.com", user: &models.User{Id: 1, Email: "user@example.com", Disabled: true}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			box := &outbox{}
+			store := &tokens{hashes: map[string]string{}}
+			emails := &auth.Emails{Mailer: box, Tokens: store}
+			handler := auth.ForgotPasswordHandler(slog.New(custom_mocks.NewMockLogger()), userRepo{user: tc.user}, emails)
+
+			w := post(handler, "/password/forgot", `{"email": "`+tc.email+`"}`)
+
+			require.Equal(t, http.StatusOK, w.Code, "the answer does not reveal accounts")
+			if !tc.sent {
+				require.Empty(t, box.messages)
+				return
+			}
+			require.Len(t, box.messages, 1)
+			token := regexp.MustCompile(`(?m)^(\w{32})$`).FindString(box.messages[0].Body)
+			require.Equal(t, hash(token), store.hashes[models.TokenResetPassword])
+		})
+	}
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	resetterMock := mocks.NewPasswordResetter(t)
+	resetterMock.On("ConsumeUserToken", models.TokenResetPassword, hash("good"), mock.AnythingOfType("time.Time")).Return(int64(7), nil).Once()
+	var saved []byte
+	resetterMock.On("UpdatePassword", int64(7), mock.AnythingOfType("[]uint8")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).([]byte) }).
+		Return(nil).Once()
+	resetterMock.On("SetEmailVerified", int64(7)).Return(nil).Once()
+	resetterMock.On("ConsumeUserToken", models.TokenResetPassword, hash("expired"), mock.AnythingOfType("time.Time")).
+		Return(int64(0), storage.ErrTokenNotFound).Once()
+
+	handler := auth.ResetPasswordHandler(slog.New(custom_mocks.NewMockLogger()), resetterMock, newHasher(t), &password.Policy{})
+
+	w := post(handler, "/password/reset", `{"token": "good", "password": "new secret"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, bcrypt.CompareHashAndPassword(saved, []byte("new secret")))
+
+	w = post(handler, "/password/reset", `{"token": "good", "password": "short"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(handler, "/password/reset", `{"token": "expired", "password": "new secret"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(handler, "/password/reset", `{"token": "good"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
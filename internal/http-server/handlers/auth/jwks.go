@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"github.com/go-chi/render"
+	"net/http"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+)
+
+// jwksMaxAge is how long clients may cache the key set. A new signing key
+// should be published at least this long before it signs.
+const jwksMaxAge = "max-age=300"
+
+type KeySet interface {
+	JWKS() jwt_helper.JWKS
+}
+
+// JWKSHandler serves the public keys tokens are signed with, so that other
+// services can verify them.
+func JWKSHandler(keys KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", jwksMaxAge)
+		render.JSON(w, r, keys.JWKS())
+	}
+}
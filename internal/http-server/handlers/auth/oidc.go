@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/oidc"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/models"
+)
+
+// SSOLoginTTL is the time left to come back from the identity provider.
+const SSOLoginTTL = 10 * time.Minute
+
+// ssoCookie keeps the state, nonce and PKCE verifier of a login at the
+// identity provider until the callback.
+const ssoCookie = "oidc_login"
+
+// SSO configures logins through an OpenID Connect provider.
+type SSO struct {
+	Provider *oidc.Provider
+	// AllowedDomains restricts logins to emails of these domains, any
+	// domain is allowed when empty.
+	AllowedDomains []string
+	// SecureCookie marks the login cookie as HTTPS only.
+	SecureCookie bool
+}
+
+type SSORepo interface {
+	ProvisionSSOUser(issuer, subject, email string) (*models.User, error)
+	SaveLoginEvent(event models.LoginEvent) error
+}
+
+// allowed reports whether email belongs to one of the allowed domains.
+func (s *SSO) allowed(email string) bool {
+	if len(s.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	for _, domain := range s.AllowedDomains {
+		if strings.EqualFold(email[at+1:], strings.TrimPrefix(domain, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SSO) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		Secure:   s.SecureCookie,
+		HttpOnly: true,
+		// the callback is a top-level navigation from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCLoginHandler redirects to the identity provider of sso. It answers
+// 404 when single sign-on is not configured.
+func OIDCLoginHandler(log *slog.Logger, sso *SSO) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		if sso == nil {
+			resp.RenderError(w, r, http.StatusNotFound, "single sign-on not enabled")
+			return
+		}
+		state, nonce, verifier := random.NewRandomString(32), random.NewRandomString(32), random.NewRandomString(64)
+		authURL, err := sso.Provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+		if err != nil {
+			log.Error("failed to reach identity provider", "err", err)
+			resp.RenderError(w, r, http.StatusBadGateway, "identity provider unavailable")
+			return
+		}
+		sso.setCookie(w, state+"."+nonce+"."+verifier, int(SSOLoginTTL.Seconds()))
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler finishes a login started by OIDCLoginHandler. The
+// account at the provider is linked to the user with its email, who is
+// created if needed, and an access token is issued like by LoginHandler.
+// Only emails verified by the provider are accepted.
+func OIDCCallbackHandler(log *slog.Logger, repo SSORepo, tokens jwt_helper.TokenIssuer, sso *SSO) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		if sso == nil {
+			resp.RenderError(w, r, http.StatusNotFound, "single sign-on not enabled")
+			return
+		}
+		query := r.URL.Query()
+		cookie, err := r.Cookie(ssoCookie)
+		if err != nil {
+			resp.RenderError(w, r, http.StatusBadRequest, "sso login expired, start again")
+			return
+		}
+		sso.setCookie(w, "", -1)
+		state, nonce, verifier, ok := parseSSOCookie(cookie.Value)
+		if !ok || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+			resp.RenderError(w, r, http.StatusBadRequest, "invalid sso state")
+			return
+		}
+		if e := query.Get("error"); e != "" {
+			log.Info("sso login refused by provider", "error", e, "description", query.Get("error_description"))
+			resp.RenderError(w, r, http.StatusUnauthorized, "sso login refused")
+			return
+		}
+		if query.Get("code") == "" {
+			resp.RenderError(w, r, http.StatusBadRequest, "missing code")
+			return
+		}
+
+		claims, err := sso.Provider.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+		if err != nil {
+			if errors.Is(err, oidc.ErrExchange) || errors.Is(err, oidc.ErrInvalidIDToken) {
+				log.Info("sso login failed", "err", err)
+				resp.RenderError(w, r, http.StatusUnauthorized, "sso login failed")
+				return
+			}
+			log.Error("failed to reach identity provider", "err", err)
+			resp.RenderError(w, r, http.StatusBadGateway, "identity provider unavailable")
+			return
+		}
+		if claims.Email == "" || !claims.EmailVerified {
+			log.Info("sso login without verified email", "subject", claims.Subject)
+			resp.RenderError(w, r, http.StatusForbidden, "email not verified by identity provider")
+			return
+		}
+		if !sso.allowed(claims.Email) {
+			log.Info("sso login of other domain", "email", claims.Email)
+			resp.RenderError(w, r, http.StatusForbidden, "email domain not allowed")
+			return
+		}
+
+		user, err := repo.ProvisionSSOUser(claims.Issuer, claims.Subject, claims.Email)
+		if err != nil {
+			log.Error("failed to provision sso user", "subject", claims.Subject, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		event := newLoginEvent(r, user.Email)
+		event.UserId = user.Id
+		record := func(result string) {
+			event.Result = result
+			if err := repo.SaveLoginEvent(event); err != nil {
+				log.Error("failed to record login", "err", err)
+			}
+		}
+		if user.Disabled {
+			log.Info("sso login to disabled account", "user_id", user.Id)
+			record(models.LoginDisabled)
+			resp.RenderError(w, r, http.StatusForbidden, "account disabled")
+			return
+		}
+
+		if user.TOTPEnabled {
+			challenge, err := tokens.NewChallengeToken(*user)
+			if err != nil {
+				log.Error("failed to generate challenge token", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			record(models.LoginTwoFactor)
+			log.Info("2fa required", "user_id", user.Id)
+			render.JSON(w, r, Response{ChallengeToken: challenge})
+			return
+		}
+
+		token, err := tokens.NewToken(*user)
+		if err != nil {
+			log.Error("failed to generate token", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		record(models.LoginSuccess)
+		log.Info("user login successfully via sso", "user_id", user.Id)
+		render.JSON(w, r, Response{Token: token})
+	}
+}
+
+func parseSSOCookie(value string) (state, nonce, verifier string, ok bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
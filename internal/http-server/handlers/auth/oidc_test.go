@@ -0,0 +1,185 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"url-shortener/internal/http-server/handlers/auth"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/lib/oidc"
+	"url-shortener/internal/lib/oidc/oidctest"
+	"url-shortener/internal/models"
+)
+
+// ssoRepo keeps users by email and the login events in memory.
+type ssoRepo struct {
+	users  map[string]*models.User
+	events []models.LoginEvent
+}
+
+func (r *ssoRepo) ProvisionSSOUser(issuer, subject, email string) (*models.User, error) {
+	user, ok := r.users[email]
+	if !ok {
+		user = &models.User{Id: int64(len(r.users) + 1), Email: email}
+		r.users[email] = user
+	}
+	user.EmailVerified = true
+	provisioned := *user
+	return &provisioned, nil
+}
+
+func (r *ssoRepo) SaveLoginEvent(event models.LoginEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestOIDCLogin(t *testing.T) {
+	tokens := newTokens(t)
+	server := oidctest.NewServer(t)
+	sso := &auth.SSO{
+		Provider: oidc.New(oidc.Options{
+			Issuer:       server.URL,
+			ClientID:     oidctest.ClientID,
+			ClientSecret: oidctest.ClientSecret,
+			RedirectURL:  "http://localhost/auth/oidc/callback",
+		}),
+		AllowedDomains: []string{"example.com"},
+	}
+	repo := &ssoRepo{users: map[string]*models.User{
+		"disabled@example.com": {Id: 100, Email: "disabled@example.com", Disabled: true},
+		"2fa@example.com":      {Id: 101, Email: "2fa@example.com", TOTPEnabled: true},
+	}}
+	log := slog.New(custom_mocks.NewMockLogger())
+	login := auth.OIDCLoginHandler(log, sso)
+	callback := auth.OIDCCallbackHandler(log, repo, tokens, sso)
+
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// start logs in at the provider and returns the callback request.
+	start := func(user oidctest.User) *http.Request {
+		server.SetUser(user)
+		w := httptest.NewRecorder()
+		login.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.True(t, cookies[0].HttpOnly)
+
+		res, err := noRedirects.Get(w.Header().Get("Location"))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		req := httptest.NewRequest(http.MethodGet, res.Header.Get("Location"), nil)
+		req.AddCookie(cookies[0])
+		return req
+	}
+	finish := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		callback.ServeHTTP(w, req)
+		return w
+	}
+
+	w := finish(start(oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true}))
+	require.Equal(t, http.StatusOK, w.Code)
+	var res auth.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	claims, err := tokens.ValidateToken(res.Token)
+	require.NoError(t, err)
+	require.Equal(t, "new@example.com", claims.Email)
+	require.True(t, repo.users["new@example.com"].EmailVerified, "users are provisioned")
+
+	w = finish(start(oidctest.User{Subject: "2", Email: "2fa@example.com", EmailVerified: true}))
+	require.Equal(t, http.StatusOK, w.Code)
+	res = auth.Response{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Empty(t, res.Token)
+	require.NotEmpty(t, res.ChallengeToken, "2fa is still asked for")
+
+	cases := []struct {
+		name   string
+		user   oidctest.User
+		modify func(*http.Request) *http.Request
+		code   int
+	}{
+		{
+			name: "unverified email",
+			user: oidctest.User{Subject: "3", Email: "unverified@example.com"},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "other domain",
+			user: oidctest.User{Subject: "4", Email: "user@evil.com", EmailVerified: true},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "disabled",
+			user: oidctest.User{Subject: "5", Email: "disabled@example.com", EmailVerified: true},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "no cookie",
+			user: oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true},
+			modify: func(r *http.Request) *http.Request {
+				r.Header.Del("Cookie")
+				return r
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "other state",
+			user: oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true},
+			modify: func(r *http.Request) *http.Request {
+				q := r.URL.Query()
+				q.Set("state", "forged")
+				r.URL.RawQuery = q.Encode()
+				return r
+			},
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "other code",
+			user: oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true},
+			modify: func(r *http.Request) *http.Request {
+				q := r.URL.Query()
+				q.Set("code", "forged")
+				r.URL.RawQuery = q.Encode()
+				return r
+			},
+			code: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := start(tc.user)
+			if tc.modify != nil {
+				req = tc.modify(req)
+			}
+			require.Equal(t, tc.code, finish(req).Code)
+		})
+	}
+
+	require.Equal(t, []string{models.LoginSuccess, models.LoginTwoFactor, models.LoginDisabled}, func() []string {
+		var results []string
+		for _, e := range repo.events {
+			results = append(results, e.Result)
+		}
+		return results
+	}())
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	log := slog.New(custom_mocks.NewMockLogger())
+	for _, handler := range []http.HandlerFunc{
+		auth.OIDCLoginHandler(log, nil),
+		auth.OIDCCallbackHandler(log, &ssoRepo{}, newTokens(t), nil),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login?"+url.Values{"code": {"x"}}.Encode(), nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
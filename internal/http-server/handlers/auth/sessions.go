@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+)
+
+// SessionsLimit is the number of login attempts listed by SessionsHandler.
+const SessionsLimit = 20
+
+const maxUserAgent = 256
+
+// Session is a login attempt into the account of the caller. Result is one
+// of success, bad_credentials, disabled, throttled and two_factor.
+type Session struct {
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SessionsResponse struct {
+	resp.Response
+	Sessions []Session `json:"sessions"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=LoginLister
+type LoginLister interface {
+	ListLoginEvents(userId int64, limit int) ([]models.LoginEvent, error)
+}
+
+// SessionsHandler lists the recent login attempts into the account of the
+// caller, failed ones included, newest first.
+func SessionsHandler(log *slog.Logger, lister LoginLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		events, err := lister.ListLoginEvents(claims.Id, SessionsLimit)
+		if err != nil {
+			log.Error("failed to list login events", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		sessions := make([]Session, 0, len(events))
+		for _, e := range events {
+			sessions = append(sessions, Session{
+				IP:        e.IP,
+				UserAgent: e.UserAgent,
+				Result:    e.Result,
+				CreatedAt: e.CreatedAt,
+			})
+		}
+		render.JSON(w, r, SessionsResponse{Response: resp.OK(), Sessions: sessions})
+	}
+}
+
+// newLoginEvent describes a login attempt of r for email, the result is set
+// once known.
+func newLoginEvent(r *http.Request, email string) models.LoginEvent {
+	// RemoteAddr is the client address set by the real IP middleware
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	return models.LoginEvent{
+		Email:     email,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/lib/totp"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// RecoveryCodes is the number of recovery codes handed out when 2FA is
+// enabled.
+const RecoveryCodes = 10
+
+type SetupResponse struct {
+	resp.Response
+	// Secret is the base32 TOTP secret, URI the otpauth URI with the secret
+	// to be shown as a QR code.
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type EnableRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type EnableResponse struct {
+	resp.Response
+	// RecoveryCodes each replace a code once, they are not shown again.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" validate:"required"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=TOTPSetter
+type TOTPSetter interface {
+	GetUserByID(id int64) (*models.User, error)
+	SetTOTPSecret(userId int64, secret string) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=TOTPEnabler
+type TOTPEnabler interface {
+	GetUserByID(id int64) (*models.User, error)
+	EnableTOTP(userId int64, step int64, codeHashes []string) error
+}
+
+type TwoFactorRepo interface {
+	GetUserByID(id int64) (*models.User, error)
+	UseTOTPStep(userId int64, step int64) (bool, error)
+	ConsumeRecoveryCode(userId int64, codeHash string) error
+	SaveLoginEvent(event models.LoginEvent) error
+}
+
+// SetupTwoFactorHandler starts the 2FA setup of the caller with a new
+// secret. Logins ask for codes once the setup is confirmed with
+// EnableTwoFactorHandler.
+func SetupTwoFactorHandler(log *slog.Logger, setter TOTPSetter, issuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		user, err := setter.GetUserByID(claims.Id)
+		if err != nil {
+			log.Error("failed to get user", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if user.TOTPEnabled {
+			resp.RenderError(w, r, http.StatusConflict, "2fa already enabled")
+			return
+		}
+		secret, err := totp.NewSecret()
+		if err == nil {
+			err = setter.SetTOTPSecret(user.Id, secret)
+		}
+		if err != nil {
+			log.Error("failed to set up 2fa", "user_id", user.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("2fa set up", "user_id", user.Id)
+		render.JSON(w, r, SetupResponse{
+			Response: resp.OK(),
+			Secret:   secret,
+			URI:      totp.URI(issuer, user.Email, secret),
+		})
+	}
+}
+
+// EnableTwoFactorHandler enables 2FA for the caller once a code proves that
+// the secret of the setup was added to an authenticator app. It returns
+// one-time recovery codes.
+func EnableTwoFactorHandler(log *slog.Logger, enabler TOTPEnabler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		var req EnableRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+		user, err := enabler.GetUserByID(claims.Id)
+		if err != nil {
+			log.Error("failed to get user", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if user.TOTPEnabled {
+			resp.RenderError(w, r, http.StatusConflict, "2fa already enabled")
+			return
+		}
+		if user.TOTPSecret == "" {
+			resp.RenderError(w, r, http.StatusBadRequest, "2fa not set up")
+			return
+		}
+		step, ok := totp.Validate(user.TOTPSecret, req.Code, time.Now())
+		if !ok {
+			resp.RenderError(w, r, http.StatusBadRequest, "invalid code")
+			return
+		}
+
+		codes := make([]string, 0, RecoveryCodes)
+		hashes := make([]string, 0, RecoveryCodes)
+		for range RecoveryCodes {
+			code, err := newRecoveryCode()
+			if err != nil {
+				log.Error("failed to generate recovery code", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			codes = append(codes, code)
+			hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+		}
+		if err := enabler.EnableTOTP(user.Id, step, hashes); err != nil {
+			log.Error("failed to enable 2fa", "user_id", user.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("2fa enabled", "user_id", user.Id)
+		render.JSON(w, r, EnableResponse{Response: resp.OK(), RecoveryCodes: codes})
+	}
+}
+
+// TwoFactorLoginHandler exchanges a challenge token of LoginHandler and a
+// TOTP or recovery code for an access token. Wrong codes count as failed
+// logins of the account.
+func TwoFactorLoginHandler(log *slog.Logger, repo TwoFactorRepo, tokens jwt_helper.TokenIssuer, guard *lockout.Guard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With("request_id", middleware.GetReqID(r.Context()))
+
+		var req TwoFactorRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+		claims, err := tokens.ValidateChallengeToken(req.ChallengeToken)
+		if err != nil {
+			log.Info("invalid challenge token", "err", err)
+			resp.RenderError(w, r, http.StatusUnauthorized, "invalid or expired challenge token")
+			return
+		}
+		event := newLoginEvent(r, claims.Email)
+		event.UserId = claims.Id
+		record := func(result string) {
+			event.Result = result
+			if err := repo.SaveLoginEvent(event); err != nil {
+				log.Error("failed to record login", "err", err)
+			}
+		}
+
+		if guard != nil {
+			wait, locked, err := guard.Wait(claims.Email, event.IP, event.CreatedAt)
+			if err != nil {
+				log.Error("failed to count failed logins", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if wait > 0 {
+				log.Info("2fa login throttled", "user_id", claims.Id, "wait", wait)
+				record(models.LoginThrottled)
+				renderThrottled(w, r, wait, locked)
+				return
+			}
+		}
+
+		user, err := repo.GetUserByID(claims.Id)
+		if err != nil {
+			log.Error("failed to get user", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if user.Disabled {
+			record(models.LoginDisabled)
+			resp.RenderError(w, r, http.StatusForbidden, "account disabled")
+			return
+		}
+		ok, err := checkSecondFactor(repo, user, req.Code, event.CreatedAt)
+		if err != nil {
+			log.Error("failed to check 2fa code", "user_id", user.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if !ok {
+			log.Info("invalid 2fa code", "user_id", user.Id)
+			record(models.LoginBadCredentials)
+			resp.RenderError(w, r, http.StatusUnauthorized, "invalid code")
+			return
+		}
+
+		token, err := tokens.NewToken(*user)
+		if err != nil {
+			log.Error("failed to generate token", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		record(models.LoginSuccess)
+		log.Info("user login successfully", "user_id", user.Id)
+		render.JSON(w, r, Response{Token: token})
+	}
+}
+
+// checkSecondFactor accepts a TOTP code that was not used before or an
+// unused recovery code.
+func checkSecondFactor(repo TwoFactorRepo, user *models.User, code string, now time.Time) (bool, error) {
+	if !user.TOTPEnabled {
+		return false, nil
+	}
+	if step, ok := totp.Validate(user.TOTPSecret, code, now); ok {
+		return repo.UseTOTPStep(user.Id, step)
+	}
+	err := repo.ConsumeRecoveryCode(user.Id, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// newRecoveryCode returns a random code like abcde-fgh23 of 50 bits.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return code[:5] + "-" + code[5:10], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
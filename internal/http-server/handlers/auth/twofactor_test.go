@@ -0,0 +1,179 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/http-server/handlers/auth"
+	"url-shortener/internal/http-server/handlers/auth/mocks"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/lib/totp"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func (r *memRepo) GetUserByID(id int64) (*models.User, error) {
+	if r.user == nil || r.user.Id != id {
+		return nil, storage.ErrUserNotFound
+	}
+	user := *r.user
+	return &user, nil
+}
+
+func (r *memRepo) UseTOTPStep(userId int64, step int64) (bool, error) {
+	if step <= r.user.TOTPLastStep {
+		return false, nil
+	}
+	r.user.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *memRepo) ConsumeRecoveryCode(userId int64, codeHash string) error {
+	if !r.recovery[codeHash] {
+		return storage.ErrRecoveryCodeNotFound
+	}
+	delete(r.recovery, codeHash)
+	return nil
+}
+
+func withClaims(req *http.Request, id int64) *http.Request {
+	return req.WithContext(jwt_helper.WithClaims(req.Context(), &jwt_helper.UserClaims{Id: id}))
+}
+
+func TestSetupTwoFactorHandler(t *testing.T) {
+	setterMock := mocks.NewTOTPSetter(t)
+	setterMock.On("GetUserByID", int64(1)).Return(&models.User{Id: 1, Email: "user@example.com"}, nil).Once()
+	setterMock.On("GetUserByID", int64(2)).Return(&models.User{Id: 2, TOTPEnabled: true}, nil).Once()
+	var secret string
+	setterMock.On("SetTOTPSecret", int64(1), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { secret = args.String(1) }).
+		Return(nil).Once()
+
+	handler := auth.SetupTwoFactorHandler(slog.New(custom_mocks.NewMockLogger()), setterMock, "url-shortener")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withClaims(httptest.NewRequest(http.MethodPost, "/me/2fa/setup", nil), 1))
+	require.Equal(t, http.StatusOK, w.Code)
+	var res auth.SetupResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, secret, res.Secret)
+	require.Equal(t, totp.URI("url-shortener", "user@example.com", secret), res.URI)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withClaims(httptest.NewRequest(http.MethodPost, "/me/2fa/setup", nil), 2))
+	require.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestEnableTwoFactorHandler(t *testing.T) {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	key, err := totp.Decode(secret)
+	require.NoError(t, err)
+	code := totp.Code(key, totp.Step(time.Now(), totp.Options{}), totp.Options{})
+
+	enablerMock := mocks.NewTOTPEnabler(t)
+	enablerMock.On("GetUserByID", int64(1)).Return(&models.User{Id: 1, TOTPSecret: secret}, nil)
+	enablerMock.On("GetUserByID", int64(2)).Return(&models.User{Id: 2}, nil)
+	var hashes []string
+	enablerMock.On("EnableTOTP", int64(1), mock.AnythingOfType("int64"), mock.AnythingOfType("[]string")).
+		Run(func(args mock.Arguments) { hashes = args.Get(2).([]string) }).
+		Return(nil).Once()
+
+	handler := auth.EnableTwoFactorHandler(slog.New(custom_mocks.NewMockLogger()), enablerMock)
+	enable := func(id int64, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/me/2fa/enable", strings.NewReader(body))
+		handler.ServeHTTP(w, withClaims(req, id))
+		return w
+	}
+
+	require.Equal(t, http.StatusBadRequest, enable(1, `{"code": "000000x"}`).Code)
+	require.Equal(t, http.StatusBadRequest, enable(2, `{"code": "`+code+`"}`).Code, "no setup")
+	require.Equal(t, http.StatusBadRequest, enable(1, `{}`).Code)
+
+	w := enable(1, `{"code": "`+code+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res auth.EnableResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.RecoveryCodes, auth.RecoveryCodes)
+	require.Len(t, hashes, auth.RecoveryCodes)
+	require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, res.RecoveryCodes[0])
+	require.Equal(t, hash(strings.ReplaceAll(res.RecoveryCodes[0], "-", "")), hashes[0])
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	tokens := newTokens(t)
+	hasher := newHasher(t)
+	passwordHash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	key, err := totp.Decode(secret)
+	require.NoError(t, err)
+
+	repo := &memRepo{
+		user: &models.User{
+			Id:          1,
+			Email:       "user@example.com",
+			Password:    passwordHash,
+			TOTPSecret:  secret,
+			TOTPEnabled: true,
+		},
+		recovery: map[string]bool{hash("abcdeabcde"): true},
+	}
+	guard := lockout.New(repo, lockout.Options{DelayAfter: 2, Delay: time.Hour, MaxDelay: time.Hour})
+	login := auth.LoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, hasher, guard)
+	secondFactor := auth.TwoFactorLoginHandler(slog.New(custom_mocks.NewMockLogger()), repo, tokens, guard)
+
+	w := post(login, "/login", `{"email": "user@example.com", "password": "correct horse"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res auth.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Empty(t, res.Token)
+	require.NotEmpty(t, res.ChallengeToken)
+	_, err = tokens.ValidateToken(res.ChallengeToken)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken, "challenge tokens are no access tokens")
+	challenge := res.ChallengeToken
+
+	exchange := func(code string) *httptest.ResponseRecorder {
+		return post(secondFactor, "/login/2fa", `{"challenge_token": "`+challenge+`", "code": "`+code+`"}`)
+	}
+
+	code := totp.Code(key, totp.Step(time.Now(), totp.Options{}), totp.Options{})
+	w = exchange(code)
+	require.Equal(t, http.StatusOK, w.Code)
+	res = auth.Response{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	claims, err := tokens.ValidateToken(res.Token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), claims.Id)
+
+	require.Equal(t, http.StatusUnauthorized, exchange(code).Code, "codes cannot be replayed")
+	require.Equal(t, http.StatusOK, exchange("ABCDE-abcde").Code)
+	require.Equal(t, http.StatusUnauthorized, exchange("abcde-abcde").Code, "recovery codes are single-use")
+
+	w = post(secondFactor, "/login/2fa", `{"challenge_token": "`+res.Token+`", "code": "`+code+`"}`)
+	require.Equal(t, http.StatusUnauthorized, w.Code, "access tokens are no challenge tokens")
+
+	require.Equal(t, http.StatusUnauthorized, exchange("000000").Code)
+	w = exchange("000000")
+	require.Equal(t, http.StatusTooManyRequests, w.Code, "wrong codes count as failed logins")
+
+	require.Equal(t, []string{
+		models.LoginTwoFactor,
+		models.LoginSuccess,
+		models.LoginBadCredentials,
+		models.LoginSuccess,
+		models.LoginBadCredentials,
+		models.LoginBadCredentials,
+		models.LoginThrottled,
+	}, repo.results())
+}
@@ -2,42 +2,70 @@ package redirect
 
 import (
 	"errors"
-	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"url-shortener/internal/lib/access"
 	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=URLDeleter
 type URLDeleter interface {
+	GetLink(alias string) (*models.UrlShortener, error)
 	DeleteURL(alias string) error
+	WorkspaceRole(workspaceId, userId int64) (string, error)
 }
 
-func DeleteHandler(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+type LinkEvents interface {
+	Publish(event string, link models.UrlShortener)
+}
+
+// DeleteHandler deletes an alias the caller may delete according to
+// access.Check, admins may delete any alias. Aliases the caller cannot see are
+// reported as not found. events may be nil.
+func DeleteHandler(log *slog.Logger, urlDeleter URLDeleter, events LinkEvents) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log = log.With(
+		log := log.With(
 			"request_id", middleware.GetReqID(r.Context()),
 		)
 		alias := chi.URLParam(r, "alias")
-		fmt.Println("requested", r.RequestURI)
-		err := urlDeleter.DeleteURL(alias)
+		link, err := urlDeleter.GetLink(alias)
+		if err == nil {
+			claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+			if !ok {
+				resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			err = access.Check(urlDeleter, claims, link, access.Delete)
+		}
+		if err == nil {
+			err = urlDeleter.DeleteURL(alias)
+		}
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Error("url not found", "alias", alias, "err", err)
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("url not found"))
+			resp.RenderError(w, r, http.StatusBadRequest, "url not found")
+			return
+		}
+		if errors.Is(err, access.ErrForbidden) {
+			log.Info("delete not allowed", "alias", alias)
+			resp.RenderError(w, r, http.StatusForbidden, "forbidden")
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", "alias", alias, "err", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
 			return
 		}
 		log.Info("url deleted", "alias", alias)
+		if events != nil {
+			events.Publish(webhook.EventLinkDeleted, *link)
+		}
 		render.JSON(w, r, resp.OK())
 	}
 }
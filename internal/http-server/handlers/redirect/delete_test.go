@@ -13,6 +13,9 @@ import (
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/http-server/handlers/url"
 	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
@@ -20,6 +23,8 @@ func TestDeleteHandler(t *testing.T) {
 	cases := []struct {
 		name      string
 		alias     string
+		link      *models.UrlShortener
+		role      string
 		respError string
 		mockError error
 	}{
@@ -29,6 +34,23 @@ func TestDeleteHandler(t *testing.T) {
 			mockError: storage.ErrUrlNotFound,
 			respError: storage.ErrUrlNotFound.Error(),
 		},
+		{
+			name:      "Foreign alias",
+			alias:     "theirs",
+			link:      &models.UrlShortener{Alias: "theirs", UserId: 2},
+			respError: storage.ErrUrlNotFound.Error(),
+		},
+		{
+			name:  "Own alias",
+			alias: "mine",
+			link:  &models.UrlShortener{Alias: "mine", UserId: 1},
+		},
+		{
+			name:  "Admin deletes foreign alias",
+			alias: "theirs",
+			link:  &models.UrlShortener{Alias: "theirs", UserId: 2},
+			role:  models.RoleAdmin,
+		},
 	}
 
 	for _, tc := range cases {
@@ -39,11 +61,15 @@ func TestDeleteHandler(t *testing.T) {
 
 			urlDeleterMock := mocks.NewURLDeleter(t)
 
-			urlDeleterMock.On("DeleteURL", tc.alias).
-				Return(tc.mockError).
+			urlDeleterMock.On("GetLink", tc.alias).
+				Return(tc.link, tc.mockError).
 				Once()
+			deleted := tc.respError == ""
+			if deleted {
+				urlDeleterMock.On("DeleteURL", tc.alias).Return(nil).Once()
+			}
 			logger := slog.New(custom_mocks.NewMockLogger())
-			handler := redirect.DeleteHandler(logger, urlDeleterMock)
+			handler := redirect.DeleteHandler(logger, urlDeleterMock, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodDelete, "/{alias}", nil)
@@ -51,10 +77,16 @@ func TestDeleteHandler(t *testing.T) {
 			reqCtx := chi.NewRouteContext()
 			reqCtx.URLParams.Add("alias", tc.alias)
 
-			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx))
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+			ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1, Role: tc.role})
+			r = r.WithContext(ctx)
 			handler.ServeHTTP(w, r)
 
-			require.Equal(t, w.Code, http.StatusOK)
+			if deleted {
+				require.Equal(t, http.StatusOK, w.Code)
+				return
+			}
+			require.Equal(t, http.StatusBadRequest, w.Code)
 
 			body := w.Body.String()
 
@@ -66,3 +98,75 @@ func TestDeleteHandler(t *testing.T) {
 		})
 	}
 }
+
+type linkEvents struct {
+	event string
+	link  models.UrlShortener
+}
+
+func (e *linkEvents) Publish(event string, link models.UrlShortener) {
+	e.event, e.link = event, link
+}
+
+func TestDeleteHandlerPublishes(t *testing.T) {
+	urlDeleterMock := mocks.NewURLDeleter(t)
+	urlDeleterMock.On("GetLink", "docs").
+		Return(&models.UrlShortener{Id: 7, Alias: "docs", Url: "https://example.com", UserId: 1}, nil).
+		Once()
+	urlDeleterMock.On("DeleteURL", "docs").Return(nil).Once()
+
+	events := &linkEvents{}
+	handler := redirect.DeleteHandler(slog.New(custom_mocks.NewMockLogger()), urlDeleterMock, events)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/docs", nil)
+	reqCtx := chi.NewRouteContext()
+	reqCtx.URLParams.Add("alias", "docs")
+	ctx := jwt_helper.WithClaims(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx), &jwt_helper.UserClaims{Id: 1})
+	handler.ServeHTTP(w, r.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, webhook.EventLinkDeleted, events.event)
+	require.Equal(t, int64(7), events.link.Id)
+	require.Equal(t, int64(1), events.link.UserId)
+}
+
+func TestDeleteHandlerWorkspace(t *testing.T) {
+	cases := []struct {
+		name     string
+		userId   int64
+		role     string
+		respCode int
+	}{
+		{name: "Workspace admin", userId: 1, role: models.WorkspaceRoleAdmin, respCode: http.StatusOK},
+		{name: "Member", userId: 1, role: models.WorkspaceRoleMember, respCode: http.StatusForbidden},
+		{name: "Creator", userId: 2, role: models.WorkspaceRoleMember, respCode: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlDeleterMock := mocks.NewURLDeleter(t)
+			urlDeleterMock.On("GetLink", "shared").
+				Return(&models.UrlShortener{Alias: "shared", UserId: 2, WorkspaceId: 5}, nil).
+				Once()
+			urlDeleterMock.On("WorkspaceRole", int64(5), tc.userId).Return(tc.role, nil).Once()
+			if tc.respCode == http.StatusOK {
+				urlDeleterMock.On("DeleteURL", "shared").Return(nil).Once()
+			}
+			handler := redirect.DeleteHandler(slog.New(custom_mocks.NewMockLogger()), urlDeleterMock, nil)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "/shared", nil)
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", "shared")
+			ctx := jwt_helper.WithClaims(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx), &jwt_helper.UserClaims{Id: tc.userId})
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, w.Code)
+		})
+	}
+}
@@ -4,37 +4,129 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	"url-shortener/internal/lib/rules"
+	"url-shortener/internal/lib/utm"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetLink(alias string) (*models.UrlShortener, error)
+	IncrementClicks(alias string) (int64, error)
+	IncrementVariantClicks(id int64) error
+	GetUserByID(id int64) (*models.User, error)
 }
 
-func GetHandler(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// Options configures GetHandler.
+type Options struct {
+	// Geo resolves client countries for redirect rules and may be nil.
+	Geo rules.CountryLookup
+	// PreviewAlways shows the preview page instead of redirecting for every
+	// link.
+	PreviewAlways bool
+	// Interstitial shows a warning page before redirecting to hosts outside
+	// InternalDomains.
+	Interstitial    bool
+	InternalDomains []string
+	// Clicks is told the click count after every counted click and may be
+	// nil.
+	Clicks ClickEvents
+}
+
+type ClickEvents interface {
+	Clicked(link models.UrlShortener, clicks int64)
+}
+
+// GetHandler redirects to the destination of the alias. It shows the preview
+// page instead for /{alias}+, ?preview=1 and links created with preview, and
+// answers 410 for expired links.
+func GetHandler(log *slog.Logger, urlGetter URLGetter, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log = log.With(
+		log := log.With(
 			"request_id", middleware.GetReqID(r.Context()),
 		)
-		alias := chi.URLParam(r, "alias")
-		url, err := urlGetter.GetURL(alias)
+		alias, preview := strings.CutSuffix(chi.URLParam(r, "alias"), custom_validators.PreviewSuffix)
+		link, err := urlGetter.GetLink(alias)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Error("url not found", "alias", alias, "err", err)
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("url not found"))
+			resp.RenderError(w, r, http.StatusBadRequest, "url not found")
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", "alias", alias, "err", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if link.Expired(time.Now()) {
+			log.Info("url expired", "alias", alias)
+			resp.RenderError(w, r, http.StatusGone, "url expired")
+			return
+		}
+		if preview || wantsPreview(r, link, opts) {
+			renderPreview(w, log, urlGetter, link)
 			return
 		}
-		http.Redirect(w, r, url, http.StatusSeeOther)
+
+		target, variant := selectTarget(w, r, link, opts.Geo)
+		target, err = addQuery(link, target, r)
+		if err != nil {
+			log.Error("failed to build destination", "alias", alias, "err", err)
+		}
+		clicks, err := urlGetter.IncrementClicks(alias)
+		if err != nil {
+			log.Error("failed to count click", "alias", alias, "err", err)
+		} else if opts.Clicks != nil {
+			opts.Clicks.Clicked(*link, clicks)
+		}
+		if variant != nil {
+			if err = urlGetter.IncrementVariantClicks(variant.Id); err != nil {
+				log.Error("failed to count variant click", "alias", alias, "variant", variant.Id, "err", err)
+			}
+		}
+		if opts.Interstitial && !isInternal(target, opts.InternalDomains) {
+			renderPage(w, log, "interstitial.html", pageData{Alias: alias, Target: target})
+			return
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	}
+}
+
+// selectTarget returns the URL a visitor of link is sent to: the target of
+// the first matching rule, else a variant picked by weight, else the stored
+// URL. The variant is returned when one was picked.
+func selectTarget(w http.ResponseWriter, r *http.Request, link *models.UrlShortener, geo rules.CountryLookup) (string, *models.Variant) {
+	if len(link.Rules) > 0 {
+		if target, ok := rules.Select(link.Rules, rules.NewVisitor(r, geo, time.Now())); ok {
+			return target, nil
+		}
+	}
+	if variant := pickVariant(w, r, link); variant != nil {
+		return variant.Target, variant
+	}
+	return link.Url, nil
+}
+
+// addQuery merges the UTM parameters of link into target, replacing the ones
+// already there, and then the forwarded query parameters, which never
+// override target or its UTM parameters. On errors target is returned as is.
+func addQuery(link *models.UrlShortener, target string, r *http.Request) (string, error) {
+	merged, err := utm.Merge(target, utm.Values(link.UTM), true)
+	if err != nil {
+		return target, err
+	}
+	if link.ForwardQuery {
+		forwarded, err := utm.Merge(merged, r.URL.Query(), false)
+		if err != nil {
+			return merged, err
+		}
+		return forwarded, nil
 	}
+	return merged, nil
 }
@@ -9,10 +9,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/http-server/handlers/url"
 	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
@@ -20,6 +22,10 @@ func TestGetHandler(t *testing.T) {
 	cases := []struct {
 		name      string
 		alias     string
+		query     string
+		userAgent string
+		link      *models.UrlShortener
+		location  string
 		respError string
 		mockError error
 	}{
@@ -29,6 +35,64 @@ func TestGetHandler(t *testing.T) {
 			mockError: storage.ErrUrlNotFound,
 			respError: storage.ErrUrlNotFound.Error(),
 		},
+		{
+			name:     "Plain redirect",
+			alias:    "plain",
+			query:    "?ref=ignored",
+			link:     &models.UrlShortener{Url: "https://example.com/a?b=2&a=1"},
+			location: "https://example.com/a?b=2&a=1",
+		},
+		{
+			name:  "UTM parameters",
+			alias: "utm",
+			link: &models.UrlShortener{
+				Url: "https://example.com/?utm_source=old&id=7",
+				UTM: models.UTM{Source: "newsletter", Medium: "email", Campaign: "spring"},
+			},
+			location: "https://example.com/?id=7&utm_campaign=spring&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:     "Forward query",
+			alias:    "forward",
+			query:    "?ref=ad&id=8",
+			link:     &models.UrlShortener{Url: "https://example.com/p?id=7", ForwardQuery: true},
+			location: "https://example.com/p?id=7&ref=ad",
+		},
+		{
+			name:  "Forward query does not override UTM",
+			alias: "both",
+			query: "?utm_source=visitor&gclid=abc",
+			link: &models.UrlShortener{
+				Url:          "https://example.com/",
+				UTM:          models.UTM{Source: "newsletter"},
+				ForwardQuery: true,
+			},
+			location: "https://example.com/?utm_source=newsletter&gclid=abc",
+		},
+		{
+			name:      "Matching rule",
+			alias:     "app",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148",
+			link: &models.UrlShortener{
+				Url: "https://example.com/app",
+				UTM: models.UTM{Source: "qr"},
+				Rules: []models.Rule{
+					{OS: []string{"android"}, Target: "https://play.google.com/store/apps/details?id=app"},
+					{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"},
+				},
+			},
+			location: "https://apps.apple.com/app/id1?utm_source=qr",
+		},
+		{
+			name:      "No matching rule",
+			alias:     "app",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+			link: &models.UrlShortener{
+				Url:   "https://example.com/app",
+				Rules: []models.Rule{{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"}},
+			},
+			location: "https://example.com/app",
+		},
 	}
 
 	for _, tc := range cases {
@@ -39,14 +103,18 @@ func TestGetHandler(t *testing.T) {
 
 			urlGetterMock := mocks.NewURLGetter(t)
 
-			urlGetterMock.On("GetURL", tc.alias).
-				Return("", tc.mockError).
+			urlGetterMock.On("GetLink", tc.alias).
+				Return(tc.link, tc.mockError).
 				Once()
+			if tc.link != nil {
+				urlGetterMock.On("IncrementClicks", tc.alias).Return(int64(1), nil).Once()
+			}
 			logger := slog.New(custom_mocks.NewMockLogger())
-			handler := redirect.GetHandler(logger, urlGetterMock)
+			handler := redirect.GetHandler(logger, urlGetterMock, redirect.Options{})
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest(http.MethodGet, "/{alias}", nil)
+			r := httptest.NewRequest(http.MethodGet, "/"+tc.alias+tc.query, nil)
+			r.Header.Set("User-Agent", tc.userAgent)
 
 			reqCtx := chi.NewRouteContext()
 			reqCtx.URLParams.Add("alias", tc.alias)
@@ -54,7 +122,13 @@ func TestGetHandler(t *testing.T) {
 			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx))
 			handler.ServeHTTP(w, r)
 
-			require.Equal(t, w.Code, http.StatusOK)
+			if tc.respError == "" {
+				require.Equal(t, http.StatusSeeOther, w.Code)
+				require.Equal(t, tc.location, w.Header().Get("Location"))
+				return
+			}
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
 
 			body := w.Body.String()
 
@@ -66,3 +140,56 @@ func TestGetHandler(t *testing.T) {
 		})
 	}
 }
+
+type clickEvents struct {
+	alias  string
+	clicks int64
+}
+
+func (e *clickEvents) Clicked(link models.UrlShortener, clicks int64) {
+	e.alias, e.clicks = link.Alias, clicks
+}
+
+func TestGetHandlerReportsClicks(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetLink", "docs").
+		Return(&models.UrlShortener{Alias: "docs", Url: "https://example.com", Clicks: 99}, nil).
+		Once()
+	urlGetterMock.On("IncrementClicks", "docs").Return(int64(100), nil).Once()
+
+	events := &clickEvents{}
+	handler := redirect.GetHandler(slog.New(custom_mocks.NewMockLogger()), urlGetterMock, redirect.Options{Clicks: events})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	reqCtx := chi.NewRouteContext()
+	reqCtx.URLParams.Add("alias", "docs")
+	handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)))
+
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	require.Equal(t, "docs", events.alias)
+	require.Equal(t, int64(100), events.clicks)
+}
+
+func TestGetHandlerExpired(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetLink", "sale").
+		Return(&models.UrlShortener{Alias: "sale", Url: "https://example.com", ExpiresAt: time.Now().Add(-time.Minute)}, nil).
+		Twice()
+
+	handler := redirect.GetHandler(slog.New(custom_mocks.NewMockLogger()), urlGetterMock, redirect.Options{})
+
+	// the preview page is gone as well
+	for _, path := range []string{"sale", "sale+"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/"+path, nil)
+		reqCtx := chi.NewRouteContext()
+		reqCtx.URLParams.Add("alias", path)
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)))
+
+		require.Equal(t, http.StatusGone, w.Code, path)
+		var resp url.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, "url expired", resp.Error)
+	}
+}
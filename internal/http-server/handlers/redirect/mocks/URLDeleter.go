@@ -2,7 +2,10 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+	models "url-shortener/internal/models"
+)
 
 // URLDeleter is an autogenerated mock type for the URLDeleter type
 type URLDeleter struct {
@@ -27,6 +30,64 @@ func (_m *URLDeleter) DeleteURL(alias string) error {
 	return r0
 }
 
+// GetLink provides a mock function with given fields: alias
+func (_m *URLDeleter) GetLink(alias string) (*models.UrlShortener, error) {
+	ret := _m.Called(alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLink")
+	}
+
+	var r0 *models.UrlShortener
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.UrlShortener, error)); ok {
+		return rf(alias)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.UrlShortener); ok {
+		r0 = rf(alias)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UrlShortener)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WorkspaceRole provides a mock function with given fields: workspaceId, userId
+func (_m *URLDeleter) WorkspaceRole(workspaceId int64, userId int64) (string, error) {
+	ret := _m.Called(workspaceId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WorkspaceRole")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int64) (string, error)); ok {
+		return rf(workspaceId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int64) string); ok {
+		r0 = rf(workspaceId, userId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int64) error); ok {
+		r1 = rf(workspaceId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewURLDeleter creates a new instance of URLDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLDeleter(t interface {
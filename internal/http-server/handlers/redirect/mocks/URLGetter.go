@@ -2,30 +2,35 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+	models "url-shortener/internal/models"
+)
 
 // URLGetter is an autogenerated mock type for the URLGetter type
 type URLGetter struct {
 	mock.Mock
 }
 
-// GetURL provides a mock function with given fields: alias
-func (_m *URLGetter) GetURL(alias string) (string, error) {
+// GetLink provides a mock function with given fields: alias
+func (_m *URLGetter) GetLink(alias string) (*models.UrlShortener, error) {
 	ret := _m.Called(alias)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetURL")
+		panic("no return value specified for GetLink")
 	}
 
-	var r0 string
+	var r0 *models.UrlShortener
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (*models.UrlShortener, error)); ok {
 		return rf(alias)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
+	if rf, ok := ret.Get(0).(func(string) *models.UrlShortener); ok {
 		r0 = rf(alias)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UrlShortener)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
@@ -37,6 +42,82 @@ func (_m *URLGetter) GetURL(alias string) (string, error) {
 	return r0, r1
 }
 
+// GetUserByID provides a mock function with given fields: id
+func (_m *URLGetter) GetUserByID(id int64) (*models.User, error) {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByID")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (*models.User, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(int64) *models.User); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementClicks provides a mock function with given fields: alias
+func (_m *URLGetter) IncrementClicks(alias string) (int64, error) {
+	ret := _m.Called(alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementClicks")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int64, error)); ok {
+		return rf(alias)
+	}
+	if rf, ok := ret.Get(0).(func(string) int64); ok {
+		r0 = rf(alias)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementVariantClicks provides a mock function with given fields: id
+func (_m *URLGetter) IncrementVariantClicks(id int64) error {
+	ret := _m.Called(id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementVariantClicks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewURLGetter creates a new instance of URLGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLGetter(t interface {
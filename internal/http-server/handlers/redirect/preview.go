@@ -0,0 +1,126 @@
+package redirect
+
+import (
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"url-shortener/internal/lib/utm"
+	"url-shortener/internal/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// pageData is rendered by the preview and interstitial templates.
+type pageData struct {
+	Alias        string
+	Title        string
+	Target       string
+	Host         string
+	Owner        string
+	CreatedAt    time.Time
+	Alternatives []string
+}
+
+// wantsPreview reports whether the link or the configuration asks for the
+// preview page instead of the redirect.
+func wantsPreview(r *http.Request, link *models.UrlShortener, opts Options) bool {
+	if opts.PreviewAlways || link.Preview {
+		return true
+	}
+	preview := r.URL.Query().Get("preview")
+	return preview == "1" || preview == "true"
+}
+
+// renderPreview shows where link leads without following it. Previews are
+// not counted as clicks. The page is public, so the owner's email is masked.
+func renderPreview(w http.ResponseWriter, log *slog.Logger, owners URLGetter, link *models.UrlShortener) {
+	target, err := utm.Merge(link.Url, utm.Values(link.UTM), true)
+	if err != nil {
+		target = link.Url
+	}
+	data := pageData{
+		Alias:     link.Alias,
+		Title:     link.Title,
+		Target:    target,
+		Owner:     "an anonymous user",
+		CreatedAt: link.CreatedAt,
+	}
+	if data.Title == "" && link.Metadata != nil {
+		data.Title = link.Metadata.Title
+	}
+	if link.UserId != 0 {
+		owner, err := owners.GetUserByID(link.UserId)
+		if err != nil {
+			log.Error("failed to get link owner", "alias", link.Alias, "err", err)
+		} else {
+			data.Owner = maskEmail(owner.Email)
+		}
+	}
+
+	seen := map[string]bool{link.Url: true}
+	alternative := func(target string) {
+		if !seen[target] {
+			seen[target] = true
+			data.Alternatives = append(data.Alternatives, target)
+		}
+	}
+	for _, rule := range link.Rules {
+		alternative(rule.Target)
+	}
+	for _, variant := range link.Variants {
+		if variant.Weight > 0 {
+			alternative(variant.Target)
+		}
+	}
+	renderPage(w, log, "preview.html", data)
+}
+
+// maskEmail keeps the first letter and the domain of email, so that
+// previews do not hand out the addresses of users.
+func maskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 1 {
+		return "a registered user"
+	}
+	_, first := utf8.DecodeRuneInString(email)
+	return email[:first] + "***" + email[at:]
+}
+
+// isInternal reports whether target points to one of the trusted domains or
+// their subdomains.
+func isInternal(target string, domains []string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func renderPage(w http.ResponseWriter, log *slog.Logger, name string, data pageData) {
+	if u, err := url.Parse(data.Target); err == nil {
+		data.Host = u.Host
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Robots-Tag", "noindex")
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Error("failed to render page", "template", name, "err", err)
+	}
+}
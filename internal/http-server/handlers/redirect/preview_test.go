@@ -0,0 +1,129 @@
+package redirect_test
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/redirect/mocks"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/models"
+)
+
+func TestGetHandlerPreview(t *testing.T) {
+	cases := []struct {
+		name    string
+		path    string
+		query   string
+		preview bool
+		opts    redirect.Options
+		page    bool
+	}{
+		{name: "Plus suffix", path: "docs+", page: true},
+		{name: "Query flag", path: "docs", query: "?preview=1", page: true},
+		{name: "Link preview", path: "docs", preview: true, page: true},
+		{name: "Global preview", path: "docs", opts: redirect.Options{PreviewAlways: true}, page: true},
+		{name: "Redirect", path: "docs", query: "?preview=0"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			link := &models.UrlShortener{
+				Alias:   "docs",
+				Url:     "https://example.com/docs",
+				UserId:  3,
+				Title:   `<script>alert("x")</script>`,
+				Preview: tc.preview,
+				UTM:     models.UTM{Source: "short"},
+				Rules:   []models.Rule{{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"}},
+			}
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "docs").Return(link, nil).Once()
+			if tc.page {
+				urlGetterMock.On("GetUserByID", int64(3)).Return(&models.User{Id: 3, Email: "owner@example.com"}, nil).Once()
+			} else {
+				urlGetterMock.On("IncrementClicks", "docs").Return(int64(1), nil).Once()
+			}
+
+			logger := slog.New(custom_mocks.NewMockLogger())
+			handler := redirect.GetHandler(logger, urlGetterMock, tc.opts)
+
+			r := httptest.NewRequest(http.MethodGet, "/"+tc.path+tc.query, nil)
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", tc.path)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if !tc.page {
+				require.Equal(t, http.StatusSeeOther, w.Code)
+				return
+			}
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+			body := w.Body.String()
+			require.Contains(t, body, "https://example.com/docs?utm_source=short")
+			require.Contains(t, body, "https://apps.apple.com/app/id1")
+			require.Contains(t, body, "o***@example.com")
+			require.NotContains(t, body, "owner@example.com", "the page is public")
+			require.Contains(t, body, "&lt;script&gt;")
+			require.NotContains(t, body, "<script>")
+		})
+	}
+}
+
+func TestGetHandlerInterstitial(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		warn   bool
+	}{
+		{name: "External", target: "https://evil.example.net/login", warn: true},
+		{name: "Internal", target: "https://example.com/a"},
+		{name: "Internal subdomain", target: "https://docs.example.com/a"},
+		{name: "Lookalike", target: "https://notexample.com/a", warn: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "go").Return(&models.UrlShortener{Alias: "go", Url: tc.target}, nil).Once()
+			urlGetterMock.On("IncrementClicks", "go").Return(int64(1), nil).Once()
+
+			logger := slog.New(custom_mocks.NewMockLogger())
+			handler := redirect.GetHandler(logger, urlGetterMock, redirect.Options{
+				Interstitial:    true,
+				InternalDomains: []string{"example.com"},
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/go", nil)
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", "go")
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if !tc.warn {
+				require.Equal(t, http.StatusSeeOther, w.Code)
+				require.Equal(t, tc.target, w.Header().Get("Location"))
+				return
+			}
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Contains(t, w.Body.String(), `href="`+tc.target+`"`)
+		})
+	}
+}
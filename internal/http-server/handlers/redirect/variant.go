@@ -0,0 +1,62 @@
+package redirect
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/models"
+)
+
+// variantCookieMaxAge is how long a visitor keeps seeing the same variant of
+// a sticky link.
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// pickVariant chooses a variant of link by weight. Sticky links first reuse
+// the variant remembered in the visitor's cookie as long as it still has a
+// positive weight, and remember new picks. It returns nil when the link has no
+// variant with a positive weight.
+func pickVariant(w http.ResponseWriter, r *http.Request, link *models.UrlShortener) *models.Variant {
+	total := 0
+	for _, v := range link.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	cookieName := fmt.Sprintf("variant_%d", link.Id)
+	if link.StickyVariants {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			id, _ := strconv.ParseInt(cookie.Value, 10, 64)
+			for i, v := range link.Variants {
+				if v.Id == id && v.Weight > 0 {
+					return &link.Variants[i]
+				}
+			}
+		}
+	}
+
+	n := rand.IntN(total)
+	var picked *models.Variant
+	for i, v := range link.Variants {
+		if n < v.Weight {
+			picked = &link.Variants[i]
+			break
+		}
+		n -= v.Weight
+	}
+
+	if link.StickyVariants {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    strconv.FormatInt(picked.Id, 10),
+			Path:     "/" + link.Alias,
+			MaxAge:   int(variantCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return picked
+}
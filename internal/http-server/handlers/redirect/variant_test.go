@@ -0,0 +1,119 @@
+package redirect_test
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/redirect/mocks"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/models"
+)
+
+func TestGetHandlerVariants(t *testing.T) {
+	cases := []struct {
+		name      string
+		sticky    bool
+		weights   [2]int
+		cookie    string
+		location  string
+		variant   int64
+		setCookie string
+	}{
+		{
+			name:     "Picks by weight",
+			weights:  [2]int{0, 100},
+			location: "https://example.com/b",
+			variant:  2,
+		},
+		{
+			name:      "Sticky without cookie",
+			sticky:    true,
+			weights:   [2]int{100, 0},
+			location:  "https://example.com/a",
+			variant:   1,
+			setCookie: "variant_10=1",
+		},
+		{
+			name:      "Sticky cookie of disabled variant",
+			sticky:    true,
+			weights:   [2]int{0, 100},
+			cookie:    "1",
+			location:  "https://example.com/b",
+			variant:   2,
+			setCookie: "variant_10=2",
+		},
+		{
+			name:     "Sticky cookie is reused",
+			sticky:   true,
+			weights:  [2]int{1, 100},
+			cookie:   "1",
+			location: "https://example.com/a",
+			variant:  1,
+		},
+		{
+			name:     "Cookie ignored when not sticky",
+			weights:  [2]int{0, 100},
+			cookie:   "1",
+			location: "https://example.com/b",
+			variant:  2,
+		},
+		{
+			name:     "All weights zero",
+			location: "https://example.com/default",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			link := &models.UrlShortener{
+				Id:             10,
+				Alias:          "landing",
+				Url:            "https://example.com/default",
+				StickyVariants: tc.sticky,
+				Variants: []models.Variant{
+					{Id: 1, Target: "https://example.com/a", Weight: tc.weights[0]},
+					{Id: 2, Target: "https://example.com/b", Weight: tc.weights[1]},
+				},
+			}
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "landing").Return(link, nil).Once()
+			urlGetterMock.On("IncrementClicks", "landing").Return(int64(1), nil).Once()
+			if tc.variant != 0 {
+				urlGetterMock.On("IncrementVariantClicks", tc.variant).Return(nil).Once()
+			}
+
+			logger := slog.New(custom_mocks.NewMockLogger())
+			handler := redirect.GetHandler(logger, urlGetterMock, redirect.Options{})
+
+			r := httptest.NewRequest(http.MethodGet, "/landing", nil)
+			if tc.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "variant_10", Value: tc.cookie})
+			}
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", "landing")
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusSeeOther, w.Code)
+			require.Equal(t, tc.location, w.Header().Get("Location"))
+			if tc.setCookie == "" {
+				require.Empty(t, w.Header().Get("Set-Cookie"))
+				return
+			}
+			require.Contains(t, w.Header().Get("Set-Cookie"), tc.setCookie)
+			require.Contains(t, w.Header().Get("Set-Cookie"), "Path=/landing")
+		})
+	}
+}
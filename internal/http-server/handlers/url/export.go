@@ -0,0 +1,118 @@
+package url
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+var csvHeader = []string{"alias", "url", "created_at", "clicks"}
+
+var contentTypes = map[string]string{
+	FormatCSV:   "text/csv",
+	FormatJSON:  "application/json",
+	FormatJSONL: "application/x-ndjson",
+}
+
+type URLWalker interface {
+	WalkURLs(userId int64, fn func(models.UrlShortener) error) error
+}
+
+// ExportHandler streams the caller's links in the format given by the format
+// query parameter, json by default.
+func ExportHandler(log *slog.Logger, urlWalker URLWalker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = FormatJSON
+		}
+		contentType, ok := contentTypes[format]
+		if !ok {
+			resp.RenderError(w, r, http.StatusBadRequest, "unknown format")
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="links.`+format+`"`)
+
+		var write func(Link) error
+		var finish func() error
+		switch format {
+		case FormatCSV:
+			cw := csv.NewWriter(w)
+			if err := cw.Write(csvHeader); err != nil {
+				log.Error("failed to write export", "err", err)
+				return
+			}
+			write = func(l Link) error {
+				return cw.Write([]string{l.Alias, l.URL, l.CreatedAt.Format(time.RFC3339), strconv.FormatInt(l.Clicks, 10)})
+			}
+			finish = func() error {
+				cw.Flush()
+				return cw.Error()
+			}
+		case FormatJSONL:
+			enc := json.NewEncoder(w)
+			write = func(l Link) error {
+				return enc.Encode(l)
+			}
+			finish = func() error { return nil }
+		case FormatJSON:
+			enc := json.NewEncoder(w)
+			first := true
+			if _, err := w.Write([]byte("[")); err != nil {
+				log.Error("failed to write export", "err", err)
+				return
+			}
+			write = func(l Link) error {
+				if !first {
+					if _, err := w.Write([]byte(",")); err != nil {
+						return err
+					}
+				}
+				first = false
+				return enc.Encode(l)
+			}
+			finish = func() error {
+				_, err := w.Write([]byte("]\n"))
+				return err
+			}
+		}
+
+		// the status line is already sent, errors past this point can only be logged
+		count := 0
+		err := urlWalker.WalkURLs(claims.Id, func(u models.UrlShortener) error {
+			count++
+			return write(NewLink(u))
+		})
+		if err == nil {
+			err = finish()
+		}
+		if err != nil {
+			log.Error("failed to export urls", "err", err)
+			return
+		}
+		log.Info("urls exported", "count", count, "format", format)
+	}
+}
@@ -0,0 +1,368 @@
+package url
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/access"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// Conflict modes decide what happens to rows whose alias already exists.
+const (
+	ConflictFail      = "fail"
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+)
+
+const (
+	RowCreated     = "created"
+	RowSkipped     = "skipped"
+	RowOverwritten = "overwritten"
+	RowFailed      = "error"
+)
+
+const maxImportSize = 32 << 20
+
+type ImportRow struct {
+	Row    int    `json:"row"`
+	Alias  string `json:"alias,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportResponse struct {
+	resp.Response
+	Created     int         `json:"created"`
+	Skipped     int         `json:"skipped"`
+	Overwritten int         `json:"overwritten"`
+	Failed      int         `json:"failed"`
+	Rows        []ImportRow `json:"rows"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=URLImporter
+type URLImporter interface {
+	SaveURL(models.UrlShortener) (int64, error)
+	GetLink(alias string) (*models.UrlShortener, error)
+	OverwriteURL(link models.UrlShortener) error
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+// rowError is a problem with a single row that does not stop the import.
+type rowError struct {
+	err error
+}
+
+func (e rowError) Error() string {
+	return e.err.Error()
+}
+
+// ImportHandler reads links in the format given by the format query
+// parameter and saves them for the caller. Every row is validated like a
+// Request; the conflict query parameter picks how existing aliases are
+// handled. fail stops the import at the first conflict without undoing the
+// rows saved before it, they are listed as created. Only links the caller
+// may edit are overwritten, those of other users count as taken aliases.
+// Overwritten links take every exported field but keep their owner, clicks
+// and creation time; metadata and health are fetched anew. Expiry times are
+// imported as they are, even when they have passed.
+func ImportHandler(log *slog.Logger, importer URLImporter, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		query := r.URL.Query()
+		conflict := query.Get("conflict")
+		if conflict == "" {
+			conflict = ConflictFail
+		}
+		if conflict != ConflictFail && conflict != ConflictSkip && conflict != ConflictOverwrite {
+			resp.RenderError(w, r, http.StatusBadRequest, "unknown conflict mode")
+			return
+		}
+		next, err := newRowReader(query.Get("format"), http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			resp.RenderError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		result := ImportResponse{Response: resp.OK(), Rows: []ImportRow{}}
+		for row := 1; ; row++ {
+			link, err := next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			var rowErr rowError
+			if err != nil && !errors.As(err, &rowErr) {
+				log.Error("failed to read import", "err", err)
+				resp.RenderError(w, r, http.StatusBadRequest, "failed to read import: "+err.Error())
+				return
+			}
+			if err == nil {
+				err = link.Validate(log)
+			}
+			if err == nil {
+				err = importLink(importer, opts, link, claims, conflict, &result, row)
+			}
+			if errors.Is(err, storage.ErrUrlExists) && conflict == ConflictFail {
+				result.addError(row, link.Alias, err)
+				result.Response = resp.Error(fmt.Sprintf("row %d: url already exists", row))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, result)
+				return
+			}
+			if err != nil {
+				result.addError(row, link.Alias, err)
+			}
+		}
+		log.Info("urls imported", "created", result.Created, "skipped", result.Skipped,
+			"overwritten", result.Overwritten, "failed", result.Failed)
+		render.JSON(w, r, result)
+	}
+}
+
+func importLink(importer URLImporter, opts Options, link Link, claims *jwt_helper.UserClaims, conflict string, result *ImportResponse, row int) error {
+	if link.WorkspaceID != 0 {
+		_, err := importer.WorkspaceRole(link.WorkspaceID, claims.Id)
+		if errors.Is(err, storage.ErrNotMember) {
+			return errors.New("not a workspace member")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	imported := link.Model()
+	imported.UserId = claims.Id
+	saved, err := trySaveAlias(imported, importer, opts.Aliases)
+	if err == nil {
+		if opts.Metadata != nil {
+			opts.Metadata.Enqueue(saved.Id, saved.Url)
+		}
+		if opts.Events != nil {
+			opts.Events.Publish(webhook.EventLinkCreated, saved)
+		}
+		result.Created++
+		result.Rows = append(result.Rows, ImportRow{Row: row, Alias: saved.Alias, Status: RowCreated})
+		return nil
+	}
+	if !errors.Is(err, storage.ErrUrlExists) || conflict == ConflictFail {
+		return err
+	}
+	if conflict == ConflictSkip {
+		result.Skipped++
+		result.Rows = append(result.Rows, ImportRow{Row: row, Alias: link.Alias, Status: RowSkipped})
+		return nil
+	}
+
+	existing, err := importer.GetLink(link.Alias)
+	if err == nil {
+		err = access.Check(importer, claims, existing, access.Edit)
+	}
+	if errors.Is(err, storage.ErrUrlNotFound) {
+		// the alias is taken by a link the caller cannot see
+		return storage.ErrUrlExists
+	}
+	if err != nil {
+		return err
+	}
+	imported.UrlHash, err = urlnorm.Hash(link.URL)
+	if err != nil {
+		return err
+	}
+	imported.Id = existing.Id
+	imported.UserId = existing.UserId
+	imported.CreatedAt = existing.CreatedAt
+	imported.Clicks = existing.Clicks
+	if err := importer.OverwriteURL(imported); err != nil {
+		return err
+	}
+	if opts.Metadata != nil {
+		opts.Metadata.Enqueue(imported.Id, imported.Url)
+	}
+	if opts.Events != nil {
+		opts.Events.Publish(webhook.EventLinkUpdated, imported)
+	}
+	result.Overwritten++
+	result.Rows = append(result.Rows, ImportRow{Row: row, Alias: link.Alias, Status: RowOverwritten})
+	return nil
+}
+
+// Validate checks an imported link with the rules of POST /url. Expiry times
+// in the past are accepted, such links are imported as expired.
+func (l Link) Validate(log *slog.Logger) error {
+	return validateRequest(Request{
+		URL:         l.URL,
+		Alias:       l.Alias,
+		UTM:         l.UTM,
+		Rules:       l.Rules,
+		Variants:    requestVariants(l.Variants),
+		Title:       l.Title,
+		WorkspaceID: l.WorkspaceID,
+	}, log)
+}
+
+// Model returns the link to save. The owner and the URL hash are left to the
+// caller.
+func (l Link) Model() models.UrlShortener {
+	return models.UrlShortener{
+		Alias:          l.Alias,
+		Url:            l.URL,
+		WorkspaceId:    l.WorkspaceID,
+		CreatedAt:      l.CreatedAt,
+		Clicks:         l.Clicks,
+		UTM:            l.UTM.model(),
+		ForwardQuery:   l.ForwardQuery,
+		Rules:          ruleModels(l.Rules),
+		Variants:       importedVariants(l.Variants),
+		StickyVariants: l.StickyVariants,
+		Title:          l.Title,
+		Preview:        l.Preview,
+		ExpiresAt:      timeValue(l.ExpiresAt),
+	}
+}
+
+// requestVariants returns the exported variants as requested ones for the
+// validation.
+func requestVariants(variants []VariantStats) []Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, Variant{URL: v.URL, Weight: v.Weight})
+	}
+	return out
+}
+
+// importedVariants returns the exported variants with their clicks, new ids
+// are assigned on save.
+func importedVariants(variants []VariantStats) []models.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]models.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, models.Variant{Target: v.URL, Weight: v.Weight, Clicks: v.Clicks})
+	}
+	return out
+}
+
+func (res *ImportResponse) addError(row int, alias string, err error) {
+	res.Failed++
+	res.Rows = append(res.Rows, ImportRow{Row: row, Alias: alias, Status: RowFailed, Error: err.Error()})
+}
+
+// newRowReader returns a function yielding one link per call and io.EOF at
+// the end of body.
+func newRowReader(format string, body io.Reader) (func() (Link, error), error) {
+	switch format {
+	case FormatCSV:
+		return csvRows(body)
+	case FormatJSONL:
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return func() (Link, error) {
+			var link Link
+			for scanner.Scan() {
+				line := bytes.TrimSpace(scanner.Bytes())
+				if len(line) == 0 {
+					continue
+				}
+				if err := json.Unmarshal(line, &link); err != nil {
+					return link, rowError{err}
+				}
+				return link, nil
+			}
+			if err := scanner.Err(); err != nil {
+				return link, err
+			}
+			return link, io.EOF
+		}, nil
+	case FormatJSON, "":
+		dec := json.NewDecoder(body)
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil, errors.New("json import must be an array")
+		}
+		return func() (Link, error) {
+			var link Link
+			if !dec.More() {
+				return link, io.EOF
+			}
+			err := dec.Decode(&link)
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return link, rowError{err}
+			}
+			return link, err
+		}, nil
+	default:
+		return nil, errors.New("unknown format")
+	}
+}
+
+func csvRows(body io.Reader) (func() (Link, error), error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("csv header has no url column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	return func() (Link, error) {
+		record, err := cr.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return Link{}, rowError{err}
+			}
+			return Link{}, err
+		}
+		link := Link{Alias: field(record, "alias"), URL: field(record, "url")}
+		if v := field(record, "created_at"); v != "" {
+			if link.CreatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+				return link, rowError{fmt.Errorf("bad created_at: %w", err)}
+			}
+		}
+		if v := field(record, "clicks"); v != "" {
+			if link.Clicks, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return link, rowError{fmt.Errorf("bad clicks: %w", err)}
+			}
+		}
+		return link, nil
+	}, nil
+}
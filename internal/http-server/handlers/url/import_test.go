@@ -0,0 +1,156 @@
+package url_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/url/mocks"
+	custommocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func savedAlias(alias string) interface{} {
+	return mock.MatchedBy(func(u models.UrlShortener) bool { return u.Alias == alias && u.UserId == 1 })
+}
+
+func TestImportHandler(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		conflict string
+		body     string
+		setup    func(m *mocks.URLImporter)
+		respCode int
+		statuses []string
+	}{
+		{
+			name:   "CSV with invalid row",
+			format: url.FormatCSV,
+			body:   "alias,url,clicks\nfirst,https://google.com,3\nsecond,not a url,0\n",
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveURL", savedAlias("first")).Return(int64(1), nil).Once()
+			},
+			respCode: http.StatusOK,
+			statuses: []string{url.RowCreated, url.RowFailed},
+		},
+		{
+			name:     "JSONL skip existing",
+			format:   url.FormatJSONL,
+			conflict: url.ConflictSkip,
+			body:     `{"alias":"taken","url":"https://google.com"}` + "\n" + `{broken` + "\n",
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveURL", savedAlias("taken")).Return(int64(0), storage.ErrUrlExists).Once()
+			},
+			respCode: http.StatusOK,
+			statuses: []string{url.RowSkipped, url.RowFailed},
+		},
+		{
+			name:   "JSON round trip of an export",
+			format: url.FormatJSON,
+			body: `[{"alias":"ab","url":"https://google.com","variants":[{"id":9,"url":"https://google.com/b","weight":1,"clicks":4}],` +
+				`"sticky_variants":true,"title":"Test","preview":true,"workspace_id":5},` +
+				`{"alias":"other","url":"https://google.com","workspace_id":6}]`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("WorkspaceRole", int64(5), int64(1)).Return(models.WorkspaceRoleMember, nil).Once()
+				m.On("WorkspaceRole", int64(6), int64(1)).Return("", storage.ErrNotMember).Once()
+				m.On("SaveURL", mock.MatchedBy(func(u models.UrlShortener) bool {
+					return u.Alias == "ab" && u.WorkspaceId == 5 && u.StickyVariants && u.Title == "Test" && u.Preview &&
+						len(u.Variants) == 1 && u.Variants[0] == models.Variant{Target: "https://google.com/b", Weight: 1, Clicks: 4}
+				})).Return(int64(1), nil).Once()
+			},
+			respCode: http.StatusOK,
+			statuses: []string{url.RowCreated, url.RowFailed},
+		},
+		{
+			name:     "JSON overwrite own, foreign and workspace links",
+			format:   url.FormatJSON,
+			conflict: url.ConflictOverwrite,
+			body: `[{"alias":"mine","url":"https://new.com","forward_query":true,"preview":true,"clicks":1},` +
+				`{"alias":"theirs","url":"https://new.com"},{"alias":"shared","url":"https://new.com"}]`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveURL", mock.Anything).Return(int64(0), storage.ErrUrlExists).Times(3)
+				m.On("GetLink", "mine").Return(&models.UrlShortener{Alias: "mine", UserId: 1, Clicks: 7}, nil).Once()
+				m.On("GetLink", "theirs").Return(&models.UrlShortener{Alias: "theirs", UserId: 2}, nil).Once()
+				m.On("GetLink", "shared").Return(&models.UrlShortener{Alias: "shared", UserId: 2, WorkspaceId: 5}, nil).Once()
+				m.On("WorkspaceRole", int64(5), int64(1)).Return(models.WorkspaceRoleMember, nil).Once()
+				m.On("OverwriteURL", mock.MatchedBy(func(u models.UrlShortener) bool {
+					return u.Alias == "shared" && u.UserId == 2
+				})).Return(nil).Once()
+				m.On("OverwriteURL", mock.MatchedBy(func(u models.UrlShortener) bool {
+					return u.Alias == "mine" && u.Url == "https://new.com" && u.UrlHash != "" && u.ForwardQuery && u.Preview &&
+						u.UserId == 1 && u.Clicks == 7
+				})).Return(nil).Once()
+			},
+			respCode: http.StatusOK,
+			statuses: []string{url.RowOverwritten, url.RowFailed, url.RowOverwritten},
+		},
+		{
+			name:   "Fail on conflict",
+			format: url.FormatJSONL,
+			body: `{"alias":"first","url":"https://google.com"}` + "\n" +
+				`{"alias":"taken","url":"https://google.com"}` + "\n" + `{"alias":"never","url":"https://google.com"}`,
+			setup: func(m *mocks.URLImporter) {
+				m.On("SaveURL", savedAlias("first")).Return(int64(1), nil).Once()
+				m.On("SaveURL", savedAlias("taken")).Return(int64(0), storage.ErrUrlExists).Once()
+			},
+			respCode: http.StatusConflict,
+			statuses: []string{url.RowCreated, url.RowFailed},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			importerMock := mocks.NewURLImporter(t)
+			tc.setup(importerMock)
+
+			logger := slog.New(custommocks.NewMockLogger())
+			handler := url.ImportHandler(logger, importerMock, url.Options{Aliases: testAliases})
+
+			target := "/url/import?format=" + tc.format + "&conflict=" + tc.conflict
+			r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(tc.body))
+			ctx := jwt_helper.WithClaims(context.Background(), &jwt_helper.UserClaims{Id: 1})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, w.Code)
+
+			var resp url.ImportResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Rows, len(tc.statuses))
+			for i, status := range tc.statuses {
+				require.Equal(t, status, resp.Rows[i].Status, resp.Rows[i].Error)
+			}
+		})
+	}
+}
+
+func TestImportHandlerEnqueuesMetadata(t *testing.T) {
+	importerMock := mocks.NewURLImporter(t)
+	importerMock.On("SaveURL", savedAlias("first")).Return(int64(42), nil).Once()
+
+	queue := &metadataQueue{}
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.ImportHandler(logger, importerMock, url.Options{Aliases: testAliases, Metadata: queue})
+
+	r := httptest.NewRequest(http.MethodPost, "/url/import?format=json", strings.NewReader(`[{"alias":"first","url":"https://google.com"}]`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r.WithContext(jwt_helper.WithClaims(context.Background(), &jwt_helper.UserClaims{Id: 1})))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, int64(42), queue.urlId)
+	require.Equal(t, "https://google.com", queue.target)
+}
@@ -0,0 +1,198 @@
+package url
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Link struct {
+	Alias          string         `json:"alias"`
+	URL            string         `json:"url"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Clicks         int64          `json:"clicks"`
+	UTM            *UTM           `json:"utm,omitempty"`
+	ForwardQuery   bool           `json:"forward_query"`
+	Rules          []Rule         `json:"rules,omitempty"`
+	Variants       []VariantStats `json:"variants,omitempty"`
+	StickyVariants bool           `json:"sticky_variants,omitempty"`
+	Title          string         `json:"title,omitempty"`
+	Preview        bool           `json:"preview,omitempty"`
+	Metadata       *Metadata      `json:"metadata,omitempty"`
+	Health         *Health        `json:"health,omitempty"`
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
+	WorkspaceID    int64          `json:"workspace_id,omitempty"`
+}
+
+// Metadata describes the destination page as fetched after the link was
+// created.
+type Metadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	FaviconURL  string    `json:"favicon_url,omitempty"`
+	FinalURL    string    `json:"final_url,omitempty"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Health is the result of the latest dead-link check of the destination.
+type Health struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+	// Failures counts the failed checks in a row.
+	Failures  int       `json:"failures"`
+	Broken    bool      `json:"broken"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Links []Link `json:"links"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=URLLister
+type URLLister interface {
+	ListURLs(userId int64) ([]models.UrlShortener, error)
+	ListBrokenURLs(userId int64, minFailures int) ([]models.UrlShortener, error)
+	ListWorkspaceURLs(workspaceId int64) ([]models.UrlShortener, error)
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+// ListHandler lists the links created by the caller, or with workspace_id the
+// links of one of the caller's workspaces. With broken=true only the links
+// whose last brokenAfter checks failed are listed.
+func ListHandler(log *slog.Logger, urlLister URLLister, brokenAfter int) http.HandlerFunc {
+	brokenAfter = max(brokenAfter, 1)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		broken := false
+		if v := r.URL.Query().Get("broken"); v != "" {
+			var err error
+			if broken, err = strconv.ParseBool(v); err != nil {
+				resp.RenderError(w, r, http.StatusBadRequest, "broken must be true or false")
+				return
+			}
+		}
+		var workspaceId int64
+		if v := r.URL.Query().Get("workspace_id"); v != "" {
+			var err error
+			if workspaceId, err = strconv.ParseInt(v, 10, 64); err != nil {
+				resp.RenderError(w, r, http.StatusBadRequest, "bad workspace_id")
+				return
+			}
+		}
+		var urls []models.UrlShortener
+		var err error
+		switch {
+		case workspaceId != 0:
+			urls, err = listWorkspaceURLs(urlLister, workspaceId, claims.Id, broken, brokenAfter)
+		case broken:
+			urls, err = urlLister.ListBrokenURLs(claims.Id, brokenAfter)
+		default:
+			urls, err = urlLister.ListURLs(claims.Id)
+		}
+		if errors.Is(err, storage.ErrNotMember) {
+			resp.RenderError(w, r, http.StatusForbidden, "not a workspace member")
+			return
+		}
+		if err != nil {
+			log.Error("failed to list urls", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		links := make([]Link, 0, len(urls))
+		for _, u := range urls {
+			link := NewLink(u)
+			link.Health = newHealth(u.Health, brokenAfter)
+			links = append(links, link)
+		}
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Links:    links,
+		})
+	}
+}
+
+func listWorkspaceURLs(lister URLLister, workspaceId, userId int64, broken bool, brokenAfter int) ([]models.UrlShortener, error) {
+	if _, err := lister.WorkspaceRole(workspaceId, userId); err != nil {
+		return nil, err
+	}
+	urls, err := lister.ListWorkspaceURLs(workspaceId)
+	if err != nil || !broken {
+		return urls, err
+	}
+	var brokenURLs []models.UrlShortener
+	for _, u := range urls {
+		if u.Health != nil && u.Health.Failures >= brokenAfter {
+			brokenURLs = append(brokenURLs, u)
+		}
+	}
+	return brokenURLs, nil
+}
+
+// NewLink returns the link as it is listed and exported.
+func NewLink(u models.UrlShortener) Link {
+	return Link{
+		Alias:          u.Alias,
+		URL:            u.Url,
+		CreatedAt:      u.CreatedAt,
+		Clicks:         u.Clicks,
+		UTM:            newUTM(u.UTM),
+		ForwardQuery:   u.ForwardQuery,
+		Rules:          newRules(u.Rules),
+		Variants:       newVariantStats(u.Variants),
+		StickyVariants: u.StickyVariants,
+		Title:          u.Title,
+		Preview:        u.Preview,
+		Metadata:       newMetadata(u.Metadata),
+		ExpiresAt:      newTime(u.ExpiresAt),
+		WorkspaceID:    u.WorkspaceId,
+	}
+}
+
+func newTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newMetadata(m *models.Metadata) *Metadata {
+	if m == nil {
+		return nil
+	}
+	meta := Metadata(*m)
+	return &meta
+}
+
+func newHealth(h *models.Health, brokenAfter int) *Health {
+	if h == nil {
+		return nil
+	}
+	return &Health{
+		StatusCode: h.StatusCode,
+		LatencyMs:  h.Latency.Milliseconds(),
+		Error:      h.Error,
+		Failures:   h.Failures,
+		Broken:     h.Failures >= brokenAfter,
+		CheckedAt:  h.CheckedAt,
+	}
+}
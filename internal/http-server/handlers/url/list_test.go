@@ -0,0 +1,180 @@
+package url_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/url/mocks"
+	custommocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandlerBroken(t *testing.T) {
+	checkedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthy := models.UrlShortener{Alias: "ok", Url: "https://example.com", UserId: 1,
+		Health: &models.Health{StatusCode: 200, Latency: 80 * time.Millisecond, CheckedAt: checkedAt}}
+	broken := models.UrlShortener{Alias: "gone", Url: "https://example.org", UserId: 1,
+		Health: &models.Health{StatusCode: 404, Failures: 3, CheckedAt: checkedAt}}
+	failing := models.UrlShortener{Alias: "flaky", Url: "https://example.net", UserId: 1,
+		Health: &models.Health{Error: "timeout", Failures: 1, CheckedAt: checkedAt}}
+
+	cases := []struct {
+		name      string
+		query     string
+		method    string
+		links     []models.UrlShortener
+		respError string
+		respCode  int
+		broken    []bool
+	}{
+		{
+			name:     "All links",
+			method:   "ListURLs",
+			links:    []models.UrlShortener{healthy, broken, failing, {Alias: "new", Url: "https://example.io", UserId: 1}},
+			respCode: http.StatusOK,
+			broken:   []bool{false, true, false, false},
+		},
+		{
+			name:     "Broken links",
+			query:    "?broken=true",
+			method:   "ListBrokenURLs",
+			links:    []models.UrlShortener{broken},
+			respCode: http.StatusOK,
+			broken:   []bool{true},
+		},
+		{
+			name:     "Broken false",
+			query:    "?broken=false",
+			method:   "ListURLs",
+			links:    []models.UrlShortener{healthy},
+			respCode: http.StatusOK,
+			broken:   []bool{false},
+		},
+		{
+			name:      "Bad broken value",
+			query:     "?broken=maybe",
+			respError: "broken must be true or false",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			listerMock := mocks.NewURLLister(t)
+			switch tc.method {
+			case "ListURLs":
+				listerMock.On("ListURLs", int64(1)).Return(tc.links, nil).Once()
+			case "ListBrokenURLs":
+				listerMock.On("ListBrokenURLs", int64(1), 3).Return(tc.links, nil).Once()
+			}
+
+			handler := url.ListHandler(slog.New(custommocks.NewMockLogger()), listerMock, 3)
+			r := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			r = r.WithContext(jwt_helper.WithClaims(r.Context(), &jwt_helper.UserClaims{Id: 1}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tc.respCode, w.Code)
+			var resp url.ListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Len(t, resp.Links, len(tc.broken))
+			for i, link := range resp.Links {
+				if tc.links[i].Health == nil {
+					require.Nil(t, link.Health)
+					continue
+				}
+				require.Equal(t, tc.broken[i], link.Health.Broken, link.Alias)
+				require.Equal(t, tc.links[i].Health.Failures, link.Health.Failures)
+			}
+		})
+	}
+}
+
+func TestListHandlerWorkspace(t *testing.T) {
+	shared := []models.UrlShortener{
+		{Alias: "spring", Url: "https://example.com/spring", UserId: 2, WorkspaceId: 5},
+		{Alias: "gone", Url: "https://example.org", UserId: 1, WorkspaceId: 5,
+			Health: &models.Health{StatusCode: 404, Failures: 3}},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		member    bool
+		aliases   []string
+		respError string
+		respCode  int
+	}{
+		{
+			name:     "Workspace links",
+			query:    "?workspace_id=5",
+			member:   true,
+			aliases:  []string{"spring", "gone"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Broken workspace links",
+			query:    "?workspace_id=5&broken=true",
+			member:   true,
+			aliases:  []string{"gone"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not a member",
+			query:     "?workspace_id=5",
+			respError: "not a workspace member",
+			respCode:  http.StatusForbidden,
+		},
+		{
+			name:      "Bad workspace id",
+			query:     "?workspace_id=marketing",
+			respError: "bad workspace_id",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			listerMock := mocks.NewURLLister(t)
+			if tc.member {
+				listerMock.On("WorkspaceRole", int64(5), int64(1)).Return(models.WorkspaceRoleMember, nil).Once()
+				listerMock.On("ListWorkspaceURLs", int64(5)).Return(shared, nil).Once()
+			} else if tc.respCode == http.StatusForbidden {
+				listerMock.On("WorkspaceRole", int64(5), int64(1)).Return("", storage.ErrNotMember).Once()
+			}
+
+			handler := url.ListHandler(slog.New(custommocks.NewMockLogger()), listerMock, 3)
+			r := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			r = r.WithContext(jwt_helper.WithClaims(r.Context(), &jwt_helper.UserClaims{Id: 1}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tc.respCode, w.Code)
+			var resp url.ListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			var aliases []string
+			for _, link := range resp.Links {
+				require.Equal(t, int64(5), link.WorkspaceID)
+				aliases = append(aliases, link.Alias)
+			}
+			require.Equal(t, tc.aliases, aliases)
+		})
+	}
+}
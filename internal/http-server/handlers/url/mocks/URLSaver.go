@@ -2,16 +2,49 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+	models "url-shortener/internal/models"
+)
 
 // URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
 	mock.Mock
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// FindDuplicateURL provides a mock function with given fields: link
+func (_m *URLSaver) FindDuplicateURL(link models.UrlShortener) (*models.UrlShortener, error) {
+	ret := _m.Called(link)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindDuplicateURL")
+	}
+
+	var r0 *models.UrlShortener
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.UrlShortener) (*models.UrlShortener, error)); ok {
+		return rf(link)
+	}
+	if rf, ok := ret.Get(0).(func(models.UrlShortener) *models.UrlShortener); ok {
+		r0 = rf(link)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UrlShortener)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.UrlShortener) error); ok {
+		r1 = rf(link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveURL provides a mock function with given fields: _a0
+func (_m *URLSaver) SaveURL(_a0 models.UrlShortener) (int64, error) {
+	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveURL")
@@ -19,17 +52,45 @@ func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(models.UrlShortener) (int64, error)); ok {
+		return rf(_a0)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(models.UrlShortener) int64); ok {
+		r0 = rf(_a0)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(models.UrlShortener) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WorkspaceRole provides a mock function with given fields: workspaceId, userId
+func (_m *URLSaver) WorkspaceRole(workspaceId int64, userId int64) (string, error) {
+	ret := _m.Called(workspaceId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WorkspaceRole")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, int64) (string, error)); ok {
+		return rf(workspaceId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(int64, int64) string); ok {
+		r0 = rf(workspaceId, userId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, int64) error); ok {
+		r1 = rf(workspaceId, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
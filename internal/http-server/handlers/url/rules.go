@@ -0,0 +1,75 @@
+package url
+
+import (
+	"time"
+	"url-shortener/internal/models"
+)
+
+// Rule sends visitors matching all of its conditions to URL instead of the
+// link's default destination. Rules are evaluated in order.
+type Rule struct {
+	OS        []string   `json:"os,omitempty" validate:"dive,oneof=ios android windows macos linux other"`
+	Devices   []string   `json:"devices,omitempty" validate:"dive,oneof=mobile tablet desktop bot"`
+	Languages []string   `json:"languages,omitempty" validate:"dive,bcp47_language_tag"`
+	CIDRs     []string   `json:"cidrs,omitempty" validate:"dive,cidr"`
+	Countries []string   `json:"countries,omitempty" validate:"dive,iso3166_1_alpha2"`
+	Start     *time.Time `json:"start,omitempty"`
+	End       *time.Time `json:"end,omitempty"`
+	URL       string     `json:"url" validate:"required,url"`
+}
+
+func (r Rule) model() models.Rule {
+	rule := models.Rule{
+		OS:        r.OS,
+		Devices:   r.Devices,
+		Languages: r.Languages,
+		CIDRs:     r.CIDRs,
+		Countries: r.Countries,
+		Target:    r.URL,
+	}
+	if r.Start != nil {
+		rule.Start = *r.Start
+	}
+	if r.End != nil {
+		rule.End = *r.End
+	}
+	return rule
+}
+
+func ruleModels(rules []Rule) []models.Rule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]models.Rule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, r.model())
+	}
+	return out
+}
+
+func newRules(rules []models.Rule) []Rule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]Rule, 0, len(rules))
+	for _, m := range rules {
+		r := Rule{
+			OS:        m.OS,
+			Devices:   m.Devices,
+			Languages: m.Languages,
+			CIDRs:     m.CIDRs,
+			Countries: m.Countries,
+			URL:       m.Target,
+		}
+		if !m.Start.IsZero() {
+			start := m.Start
+			r.Start = &start
+		}
+		if !m.End.IsZero() {
+			end := m.End
+			r.End = &end
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -2,14 +2,20 @@ package url
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
+	"time"
+	alias_generator "url-shortener/internal/lib/alias-generator"
 	resp "url-shortener/internal/lib/api/response"
 	custom_validators "url-shortener/internal/lib/custom-validators"
-	"url-shortener/internal/lib/random"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/rules"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/lib/webhook"
 	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
@@ -17,53 +23,186 @@ import (
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty" validate:"isValidAlias"`
+	// Dedupe returns the caller's existing alias for the same normalized URL
+	// instead of creating a new one. Defaults to Options.Dedupe.
+	Dedupe *bool `json:"dedupe,omitempty"`
+	// UTM parameters are added to the destination on every redirect.
+	UTM *UTM `json:"utm,omitempty"`
+	// ForwardQuery appends the query string of the short link request to the
+	// destination.
+	ForwardQuery bool   `json:"forward_query,omitempty"`
+	Rules        []Rule `json:"rules,omitempty" validate:"max=20,dive"`
+	// Variants split the traffic across several destinations by weight.
+	Variants       []Variant `json:"variants,omitempty" validate:"max=10,dive"`
+	StickyVariants bool      `json:"sticky_variants,omitempty"`
+	Title          string    `json:"title,omitempty" validate:"max=256"`
+	// Preview shows a page describing the destination instead of redirecting.
+	Preview bool `json:"preview,omitempty"`
+	// ExpiresAt ends the lifetime of the link, it must be in the future.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// WorkspaceID makes the link owned by a workspace of the caller.
+	WorkspaceID int64 `json:"workspace_id,omitempty"`
+}
+
+type UTM struct {
+	Source   string `json:"source,omitempty" validate:"max=256"`
+	Medium   string `json:"medium,omitempty" validate:"max=256"`
+	Campaign string `json:"campaign,omitempty" validate:"max=256"`
+	Term     string `json:"term,omitempty" validate:"max=256"`
+	Content  string `json:"content,omitempty" validate:"max=256"`
+}
+
+func (u *UTM) model() models.UTM {
+	if u == nil {
+		return models.UTM{}
+	}
+	return models.UTM(*u)
+}
+
+func newUTM(m models.UTM) *UTM {
+	if m == (models.UTM{}) {
+		return nil
+	}
+	u := UTM(m)
+	return &u
 }
 
 type Response struct {
 	resp.Response
 	Alias string `json:"alias,omitempty"`
+	// Existing is set when an existing alias was returned by deduplication.
+	Existing bool `json:"existing,omitempty"`
+}
+
+// Options configures link creation.
+type Options struct {
+	Aliases alias_generator.Policy
+	// Dedupe is used for requests that do not set dedupe themselves.
+	Dedupe bool
+	// Metadata schedules fetching the destination of new links, nil disables
+	// fetching.
+	Metadata MetadataQueue
+	// Events is notified of created and updated links and may be nil.
+	Events LinkEvents
+}
+
+type MetadataQueue interface {
+	Enqueue(urlId int64, target string)
+}
+
+type LinkEvents interface {
+	Publish(event string, link models.UrlShortener)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=URLSaver
 type URLSaver interface {
 	SaveURL(models.UrlShortener) (int64, error)
+	FindDuplicateURL(link models.UrlShortener) (*models.UrlShortener, error)
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+// aliasSaver is the part of URLSaver and URLImporter used by trySaveAlias.
+type aliasSaver interface {
+	SaveURL(models.UrlShortener) (int64, error)
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+func New(log *slog.Logger, urlSaver URLSaver, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log = log.With(
+		log := log.With(
 			"request_id", middleware.GetReqID(r.Context()),
 		)
 		var req Request
 
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
 			log.Error("failed to decode request body", "err", err)
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("failed to decode request body"))
+			resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
 			return
 		}
 		log.Debug("request body decoded", "body", req)
 
-		if err := validateRequest(r, log); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, resp.Error(err.Error()))
+		if err := validateRequest(req, log); err != nil {
+			resp.RenderValidationError(w, r, err)
 			return
 		}
 
-		urlShortener, err := trySaveAlias(req, urlSaver)
+		var userId int64
+		if claims, ok := jwt_helper.ClaimsFromContext(r.Context()); ok {
+			userId = claims.Id
+		}
+
+		if req.WorkspaceID != 0 {
+			_, err := urlSaver.WorkspaceRole(req.WorkspaceID, userId)
+			if errors.Is(err, storage.ErrNotMember) {
+				log.Info("not a workspace member", "workspace_id", req.WorkspaceID)
+				resp.RenderError(w, r, http.StatusForbidden, "not a workspace member")
+				return
+			}
+			if err != nil {
+				log.Error("failed to look up workspace role", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "failed to save url")
+				return
+			}
+		}
+
+		dedupe := opts.Dedupe
+		if req.Dedupe != nil {
+			dedupe = *req.Dedupe
+		}
+		// a custom alias is an explicit request for a new link
+		if dedupe && req.Alias == "" && len(req.Rules) == 0 && len(req.Variants) == 0 && req.ExpiresAt == nil && userId != 0 {
+			existing, err := findDuplicate(urlSaver, userId, req)
+			if err != nil {
+				log.Error("failed to look up duplicate url", "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "failed to save url")
+				return
+			}
+			if existing != nil {
+				log.Info("returning existing alias", "id", existing.Id)
+				render.JSON(w, r, Response{
+					Response: resp.OK(),
+					Alias:    existing.Alias,
+					Existing: true,
+				})
+				return
+			}
+		}
+
+		urlShortener, err := trySaveAlias(models.UrlShortener{
+			Url:            req.URL,
+			Alias:          req.Alias,
+			UserId:         userId,
+			WorkspaceId:    req.WorkspaceID,
+			UTM:            req.UTM.model(),
+			ForwardQuery:   req.ForwardQuery,
+			Rules:          ruleModels(req.Rules),
+			Variants:       variantModels(req.Variants),
+			StickyVariants: req.StickyVariants,
+			Title:          req.Title,
+			Preview:        req.Preview,
+			ExpiresAt:      timeValue(req.ExpiresAt),
+		}, urlSaver, opts.Aliases)
 		if errors.Is(err, storage.ErrUrlExists) {
 			log.Info("url already exists", "url", req.URL)
-			w.WriteHeader(http.StatusConflict)
-			render.JSON(w, r, resp.Error("url already exists"))
+			resp.RenderError(w, r, http.StatusConflict, "url already exists")
+			return
+		}
+		if errors.Is(err, alias_generator.ErrExhausted) {
+			log.Error("failed to generate a free alias", "err", err)
+			resp.RenderError(w, r, http.StatusServiceUnavailable, "failed to generate alias")
 			return
 		}
 		if err != nil {
 			log.Error("failed to save url", "err", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to save url"))
+			resp.RenderError(w, r, http.StatusInternalServerError, "failed to save url")
 			return
 		}
 		log.Info("url saved", "id", urlShortener.Id)
+		if opts.Metadata != nil {
+			opts.Metadata.Enqueue(urlShortener.Id, urlShortener.Url)
+		}
+		if opts.Events != nil {
+			opts.Events.Publish(webhook.EventLinkCreated, urlShortener)
+		}
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
 			Alias:    urlShortener.Alias,
@@ -71,21 +210,45 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 	}
 }
 
-func trySaveAlias(req Request, saver URLSaver) (models.UrlShortener, error) {
-	aliasProvided := true
-	var urlShortener models.UrlShortener
-	for {
-		if req.Alias == "" {
-			aliasProvided = false
-			req.Alias = random.NewRandomString(resp.AliasFixedLength)
-		}
-		urlShortener = models.UrlShortener{
-			Url:   req.URL,
-			Alias: req.Alias,
+// findDuplicate returns the link of userId with the same normalized URL,
+// workspace and redirect options as req, or nil when there is none.
+func findDuplicate(saver URLSaver, userId int64, req Request) (*models.UrlShortener, error) {
+	urlHash, err := urlnorm.Hash(req.URL)
+	if err != nil {
+		return nil, nil
+	}
+	existing, err := saver.FindDuplicateURL(models.UrlShortener{
+		UrlHash:      urlHash,
+		UserId:       userId,
+		WorkspaceId:  req.WorkspaceID,
+		UTM:          req.UTM.model(),
+		ForwardQuery: req.ForwardQuery,
+		Preview:      req.Preview,
+	})
+	if errors.Is(err, storage.ErrUrlNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// trySaveAlias saves urlShortener, generating an alias when it has none.
+// Generated aliases are retried on collisions as long as the policy allows.
+func trySaveAlias(urlShortener models.UrlShortener, saver aliasSaver, aliases alias_generator.Policy) (models.UrlShortener, error) {
+	aliasProvided := urlShortener.Alias != ""
+	urlShortener.UrlHash, _ = urlnorm.Hash(urlShortener.Url)
+	for attempt := 0; ; attempt++ {
+		if !aliasProvided {
+			alias, err := aliases.Next(attempt)
+			if err != nil {
+				return urlShortener, err
+			}
+			urlShortener.Alias = alias
 		}
 		id, err := saver.SaveURL(urlShortener)
 		if errors.Is(err, storage.ErrUrlExists) && !aliasProvided {
-			req.Alias = ""
 			continue
 		}
 		urlShortener.Id = id
@@ -93,7 +256,7 @@ func trySaveAlias(req Request, saver URLSaver) (models.UrlShortener, error) {
 	}
 }
 
-func validateRequest(req *http.Request, log *slog.Logger) error {
+func validateRequest(req Request, log *slog.Logger) error {
 	validate := validator.New()
 	err := validate.RegisterValidation("isValidAlias", custom_validators.AliasValidation)
 	if err != nil {
@@ -107,5 +270,48 @@ func validateRequest(req *http.Request, log *slog.Logger) error {
 		log.Error("failed to validate request", "err", err.Error())
 		return err
 	}
+	var fieldErrs custom_validators.ValidationErrors
+	for i, rule := range req.Rules {
+		if err := rules.Validate(rule.model()); err != nil {
+			fieldErrs = append(fieldErrs, custom_validators.FieldError{
+				Field:  fmt.Sprintf("Rules[%d]", i),
+				Rule:   "rule",
+				Detail: fmt.Sprintf("rule %d: %s", i+1, err),
+			})
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		fieldErrs = append(fieldErrs, custom_validators.FieldError{
+			Field:  "ExpiresAt",
+			Rule:   "future",
+			Detail: "field ExpiresAt must be in the future",
+		})
+	}
+	if len(req.Variants) > 0 && totalWeight(req.Variants) == 0 {
+		fieldErrs = append(fieldErrs, custom_validators.FieldError{
+			Field:  "Variants",
+			Rule:   "weight",
+			Detail: "variant weights must not all be zero",
+		})
+	}
+	if len(fieldErrs) > 0 {
+		log.Error("failed to validate request", "err", fieldErrs.Error())
+		return fieldErrs
+	}
 	return nil
 }
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func totalWeight(variants []Variant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	return total
+}
@@ -11,46 +11,65 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"url-shortener/internal/http-server/handlers/url"
 	"url-shortener/internal/http-server/handlers/url/mocks"
+	alias_generator "url-shortener/internal/lib/alias-generator"
+	resp "url-shortener/internal/lib/api/response"
 	custommocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
 )
 
+var testAliases = alias_generator.Policy{
+	Generator:   alias_generator.NewRandom(alias_generator.DefaultLength),
+	MaxAttempts: 3,
+}
+
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
 		name      string
 		alias     string
 		url       string
 		respError string
+		respCode  int
 		mockError error
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
-			url:   "https://google.com",
+			name:     "Success",
+			alias:    "test_alias",
+			url:      "https://google.com",
+			respCode: http.StatusOK,
 		},
 		{
-			name:  "Empty alias",
-			alias: "",
-			url:   "https://google.com",
+			name:     "Empty alias",
+			alias:    "",
+			url:      "https://google.com",
+			respCode: http.StatusOK,
 		},
 		{
 			name:      "Empty URL",
 			url:       "",
 			alias:     "some_alias",
-			respError: "failed to validate request",
+			respError: "field URL is required",
+			respCode:  http.StatusBadRequest,
 		},
 		{
 			name:      "Invalid URL",
 			url:       "some invalid URL",
 			alias:     "some_alias",
-			respError: "failed to validate request",
+			respError: "field URL is not a valid URL",
+			respCode:  http.StatusBadRequest,
 		},
 		{
 			name:      "SaveURL Error",
 			alias:     "test_alias",
 			url:       "https://google.com",
 			respError: "failed to save url",
+			respCode:  http.StatusInternalServerError,
 			mockError: errors.New("unexpected error"),
 		},
 	}
@@ -64,12 +83,12 @@ func TestSaveHandler(t *testing.T) {
 			urlSaverMock := mocks.NewURLSaver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", mock.AnythingOfType("models.UrlShortener")).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
 			logger := slog.New(custommocks.NewMockLogger())
-			handler := url.New(logger, urlSaverMock)
+			handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases})
 
 			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
 
@@ -79,7 +98,7 @@ func TestSaveHandler(t *testing.T) {
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.respCode, rr.Code)
 
 			body := rr.Body.String()
 
@@ -91,3 +110,350 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveHandlerRules(t *testing.T) {
+	cases := []struct {
+		name      string
+		rules     string
+		respError string
+	}{
+		{
+			name:  "Valid rules",
+			rules: `[{"os": ["ios"], "url": "https://apps.apple.com/app/id1"}, {"countries": ["DE"], "cidrs": ["192.0.2.0/24"], "languages": ["de-AT"], "url": "https://example.de"}]`,
+		},
+		{
+			name:      "Unknown OS",
+			rules:     `[{"os": ["beos"], "url": "https://example.com"}]`,
+			respError: "field OS[0] is not valid",
+		},
+		{
+			name:      "Missing target",
+			rules:     `[{"os": ["ios"]}]`,
+			respError: "field URL is required",
+		},
+		{
+			name:      "Bad CIDR",
+			rules:     `[{"cidrs": ["10.0.0.0/33"], "url": "https://example.com"}]`,
+			respError: "field CIDRs[0] is not valid",
+		},
+		{
+			name:      "No conditions",
+			rules:     `[{"url": "https://example.com"}]`,
+			respError: "rule 1: rule has no conditions",
+		},
+		{
+			name:      "Reversed window",
+			rules:     `[{"start": "2025-02-01T00:00:00Z", "end": "2025-01-01T00:00:00Z", "url": "https://example.com"}]`,
+			respError: "rule 1: rule ends before it starts",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", mock.MatchedBy(func(u models.UrlShortener) bool {
+					return len(u.Rules) == 2 && u.Rules[1].Target == "https://example.de"
+				})).Return(int64(1), nil).Once()
+			}
+
+			logger := slog.New(custommocks.NewMockLogger())
+			handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases})
+
+			input := fmt.Sprintf(`{"url": "https://example.com", "rules": %s}`, tc.rules)
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp url.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			}
+		})
+	}
+}
+
+func TestSaveHandlerAliasExhausted(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.AnythingOfType("models.UrlShortener")).
+		Return(int64(0), storage.ErrUrlExists).
+		Times(testAliases.MaxAttempts)
+
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases})
+
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com"}`)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestSaveHandlerDedupe(t *testing.T) {
+	urlHash, err := urlnorm.Hash("https://example.com/")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		input  string
+		dedupe bool
+		lookup bool
+		// query holds the redirect options looked up besides owner and hash
+		query    models.UrlShortener
+		existing *models.UrlShortener
+		alias    string
+		reused   bool
+	}{
+		{
+			name:     "Config default",
+			input:    `{"url": "HTTPS://Example.com:443"}`,
+			dedupe:   true,
+			lookup:   true,
+			existing: &models.UrlShortener{Id: 1, Alias: "existing"},
+			alias:    "existing",
+			reused:   true,
+		},
+		{
+			name:     "Request flag",
+			input:    `{"url": "https://example.com", "dedupe": true}`,
+			lookup:   true,
+			existing: &models.UrlShortener{Id: 1, Alias: "existing"},
+			alias:    "existing",
+			reused:   true,
+		},
+		{
+			name:   "No existing link",
+			input:  `{"url": "https://example.com"}`,
+			dedupe: true,
+			lookup: true,
+		},
+		{
+			name:   "Redirect options are matched",
+			input:  `{"url": "https://example.com", "utm": {"source": "mail"}, "forward_query": true, "preview": true}`,
+			dedupe: true,
+			lookup: true,
+			query: models.UrlShortener{
+				UTM:          models.UTM{Source: "mail"},
+				ForwardQuery: true,
+				Preview:      true,
+			},
+		},
+		{
+			name:   "Disabled by request",
+			input:  `{"url": "https://example.com", "dedupe": false}`,
+			dedupe: true,
+		},
+		{
+			name:   "Custom alias",
+			input:  `{"url": "https://example.com", "alias": "custom"}`,
+			dedupe: true,
+			alias:  "custom",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.lookup {
+				var err error
+				if tc.existing == nil {
+					err = storage.ErrUrlNotFound
+				}
+				query := tc.query
+				query.UserId, query.UrlHash = 7, urlHash
+				urlSaverMock.On("FindDuplicateURL", query).Return(tc.existing, err).Once()
+			}
+			if !tc.reused {
+				urlSaverMock.On("SaveURL", mock.MatchedBy(func(u models.UrlShortener) bool {
+					return u.UrlHash == urlHash && u.UserId == 7
+				})).Return(int64(2), nil).Once()
+			}
+
+			logger := slog.New(custommocks.NewMockLogger())
+			handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases, Dedupe: tc.dedupe})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(tc.input)))
+			require.NoError(t, err)
+			req = req.WithContext(jwt_helper.WithClaims(req.Context(), &jwt_helper.UserClaims{Id: 7}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp url.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.reused, resp.Existing)
+			if tc.alias != "" {
+				require.Equal(t, tc.alias, resp.Alias)
+			}
+		})
+	}
+}
+
+func TestSaveHandlerProblem(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases})
+
+	input := `{"url": "some invalid URL", "alias": "url"}`
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/problem+json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, resp.ContentTypeProblem, rr.Header().Get("Content-Type"))
+
+	var problem resp.Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+
+	require.Equal(t, resp.ProblemTypeValidation, problem.Type)
+	require.Equal(t, http.StatusBadRequest, problem.Status)
+	require.Len(t, problem.Errors, 2)
+	require.Equal(t, "URL", problem.Errors[0].Field)
+	require.Equal(t, "url", problem.Errors[0].Rule)
+	require.Equal(t, "Alias", problem.Errors[1].Field)
+	require.Equal(t, "isValidAlias", problem.Errors[1].Rule)
+}
+
+type metadataQueue struct {
+	urlId  int64
+	target string
+}
+
+func (q *metadataQueue) Enqueue(urlId int64, target string) {
+	q.urlId, q.target = urlId, target
+}
+
+func TestSaveHandlerEnqueuesMetadata(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.AnythingOfType("models.UrlShortener")).Return(int64(42), nil).Once()
+
+	queue := &metadataQueue{}
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases, Metadata: queue})
+
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://example.com/page"}`)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, int64(42), queue.urlId)
+	require.Equal(t, "https://example.com/page", queue.target)
+}
+
+type linkEvents struct {
+	events []string
+	links  []models.UrlShortener
+}
+
+func (e *linkEvents) Publish(event string, link models.UrlShortener) {
+	e.events = append(e.events, event)
+	e.links = append(e.links, link)
+}
+
+func TestSaveHandlerPublishesCreated(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.AnythingOfType("models.UrlShortener")).Return(int64(42), nil).Once()
+
+	events := &linkEvents{}
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases, Events: events})
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"url": "https://example.com/page", "alias": "page", "expires_at": %q}`, expiresAt.Format(time.RFC3339))
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, []string{webhook.EventLinkCreated}, events.events)
+	require.Equal(t, int64(42), events.links[0].Id)
+	require.Equal(t, "page", events.links[0].Alias)
+	require.Equal(t, expiresAt, events.links[0].ExpiresAt)
+}
+
+func TestSaveHandlerRejectsPastExpiry(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	logger := slog.New(custommocks.NewMockLogger())
+	handler := url.New(logger, urlSaverMock, url.Options{Aliases: testAliases})
+
+	body := `{"url": "https://example.com/page", "expires_at": "2020-01-01T00:00:00Z"}`
+	req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	var res url.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	require.Equal(t, "field ExpiresAt must be in the future", res.Error)
+}
+
+func TestSaveHandlerWorkspace(t *testing.T) {
+	cases := []struct {
+		name      string
+		memberErr error
+		respError string
+		respCode  int
+	}{
+		{name: "Member", respCode: http.StatusOK},
+		{name: "Not a member", memberErr: storage.ErrNotMember, respError: "not a workspace member", respCode: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("WorkspaceRole", int64(5), int64(1)).Return(models.WorkspaceRoleMember, tc.memberErr).Once()
+			var saved models.UrlShortener
+			if tc.memberErr == nil {
+				urlSaverMock.On("SaveURL", mock.AnythingOfType("models.UrlShortener")).
+					Run(func(args mock.Arguments) { saved = args.Get(0).(models.UrlShortener) }).
+					Return(int64(1), nil).
+					Once()
+			}
+			handler := url.New(slog.New(custommocks.NewMockLogger()), urlSaverMock, url.Options{Aliases: testAliases})
+
+			body := `{"url": "https://example.com/spring", "alias": "spring", "workspace_id": 5}`
+			req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewReader([]byte(body)))
+			req = req.WithContext(jwt_helper.WithClaims(req.Context(), &jwt_helper.UserClaims{Id: 1}))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			var resp url.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.memberErr == nil {
+				require.Equal(t, int64(5), saved.WorkspaceId)
+				require.Equal(t, int64(1), saved.UserId)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package url
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/lib/access"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type StatsResponse struct {
+	resp.Response
+	Link
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=LinkGetter
+type LinkGetter interface {
+	GetLink(alias string) (*models.UrlShortener, error)
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+// StatsHandler returns the click statistics of a link the caller may view,
+// one of their own or of one of their workspaces. Other links are reported as
+// not found.
+func StatsHandler(log *slog.Logger, linkGetter LinkGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		alias := chi.URLParam(r, "alias")
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		link, err := linkGetter.GetLink(alias)
+		if err == nil {
+			err = access.Check(linkGetter, claims, link, access.View)
+		}
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", "alias", alias)
+			resp.RenderError(w, r, http.StatusNotFound, "url not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", "alias", alias, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		render.JSON(w, r, StatsResponse{
+			Response: resp.OK(),
+			Link:     NewLink(*link),
+		})
+	}
+}
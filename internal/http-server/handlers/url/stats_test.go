@@ -0,0 +1,105 @@
+package url_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/url/mocks"
+	custommocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestStatsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		link      *models.UrlShortener
+		mockError error
+		// memberError is the result of the workspace membership lookup
+		memberError error
+		respError   string
+		respCode    int
+	}{
+		{
+			name:     "Own link",
+			alias:    "mine",
+			link:     &models.UrlShortener{Alias: "mine", Url: "https://google.com", UserId: 1, Clicks: 5},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Foreign link",
+			alias:     "theirs",
+			link:      &models.UrlShortener{Alias: "theirs", Url: "https://google.com", UserId: 2},
+			respError: "url not found",
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:     "Workspace link",
+			alias:    "shared",
+			link:     &models.UrlShortener{Alias: "shared", Url: "https://google.com", UserId: 2, WorkspaceId: 5, Clicks: 8},
+			respCode: http.StatusOK,
+		},
+		{
+			name:        "Workspace link of other workspace",
+			alias:       "shared",
+			link:        &models.UrlShortener{Alias: "shared", Url: "https://google.com", UserId: 2, WorkspaceId: 6},
+			memberError: storage.ErrNotMember,
+			respError:   "url not found",
+			respCode:    http.StatusNotFound,
+		},
+		{
+			name:      "Missing link",
+			alias:     "missing",
+			mockError: storage.ErrUrlNotFound,
+			respError: "url not found",
+			respCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			linkGetterMock.On("GetLink", tc.alias).
+				Return(tc.link, tc.mockError).
+				Once()
+			if tc.link != nil && tc.link.WorkspaceId != 0 {
+				linkGetterMock.On("WorkspaceRole", tc.link.WorkspaceId, int64(1)).
+					Return(models.WorkspaceRoleMember, tc.memberError).
+					Once()
+			}
+
+			logger := slog.New(custommocks.NewMockLogger())
+			handler := url.StatsHandler(logger, linkGetterMock)
+
+			r := httptest.NewRequest(http.MethodGet, "/url/{alias}/stats", nil)
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", tc.alias)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+			ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, w.Code)
+
+			var resp url.StatsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.link.Clicks, resp.Clicks)
+			}
+		})
+	}
+}
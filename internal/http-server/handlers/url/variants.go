@@ -0,0 +1,158 @@
+package url
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/lib/access"
+	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// Variant is one weighted destination of a link created with variants.
+type Variant struct {
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=0,max=10000"`
+}
+
+// VariantStats reports a variant with the number of redirects it served.
+type VariantStats struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks"`
+}
+
+type VariantWeight struct {
+	ID     int64 `json:"id" validate:"required"`
+	Weight int   `json:"weight" validate:"min=0,max=10000"`
+}
+
+type WeightsRequest struct {
+	Variants []VariantWeight `json:"variants" validate:"required,min=1,dive"`
+}
+
+type VariantsResponse struct {
+	resp.Response
+	Variants []VariantStats `json:"variants"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=VariantUpdater
+type VariantUpdater interface {
+	GetLink(alias string) (*models.UrlShortener, error)
+	SetVariantWeights(urlId int64, weights map[int64]int) error
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+func variantModels(variants []Variant) []models.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]models.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, models.Variant{Target: v.URL, Weight: v.Weight})
+	}
+	return out
+}
+
+func newVariantStats(variants []models.Variant) []VariantStats {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]VariantStats, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, VariantStats{ID: v.Id, URL: v.Target, Weight: v.Weight, Clicks: v.Clicks})
+	}
+	return out
+}
+
+// VariantsHandler changes the weights of the variants of a link the caller
+// may edit. Variants left out of the request keep their weight, and at least one
+// variant must keep a positive weight. events, which may be nil, is notified
+// of the update.
+func VariantsHandler(log *slog.Logger, updater VariantUpdater, events LinkEvents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		alias := chi.URLParam(r, "alias")
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		var req WeightsRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", "err", err)
+			resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			resp.RenderValidationError(w, r, custom_validators.ValidationError(validateErr))
+			return
+		}
+
+		link, err := updater.GetLink(alias)
+		if err == nil {
+			err = access.Check(updater, claims, link, access.Edit)
+		}
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", "alias", alias)
+			resp.RenderError(w, r, http.StatusNotFound, "url not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", "alias", alias, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		weights := make(map[int64]int, len(req.Variants))
+		for _, v := range req.Variants {
+			weights[v.ID] = v.Weight
+		}
+		total := 0
+		for i, v := range link.Variants {
+			if weight, ok := weights[v.Id]; ok {
+				link.Variants[i].Weight = weight
+			}
+			total += link.Variants[i].Weight
+		}
+		if total == 0 {
+			resp.RenderError(w, r, http.StatusBadRequest, "variant weights must not all be zero")
+			return
+		}
+
+		err = updater.SetVariantWeights(link.Id, weights)
+		if errors.Is(err, storage.ErrVariantNotFound) {
+			log.Info("unknown variant", "alias", alias)
+			resp.RenderError(w, r, http.StatusBadRequest, "variant not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to update variant weights", "alias", alias, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("variant weights updated", "alias", alias)
+		if events != nil {
+			events.Publish(webhook.EventLinkUpdated, *link)
+		}
+
+		render.JSON(w, r, VariantsResponse{
+			Response: resp.OK(),
+			Variants: newVariantStats(link.Variants),
+		})
+	}
+}
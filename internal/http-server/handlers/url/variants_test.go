@@ -0,0 +1,118 @@
+package url_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/url/mocks"
+	custommocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func splitLink(userId int64) *models.UrlShortener {
+	return &models.UrlShortener{
+		Id:     10,
+		Alias:  "landing",
+		UserId: userId,
+		Variants: []models.Variant{
+			{Id: 1, Target: "https://example.com/a", Weight: 50, Clicks: 3},
+			{Id: 2, Target: "https://example.com/b", Weight: 50, Clicks: 4},
+		},
+	}
+}
+
+func TestVariantsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		link      *models.UrlShortener
+		weights   map[int64]int
+		mockError error
+		respError string
+		respCode  int
+		want      []int
+	}{
+		{
+			name:     "Update one weight",
+			input:    `{"variants": [{"id": 2, "weight": 0}]}`,
+			link:     splitLink(1),
+			weights:  map[int64]int{2: 0},
+			respCode: http.StatusOK,
+			want:     []int{50, 0},
+		},
+		{
+			name:      "All zero",
+			input:     `{"variants": [{"id": 1, "weight": 0}, {"id": 2, "weight": 0}]}`,
+			link:      splitLink(1),
+			respError: "variant weights must not all be zero",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Unknown variant",
+			input:     `{"variants": [{"id": 99, "weight": 10}]}`,
+			link:      splitLink(1),
+			weights:   map[int64]int{99: 10},
+			mockError: storage.ErrVariantNotFound,
+			respError: "variant not found",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Foreign link",
+			input:     `{"variants": [{"id": 1, "weight": 10}]}`,
+			link:      splitLink(2),
+			respError: "url not found",
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:      "Negative weight",
+			input:     `{"variants": [{"id": 1, "weight": -1}]}`,
+			respError: "field Weight is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			updaterMock := mocks.NewVariantUpdater(t)
+			if tc.link != nil {
+				updaterMock.On("GetLink", "landing").Return(tc.link, nil).Once()
+			}
+			if tc.weights != nil {
+				updaterMock.On("SetVariantWeights", int64(10), tc.weights).Return(tc.mockError).Once()
+			}
+
+			logger := slog.New(custommocks.NewMockLogger())
+			handler := url.VariantsHandler(logger, updaterMock, nil)
+
+			r := httptest.NewRequest(http.MethodPut, "/url/landing/variants", strings.NewReader(tc.input))
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("alias", "landing")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+			ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1})
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp url.VariantsResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			for i, weight := range tc.want {
+				require.Equal(t, weight, resp.Variants[i].Weight)
+			}
+		})
+	}
+}
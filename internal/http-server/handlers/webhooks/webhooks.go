@@ -0,0 +1,179 @@
+package webhooks
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	URL string `json:"url" validate:"required,http_url"`
+	// Secret signs the deliveries, a random one is generated when empty.
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16,max=256"`
+	// Events subscribes to some events only, every event when empty.
+	Events []string `json:"events,omitempty" validate:"max=5,dive,oneof=link.created link.updated link.deleted link.expired link.clicks"`
+}
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Webhook Webhook `json:"webhook"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=WebhookSaver
+type WebhookSaver interface {
+	SaveWebhook(models.Webhook) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=WebhookLister
+type WebhookLister interface {
+	ListWebhooks(userId int64) ([]models.Webhook, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=WebhookDeleter
+type WebhookDeleter interface {
+	DeleteWebhook(userId, id int64) error
+}
+
+// CreateHandler registers a webhook of the caller. The response carries the
+// signing secret, which is not shown again.
+func CreateHandler(log *slog.Logger, saver WebhookSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", "err", err)
+			resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			resp.RenderValidationError(w, r, custom_validators.ValidationError(validateErr))
+			return
+		}
+
+		hook := models.Webhook{
+			UserId:    claims.Id,
+			URL:       req.URL,
+			Secret:    req.Secret,
+			Events:    req.Events,
+			CreatedAt: time.Now(),
+		}
+		if hook.Secret == "" {
+			hook.Secret = webhook.NewSecret()
+		}
+		id, err := saver.SaveWebhook(hook)
+		if err != nil {
+			log.Error("failed to save webhook", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		hook.Id = id
+		log.Info("webhook saved", "id", id)
+
+		res := newWebhook(hook)
+		res.Secret = hook.Secret
+		render.JSON(w, r, Response{Response: resp.OK(), Webhook: res})
+	}
+}
+
+func ListHandler(log *slog.Logger, lister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		hooks, err := lister.ListWebhooks(claims.Id)
+		if err != nil {
+			log.Error("failed to list webhooks", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		res := make([]Webhook, 0, len(hooks))
+		for _, hook := range hooks {
+			res = append(res, newWebhook(hook))
+		}
+		render.JSON(w, r, ListResponse{Response: resp.OK(), Webhooks: res})
+	}
+}
+
+// DeleteHandler removes a webhook of the caller together with its queued
+// deliveries.
+func DeleteHandler(log *slog.Logger, deleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err == nil {
+			err = deleter.DeleteWebhook(claims.Id, id)
+		}
+		var numErr *strconv.NumError
+		if errors.Is(err, storage.ErrWebhookNotFound) || errors.As(err, &numErr) {
+			resp.RenderError(w, r, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete webhook", "id", id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("webhook deleted", "id", id)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func newWebhook(hook models.Webhook) Webhook {
+	events := hook.Events
+	if len(events) == 0 {
+		events = webhook.Events
+	}
+	return Webhook{
+		ID:        hook.Id,
+		URL:       hook.URL,
+		Events:    events,
+		CreatedAt: hook.CreatedAt,
+	}
+}
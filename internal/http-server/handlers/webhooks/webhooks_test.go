@@ -0,0 +1,165 @@
+package webhooks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/handlers/webhooks"
+	"url-shortener/internal/http-server/handlers/webhooks/mocks"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		secret    string
+		events    []string
+		respError string
+		respCode  int
+	}{
+		{
+			name:     "Generated secret",
+			body:     `{"url": "https://cms.example.com/hooks"}`,
+			events:   webhook.Events,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Own secret and events",
+			body:     `{"url": "https://cms.example.com/hooks", "secret": "0123456789abcdef", "events": ["link.created", "link.clicks"]}`,
+			secret:   "0123456789abcdef",
+			events:   []string{webhook.EventLinkCreated, webhook.EventLinkClicks},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Unknown event",
+			body:      `{"url": "https://cms.example.com/hooks", "events": ["link.viewed"]}`,
+			respError: "field Events[0] is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Not an http URL",
+			body:      `{"url": "ftp://cms.example.com/hooks"}`,
+			respError: "field URL is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Short secret",
+			body:      `{"url": "https://cms.example.com/hooks", "secret": "short"}`,
+			respError: "field Secret is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			saverMock := mocks.NewWebhookSaver(t)
+			var saved models.Webhook
+			if tc.respError == "" {
+				saverMock.On("SaveWebhook", mock.AnythingOfType("models.Webhook")).
+					Run(func(args mock.Arguments) { saved = args.Get(0).(models.Webhook) }).
+					Return(int64(3), nil).
+					Once()
+			}
+
+			handler := webhooks.CreateHandler(slog.New(custom_mocks.NewMockLogger()), saverMock)
+			r := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader([]byte(tc.body)))
+			r = r.WithContext(jwt_helper.WithClaims(r.Context(), &jwt_helper.UserClaims{Id: 1}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tc.respCode, w.Code)
+			var resp webhooks.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				return
+			}
+			require.Equal(t, int64(1), saved.UserId)
+			require.Equal(t, int64(3), resp.Webhook.ID)
+			require.Equal(t, tc.events, resp.Webhook.Events)
+			require.Equal(t, saved.Secret, resp.Webhook.Secret)
+			if tc.secret != "" {
+				require.Equal(t, tc.secret, saved.Secret)
+			} else {
+				require.Len(t, saved.Secret, 64)
+			}
+		})
+	}
+}
+
+func TestListHandlerHidesSecrets(t *testing.T) {
+	listerMock := mocks.NewWebhookLister(t)
+	listerMock.On("ListWebhooks", int64(1)).
+		Return([]models.Webhook{{Id: 1, UserId: 1, URL: "https://cms.example.com/hooks", Secret: "0123456789abcdef"}}, nil).
+		Once()
+
+	handler := webhooks.ListHandler(slog.New(custom_mocks.NewMockLogger()), listerMock)
+	r := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	r = r.WithContext(jwt_helper.WithClaims(r.Context(), &jwt_helper.UserClaims{Id: 1}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotContains(t, w.Body.String(), "0123456789abcdef")
+	var resp webhooks.ListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Webhooks, 1)
+	require.Equal(t, "https://cms.example.com/hooks", resp.Webhooks[0].URL)
+}
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		mockError error
+		respError string
+		respCode  int
+	}{
+		{name: "Own webhook", id: "3", respCode: http.StatusOK},
+		{name: "Foreign webhook", id: "4", mockError: storage.ErrWebhookNotFound, respError: "webhook not found", respCode: http.StatusNotFound},
+		{name: "Bad id", id: "abc", respError: "webhook not found", respCode: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			deleterMock := mocks.NewWebhookDeleter(t)
+			if tc.id != "abc" {
+				deleterMock.On("DeleteWebhook", int64(1), mock.AnythingOfType("int64")).Return(tc.mockError).Once()
+			}
+
+			handler := webhooks.DeleteHandler(slog.New(custom_mocks.NewMockLogger()), deleterMock)
+			r := httptest.NewRequest(http.MethodDelete, "/webhooks/{id}", nil)
+			reqCtx := chi.NewRouteContext()
+			reqCtx.URLParams.Add("id", tc.id)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+			ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			require.Equal(t, tc.respCode, w.Code)
+			var resp webhooks.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
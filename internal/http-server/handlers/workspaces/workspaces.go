@@ -0,0 +1,382 @@
+package workspaces
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type InviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	// Role defaults to member.
+	Role string `json:"role,omitempty" validate:"omitempty,oneof=admin member"`
+}
+
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Invite struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Member struct {
+	UserID   int64     `json:"user_id"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type Response struct {
+	resp.Response
+	Workspace Workspace `json:"workspace"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Workspaces []Workspace `json:"workspaces"`
+	// Invites are the pending invites for the email of the caller.
+	Invites []Invite `json:"invites"`
+}
+
+type MembersResponse struct {
+	resp.Response
+	Members []Member `json:"members"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=WorkspaceCreator
+type WorkspaceCreator interface {
+	CreateWorkspace(name string, ownerId int64) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=WorkspaceLister
+type WorkspaceLister interface {
+	ListWorkspaces(userId int64) ([]models.Workspace, error)
+	ListInvites(email string) ([]models.WorkspaceInvite, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=Inviter
+type Inviter interface {
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+	SaveInvite(models.WorkspaceInvite) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=InviteAccepter
+type InviteAccepter interface {
+	GetUserByID(id int64) (*models.User, error)
+	AcceptInvite(workspaceId, userId int64, email string) (string, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=MemberLister
+type MemberLister interface {
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+	ListMembers(workspaceId int64) ([]models.WorkspaceMember, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name=MemberRemover
+type MemberRemover interface {
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+	RemoveMember(workspaceId, userId int64) error
+}
+
+// CreateHandler creates a workspace with the caller as its admin.
+func CreateHandler(log *slog.Logger, creator WorkspaceCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		var req Request
+		if !decode(w, r, log, &req) {
+			return
+		}
+		id, err := creator.CreateWorkspace(req.Name, claims.Id)
+		if err != nil {
+			log.Error("failed to create workspace", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("workspace created", "id", id)
+		render.JSON(w, r, Response{Response: resp.OK(), Workspace: Workspace{
+			ID:        id,
+			Name:      req.Name,
+			Role:      models.WorkspaceRoleAdmin,
+			CreatedAt: time.Now().UTC(),
+		}})
+	}
+}
+
+// ListHandler lists the workspaces of the caller and the invites waiting for
+// the caller's email.
+func ListHandler(log *slog.Logger, lister WorkspaceLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+		if !ok {
+			resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		workspaces, err := lister.ListWorkspaces(claims.Id)
+		if err != nil {
+			log.Error("failed to list workspaces", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		invites, err := lister.ListInvites(claims.Email)
+		if err != nil {
+			log.Error("failed to list invites", "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		res := ListResponse{
+			Response:   resp.OK(),
+			Workspaces: make([]Workspace, 0, len(workspaces)),
+			Invites:    make([]Invite, 0, len(invites)),
+		}
+		for _, ws := range workspaces {
+			res.Workspaces = append(res.Workspaces, Workspace{ID: ws.Id, Name: ws.Name, Role: ws.Role, CreatedAt: ws.CreatedAt})
+		}
+		for _, inv := range invites {
+			res.Invites = append(res.Invites, Invite{WorkspaceID: inv.WorkspaceId, Role: inv.Role, CreatedAt: inv.CreatedAt})
+		}
+		render.JSON(w, r, res)
+	}
+}
+
+// InviteHandler lets workspace admins invite a user by email. The user joins
+// with JoinHandler, registering first if needed.
+func InviteHandler(log *slog.Logger, inviter Inviter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, workspaceId, ok := workspaceRequest(w, r)
+		if !ok {
+			return
+		}
+		var req InviteRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+		if req.Role == "" {
+			req.Role = models.WorkspaceRoleMember
+		}
+		err := requireAdmin(inviter, workspaceId, claims.Id)
+		if err == nil {
+			err = inviter.SaveInvite(models.WorkspaceInvite{
+				WorkspaceId: workspaceId,
+				Email:       req.Email,
+				Role:        req.Role,
+				InvitedBy:   claims.Id,
+				CreatedAt:   time.Now(),
+			})
+		}
+		if errors.Is(err, storage.ErrMemberExists) {
+			resp.RenderError(w, r, http.StatusConflict, "already a workspace member")
+			return
+		}
+		if !renderAccessError(w, r, log, err) {
+			return
+		}
+		log.Info("member invited", "workspace_id", workspaceId, "role", req.Role)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// JoinHandler accepts the invite to a workspace for the caller's email. The
+// email has to be verified, otherwise anyone could register with an invited
+// address and join.
+func JoinHandler(log *slog.Logger, accepter InviteAccepter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, workspaceId, ok := workspaceRequest(w, r)
+		if !ok {
+			return
+		}
+		user, err := accepter.GetUserByID(claims.Id)
+		if err != nil {
+			log.Error("failed to get user", "user_id", claims.Id, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if !user.EmailVerified {
+			resp.RenderError(w, r, http.StatusForbidden, "email not verified")
+			return
+		}
+		role, err := accepter.AcceptInvite(workspaceId, user.Id, user.Email)
+		if errors.Is(err, storage.ErrInviteNotFound) {
+			resp.RenderError(w, r, http.StatusNotFound, "invite not found")
+			return
+		}
+		if errors.Is(err, storage.ErrMemberExists) {
+			resp.RenderError(w, r, http.StatusConflict, "already a workspace member")
+			return
+		}
+		if err != nil {
+			log.Error("failed to accept invite", "workspace_id", workspaceId, "err", err)
+			resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		log.Info("workspace joined", "workspace_id", workspaceId, "role", role)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// MembersHandler lists the members of a workspace of the caller.
+func MembersHandler(log *slog.Logger, lister MemberLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, workspaceId, ok := workspaceRequest(w, r)
+		if !ok {
+			return
+		}
+		var members []models.WorkspaceMember
+		_, err := lister.WorkspaceRole(workspaceId, claims.Id)
+		if err == nil {
+			members, err = lister.ListMembers(workspaceId)
+		}
+		if !renderAccessError(w, r, log, err) {
+			return
+		}
+		res := make([]Member, 0, len(members))
+		for _, m := range members {
+			res = append(res, Member{UserID: m.UserId, Email: m.Email, Role: m.Role, JoinedAt: m.CreatedAt})
+		}
+		render.JSON(w, r, MembersResponse{Response: resp.OK(), Members: res})
+	}
+}
+
+// RemoveMemberHandler removes a member from a workspace. Workspace admins may
+// remove other members and members may leave, but admins cannot leave so
+// that a workspace keeps an admin. The links of the member stay in the
+// workspace.
+func RemoveMemberHandler(log *slog.Logger, remover MemberRemover) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			"request_id", middleware.GetReqID(r.Context()),
+		)
+		claims, workspaceId, ok := workspaceRequest(w, r)
+		if !ok {
+			return
+		}
+		userId, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil {
+			resp.RenderError(w, r, http.StatusNotFound, "member not found")
+			return
+		}
+		role, err := remover.WorkspaceRole(workspaceId, claims.Id)
+		if err == nil {
+			switch {
+			case userId == claims.Id && role == models.WorkspaceRoleAdmin:
+				resp.RenderError(w, r, http.StatusBadRequest, "admins cannot leave their workspace")
+				return
+			case userId != claims.Id && role != models.WorkspaceRoleAdmin:
+				err = errForbidden
+			default:
+				// the caller is a member, so the user removed is not
+				if err = remover.RemoveMember(workspaceId, userId); errors.Is(err, storage.ErrNotMember) {
+					resp.RenderError(w, r, http.StatusNotFound, "member not found")
+					return
+				}
+			}
+		}
+		if !renderAccessError(w, r, log, err) {
+			return
+		}
+		log.Info("member removed", "workspace_id", workspaceId, "user_id", userId)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+var errForbidden = errors.New("workspace admin required")
+
+func requireAdmin(m interface {
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}, workspaceId, userId int64) error {
+	role, err := m.WorkspaceRole(workspaceId, userId)
+	if err != nil {
+		return err
+	}
+	if role != models.WorkspaceRoleAdmin {
+		return errForbidden
+	}
+	return nil
+}
+
+// renderAccessError renders err and reports whether it was nil. Workspaces the
+// caller is not a member of are reported as not found.
+func renderAccessError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotMember):
+		resp.RenderError(w, r, http.StatusNotFound, "workspace not found")
+	case errors.Is(err, errForbidden):
+		resp.RenderError(w, r, http.StatusForbidden, "workspace admin required")
+	default:
+		log.Error("failed to access workspace", "err", err)
+		resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+	}
+	return false
+}
+
+// workspaceRequest returns the claims of the caller and the workspace id of
+// the path, rendering an error when either is missing.
+func workspaceRequest(w http.ResponseWriter, r *http.Request) (*jwt_helper.UserClaims, int64, bool) {
+	claims, ok := jwt_helper.ClaimsFromContext(r.Context())
+	if !ok {
+		resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+		return nil, 0, false
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		resp.RenderError(w, r, http.StatusNotFound, "workspace not found")
+		return nil, 0, false
+	}
+	return claims, id, true
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		log.Error("failed to decode request body", "err", err)
+		resp.RenderError(w, r, http.StatusBadRequest, "failed to decode request body")
+		return false
+	}
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		errors.As(err, &validateErr)
+		resp.RenderValidationError(w, r, custom_validators.ValidationError(validateErr))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package workspaces_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/handlers/workspaces"
+	"url-shortener/internal/http-server/handlers/workspaces/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// serve runs handler for a request of user 1 on workspace 5.
+func serve(handler http.HandlerFunc, method, body string, params map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/workspaces/5", bytes.NewReader([]byte(body)))
+	reqCtx := chi.NewRouteContext()
+	reqCtx.URLParams.Add("id", "5")
+	for k, v := range params {
+		reqCtx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, reqCtx)
+	ctx = jwt_helper.WithClaims(ctx, &jwt_helper.UserClaims{Id: 1, Email: "me@example.com"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r.WithContext(ctx))
+	return w
+}
+
+func TestCreateHandler(t *testing.T) {
+	creatorMock := mocks.NewWorkspaceCreator(t)
+	creatorMock.On("CreateWorkspace", "Marketing", int64(1)).Return(int64(5), nil).Once()
+
+	handler := workspaces.CreateHandler(slog.New(custom_mocks.NewMockLogger()), creatorMock)
+	w := serve(handler, http.MethodPost, `{"name": "Marketing"}`, nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var res workspaces.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, int64(5), res.Workspace.ID)
+	require.Equal(t, models.WorkspaceRoleAdmin, res.Workspace.Role)
+
+	w = serve(handler, http.MethodPost, `{}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListHandler(t *testing.T) {
+	listerMock := mocks.NewWorkspaceLister(t)
+	listerMock.On("ListWorkspaces", int64(1)).
+		Return([]models.Workspace{{Id: 5, Name: "Marketing", Role: models.WorkspaceRoleMember}}, nil).
+		Once()
+	listerMock.On("ListInvites", "me@example.com").
+		Return([]models.WorkspaceInvite{{WorkspaceId: 6, Email: "me@example.com", Role: models.WorkspaceRoleAdmin}}, nil).
+		Once()
+
+	w := serve(workspaces.ListHandler(slog.New(custom_mocks.NewMockLogger()), listerMock), http.MethodGet, "", nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var res workspaces.ListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Workspaces, 1)
+	require.Equal(t, "Marketing", res.Workspaces[0].Name)
+	require.Equal(t, []workspaces.Invite{{WorkspaceID: 6, Role: models.WorkspaceRoleAdmin}}, res.Invites)
+}
+
+func TestInviteHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		role      string
+		roleErr   error
+		saveErr   error
+		saveRole  string
+		respError string
+		respCode  int
+	}{
+		{
+			name:     "Admin invites member",
+			body:     `{"email": "new@example.com"}`,
+			role:     models.WorkspaceRoleAdmin,
+			saveRole: models.WorkspaceRoleMember,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Admin invites admin",
+			body:     `{"email": "new@example.com", "role": "admin"}`,
+			role:     models.WorkspaceRoleAdmin,
+			saveRole: models.WorkspaceRoleAdmin,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Already a member",
+			body:      `{"email": "old@example.com"}`,
+			role:      models.WorkspaceRoleAdmin,
+			saveRole:  models.WorkspaceRoleMember,
+			saveErr:   storage.ErrMemberExists,
+			respError: "already a workspace member",
+			respCode:  http.StatusConflict,
+		},
+		{
+			name:      "Member invites",
+			body:      `{"email": "new@example.com"}`,
+			role:      models.WorkspaceRoleMember,
+			respError: "workspace admin required",
+			respCode:  http.StatusForbidden,
+		},
+		{
+			name:      "Outsider invites",
+			body:      `{"email": "new@example.com"}`,
+			roleErr:   storage.ErrNotMember,
+			respError: "workspace not found",
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:      "Unknown role",
+			body:      `{"email": "new@example.com", "role": "owner"}`,
+			respError: "field Role is not valid",
+			respCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inviterMock := mocks.NewInviter(t)
+			if tc.role != "" || tc.roleErr != nil {
+				inviterMock.On("WorkspaceRole", int64(5), int64(1)).Return(tc.role, tc.roleErr).Once()
+			}
+			if tc.saveRole != "" {
+				inviterMock.On("SaveInvite", mock.MatchedBy(func(i models.WorkspaceInvite) bool {
+					return i.WorkspaceId == 5 && i.Role == tc.saveRole && i.InvitedBy == 1
+				})).Return(tc.saveErr).Once()
+			}
+
+			w := serve(workspaces.InviteHandler(slog.New(custom_mocks.NewMockLogger()), inviterMock), http.MethodPost, tc.body, nil)
+
+			require.Equal(t, tc.respCode, w.Code)
+			var res resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.Equal(t, tc.respError, res.Error)
+		})
+	}
+}
+
+func TestJoinHandler(t *testing.T) {
+	accepterMock := mocks.NewInviteAccepter(t)
+	accepterMock.On("GetUserByID", int64(1)).
+		Return(&models.User{Id: 1, Email: "me@example.com", EmailVerified: true}, nil).Twice()
+	accepterMock.On("GetUserByID", int64(1)).Return(&models.User{Id: 1, Email: "me@example.com"}, nil).Once()
+	accepterMock.On("AcceptInvite", int64(5), int64(1), "me@example.com").Return(models.WorkspaceRoleMember, nil).Once()
+	accepterMock.On("AcceptInvite", int64(5), int64(1), "me@example.com").Return("", storage.ErrInviteNotFound).Once()
+
+	handler := workspaces.JoinHandler(slog.New(custom_mocks.NewMockLogger()), accepterMock)
+
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "", nil).Code)
+	require.Equal(t, http.StatusNotFound, serve(handler, http.MethodPost, "", nil).Code)
+	w := serve(handler, http.MethodPost, "", nil)
+	require.Equal(t, http.StatusForbidden, w.Code, "unverified emails cannot join")
+	var res resp.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, "email not verified", res.Error)
+}
+
+func TestMembersHandler(t *testing.T) {
+	listerMock := mocks.NewMemberLister(t)
+	listerMock.On("WorkspaceRole", int64(5), int64(1)).Return(models.WorkspaceRoleMember, nil).Once()
+	listerMock.On("ListMembers", int64(5)).Return([]models.WorkspaceMember{
+		{WorkspaceId: 5, UserId: 2, Email: "admin@example.com", Role: models.WorkspaceRoleAdmin},
+		{WorkspaceId: 5, UserId: 1, Email: "me@example.com", Role: models.WorkspaceRoleMember},
+	}, nil).Once()
+
+	w := serve(workspaces.MembersHandler(slog.New(custom_mocks.NewMockLogger()), listerMock), http.MethodGet, "", nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var res workspaces.MembersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Members, 2)
+	require.Equal(t, "admin@example.com", res.Members[0].Email)
+}
+
+func TestRemoveMemberHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		userId    string
+		role      string
+		removeErr error
+		remove    bool
+		respError string
+		respCode  int
+	}{
+		{name: "Admin removes member", userId: "2", role: models.WorkspaceRoleAdmin, remove: true, respCode: http.StatusOK},
+		{name: "Member leaves", userId: "1", role: models.WorkspaceRoleMember, remove: true, respCode: http.StatusOK},
+		{
+			name:      "Admin leaves",
+			userId:    "1",
+			role:      models.WorkspaceRoleAdmin,
+			respError: "admins cannot leave their workspace",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Member removes member",
+			userId:    "2",
+			role:      models.WorkspaceRoleMember,
+			respError: "workspace admin required",
+			respCode:  http.StatusForbidden,
+		},
+		{
+			name:      "Admin removes stranger",
+			userId:    "3",
+			role:      models.WorkspaceRoleAdmin,
+			remove:    true,
+			removeErr: storage.ErrNotMember,
+			respError: "member not found",
+			respCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			removerMock := mocks.NewMemberRemover(t)
+			removerMock.On("WorkspaceRole", int64(5), int64(1)).Return(tc.role, nil).Once()
+			if tc.remove {
+				removerMock.On("RemoveMember", int64(5), mock.AnythingOfType("int64")).Return(tc.removeErr).Once()
+			}
+
+			w := serve(workspaces.RemoveMemberHandler(slog.New(custom_mocks.NewMockLogger()), removerMock),
+				http.MethodDelete, "", map[string]string{"user_id": tc.userId})
+
+			require.Equal(t, tc.respCode, w.Code)
+			var res resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.Equal(t, tc.respError, res.Error)
+		})
+	}
+}
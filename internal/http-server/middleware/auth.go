@@ -1,41 +1,56 @@
 package middleware
 
 import (
-	"context"
 	"errors"
-	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"strings"
 	resp "url-shortener/internal/lib/api/response"
 	jwthelper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/storage"
 )
 
-func NewAuthMW(log *slog.Logger) func(next http.Handler) http.Handler {
+// NewAuthMW lets requests with a valid access token through. The user is
+// looked up on every request, so that disabling an account or changing its
+// role takes effect before the token expires; the role in the context is the
+// current one.
+func NewAuthMW(log *slog.Logger, tokens jwthelper.TokenIssuer, users UserGetter) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			authHeaderSplitted := strings.SplitN(authHeader, " ", 2)
-			if len(authHeaderSplitted) != 2 && authHeaderSplitted[0] != "Bearer" {
-				w.WriteHeader(http.StatusUnauthorized)
-				render.JSON(w, r, resp.Error("unauthorized"))
+			if len(authHeaderSplitted) != 2 || authHeaderSplitted[0] != "Bearer" {
+				resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
 				return
 			}
 			rawToken := authHeaderSplitted[1]
-			validToken, err := jwthelper.ValidateToken(rawToken)
+			validToken, err := tokens.ValidateToken(rawToken)
 			if err != nil {
 				log.Error("error validating token", "err", err)
 				if errors.Is(err, jwthelper.ErrInvalidToken) {
-					w.WriteHeader(http.StatusUnauthorized)
-					render.JSON(w, r, resp.Error("unauthorized"))
+					resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
 					return
 				}
-				w.WriteHeader(http.StatusInternalServerError)
-				render.JSON(w, r, resp.Error("internal server error"))
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
 				return
 			}
-			rr := context.WithValue(r.Context(), "token", validToken)
-			next.ServeHTTP(w, r.WithContext(rr))
+			user, err := users.GetUserByID(validToken.Id)
+			if errors.Is(err, storage.ErrUserNotFound) {
+				resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			if err != nil {
+				log.Error("failed to get user", "user_id", validToken.Id, "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if user.Disabled {
+				log.Info("request of disabled account", "user_id", user.Id)
+				resp.RenderError(w, r, http.StatusForbidden, "account disabled")
+				return
+			}
+			validToken.Role = user.Role
+			next.ServeHTTP(w, r.WithContext(jwthelper.WithClaims(r.Context(), validToken)))
 		}
 		return http.HandlerFunc(fn)
 	}
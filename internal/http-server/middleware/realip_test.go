@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/http-server/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies, err := middleware.ParseProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	require.NoError(t, err)
+	_, err = middleware.ParseProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+
+	cases := []struct {
+		name      string
+		peer      string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{name: "direct", peer: "192.0.2.1:1234", want: "192.0.2.1:1234"},
+		{name: "spoofed by client", peer: "192.0.2.1:1234", forwarded: "198.51.100.7", realIP: "198.51.100.8", want: "192.0.2.1:1234"},
+		{name: "trusted proxy", peer: "10.0.0.1:1234", forwarded: "198.51.100.7", want: "198.51.100.7"},
+		{name: "trusted ipv6 proxy", peer: "[2001:db8::1]:1234", forwarded: "198.51.100.7", want: "198.51.100.7"},
+		{name: "forged hop before the client", peer: "10.0.0.1:1234", forwarded: "203.0.113.9, 198.51.100.7, 10.0.0.2", want: "198.51.100.7"},
+		{name: "only proxies", peer: "10.0.0.1:1234", forwarded: "10.0.0.3, 10.0.0.2", want: "10.0.0.3"},
+		{name: "malformed hop", peer: "10.0.0.1:1234", forwarded: "198.51.100.7, bogus", want: "10.0.0.1:1234"},
+		{name: "real ip header", peer: "10.0.0.1:1234", realIP: "198.51.100.8", want: "198.51.100.8"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := middleware.NewRealIPMW(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.peer
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				r.Header.Set("X-Real-IP", tc.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
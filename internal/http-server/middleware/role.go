@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	resp "url-shortener/internal/lib/api/response"
+	jwthelper "url-shortener/internal/lib/jwt-helper"
+)
+
+// RequireRole lets only tokens with one of roles through. It must run after
+// the auth middleware.
+func RequireRole(log *slog.Logger, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := jwthelper.ClaimsFromContext(r.Context())
+			if !ok {
+				resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			if !claims.HasRole(roles...) {
+				log.Info("role not allowed", "user_id", claims.Id, "role", claims.UserRole(), "path", r.URL.Path)
+				resp.RenderError(w, r, http.StatusForbidden, "forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
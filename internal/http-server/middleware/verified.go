@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	resp "url-shortener/internal/lib/api/response"
+	jwthelper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+)
+
+type UserGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// RequireVerifiedEmail lets only users with a verified email through. The
+// user is looked up on every request so that verifying takes effect without
+// a new token. It must run after the auth middleware.
+func RequireVerifiedEmail(log *slog.Logger, users UserGetter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := jwthelper.ClaimsFromContext(r.Context())
+			if !ok {
+				resp.RenderError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			user, err := users.GetUserByID(claims.Id)
+			if err != nil {
+				log.Error("failed to get user", "user_id", claims.Id, "err", err)
+				resp.RenderError(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !user.EmailVerified {
+				resp.RenderError(w, r, http.StatusForbidden, "email not verified")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,602 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"url-shortener/internal/http-server/handlers/admin"
+	"url-shortener/internal/http-server/handlers/auth"
+	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/webhooks"
+	"url-shortener/internal/http-server/handlers/workspaces"
+	resp "url-shortener/internal/lib/api/response"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+)
+
+const bearerAuth = "bearerAuth"
+
+// Spec describes every route registered by http_server.initRoutes.
+func Spec() *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "URL Shortener API", Version: "1.0.0"},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Response":          SchemaOf(resp.Response{}),
+				"Problem":           SchemaOf(resp.Problem{}),
+				"URLRequest":        SchemaOf(url.Request{}),
+				"URLResponse":       SchemaOf(url.Response{}),
+				"LinkList":          SchemaOf(url.ListResponse{}),
+				"LinkStats":         SchemaOf(url.StatsResponse{}),
+				"Link":              SchemaOf(url.Link{}),
+				"ImportResult":      SchemaOf(url.ImportResponse{}),
+				"Weights":           SchemaOf(url.WeightsRequest{}),
+				"Variants":          SchemaOf(url.VariantsResponse{}),
+				"AuthRequest":       SchemaOf(auth.Request{}),
+				"AuthResponse":      SchemaOf(auth.Response{}),
+				"SessionsResponse":  SchemaOf(auth.SessionsResponse{}),
+				"SetupResponse":     SchemaOf(auth.SetupResponse{}),
+				"EnableRequest":     SchemaOf(auth.EnableRequest{}),
+				"EnableResponse":    SchemaOf(auth.EnableResponse{}),
+				"TwoFactorRequest":  SchemaOf(auth.TwoFactorRequest{}),
+				"JWKS":              SchemaOf(jwt_helper.JWKS{}),
+				"WebhookRequest":    SchemaOf(webhooks.Request{}),
+				"WebhookResponse":   SchemaOf(webhooks.Response{}),
+				"WebhookList":       SchemaOf(webhooks.ListResponse{}),
+				"WorkspaceRequest":  SchemaOf(workspaces.Request{}),
+				"WorkspaceResponse": SchemaOf(workspaces.Response{}),
+				"WorkspaceList":     SchemaOf(workspaces.ListResponse{}),
+				"InviteRequest":     SchemaOf(workspaces.InviteRequest{}),
+				"MemberList":        SchemaOf(workspaces.MembersResponse{}),
+				"UserList":          SchemaOf(admin.UsersResponse{}),
+				"UserUpdate":        SchemaOf(admin.UpdateUserRequest{}),
+				"UserResponse":      SchemaOf(admin.UserResponse{}),
+				"AdminLinkList":     SchemaOf(admin.LinksResponse{}),
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	doc.add(http.MethodPost, "/url", &Operation{
+		OperationID: "saveURL",
+		Summary:     "Create a short alias for a URL",
+		RequestBody: jsonBody("URLRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("URL saved", "URLResponse"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account or not a member of the workspace; or email not verified when required"),
+			"409": errorResponse("Alias already exists"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/url", &Operation{
+		OperationID: "listURLs",
+		Summary:     "List the caller's links or the links of a workspace",
+		Description: "Without workspace_id the links created by the caller are listed. " +
+			"With broken=true only links whose destination failed the configured number of dead-link checks in a row are listed.",
+		Parameters: []Parameter{
+			queryParam("broken", "true", "false"),
+			{Name: "workspace_id", In: "query", Schema: &Schema{Type: "integer"}},
+		},
+		Responses: map[string]Response{
+			"200": jsonResponse("Links of the caller", "LinkList"),
+			"400": errorResponse("Invalid broken or workspace_id value"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Not a member of the workspace"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/url/{alias}/stats", &Operation{
+		OperationID: "linkStats",
+		Summary:     "Click statistics of a link of the caller or of one of their workspaces",
+		Parameters:  []Parameter{aliasParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Link statistics", "LinkStats"),
+			"401": errorResponse("Missing or invalid token"),
+			"404": errorResponse("Alias not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPut, "/url/{alias}/variants", &Operation{
+		OperationID: "setVariantWeights",
+		Summary:     "Change the traffic split of a link of the caller or of one of their workspaces",
+		Parameters:  []Parameter{aliasParam()},
+		RequestBody: jsonBody("Weights"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Updated variants", "Variants"),
+			"400": errorResponse("Invalid weights or unknown variant"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account"),
+			"404": errorResponse("Alias not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/url/export", &Operation{
+		OperationID: "exportURLs",
+		Summary:     "Stream the caller's links",
+		Parameters:  []Parameter{queryParam("format", url.FormatJSON, url.FormatCSV, url.FormatJSONL)},
+		Responses: map[string]Response{
+			"200": {Description: "Links of the caller", Content: linkFileContent()},
+			"400": errorResponse("Unknown format"),
+			"401": errorResponse("Missing or invalid token"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/url/import", &Operation{
+		OperationID: "importURLs",
+		Summary:     "Import links, reporting the outcome of every row",
+		Parameters: []Parameter{
+			queryParam("format", url.FormatJSON, url.FormatCSV, url.FormatJSONL),
+			queryParam("conflict", url.ConflictFail, url.ConflictSkip, url.ConflictOverwrite),
+		},
+		RequestBody: &RequestBody{Required: true, Content: linkFileContent()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Import finished, failed rows are listed", "ImportResult"),
+			"400": errorResponse("Unreadable import"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account; or email not verified when required"),
+			"409": jsonResponse("Conflict in fail mode, the import stopped; rows before it stay imported", "ImportResult"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/{alias}", &Operation{
+		OperationID: "redirect",
+		Summary:     "Redirect to the URL behind an alias",
+		Description: "A trailing + on the alias or preview=1 shows an HTML page describing the " +
+			"destination instead of redirecting. Links to external domains may show an interstitial " +
+			"warning page when configured. Expired links answer 410.",
+		Parameters: []Parameter{aliasParam(), queryParam("preview", "1", "true")},
+		Responses: map[string]Response{
+			"200": {
+				Description: "Preview or interstitial page",
+				Content:     map[string]MediaType{"text/html": {Schema: &Schema{Type: "string"}}},
+			},
+			"303": {
+				Description: "Redirect to the stored URL",
+				Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri"}}},
+			},
+			"400": errorResponse("Alias not found"),
+			"410": errorResponse("Link expired"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodDelete, "/{alias}", &Operation{
+		OperationID: "deleteURL",
+		Summary:     "Delete one of the caller's aliases",
+		Description: "Workspace links may be deleted by their creator and by workspace admins. " +
+			"Admins can delete aliases of any user.",
+		Parameters: []Parameter{aliasParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Alias deleted", "Response"),
+			"400": errorResponse("Alias not found"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account or not allowed to delete the workspace link"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/webhooks", &Operation{
+		OperationID: "createWebhook",
+		Summary:     "Register an endpoint for events about the caller's links",
+		Description: "Events are POSTed as JSON and signed in the X-Webhook-Signature header with " +
+			"sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + \".\" + body). Failed deliveries are " +
+			"retried with exponential backoff. The secret is only returned by this operation.",
+		RequestBody: jsonBody("WebhookRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Webhook registered", "WebhookResponse"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/webhooks", &Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List the caller's webhooks",
+		Responses: map[string]Response{
+			"200": jsonResponse("Webhooks of the caller", "WebhookList"),
+			"401": errorResponse("Missing or invalid token"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodDelete, "/webhooks/{id}", &Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook and its queued deliveries",
+		Parameters:  []Parameter{idParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Webhook deleted", "Response"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account"),
+			"404": errorResponse("Webhook not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/workspaces", &Operation{
+		OperationID: "createWorkspace",
+		Summary:     "Create a workspace with the caller as its admin",
+		Description: "Links created with a workspace_id are owned by the workspace. Members may view and " +
+			"edit them, workspace admins and the creator of a link may also delete it.",
+		RequestBody: jsonBody("WorkspaceRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Workspace created", "WorkspaceResponse"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/workspaces", &Operation{
+		OperationID: "listWorkspaces",
+		Summary:     "List the caller's workspaces and pending invites",
+		Responses: map[string]Response{
+			"200": jsonResponse("Workspaces and invites of the caller", "WorkspaceList"),
+			"401": errorResponse("Missing or invalid token"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/workspaces/{id}/invites", &Operation{
+		OperationID: "inviteMember",
+		Summary:     "Invite a user to a workspace by email",
+		Description: "Only workspace admins may invite. Inviting the same email again replaces the role of the invite.",
+		Parameters:  []Parameter{idParam()},
+		RequestBody: jsonBody("InviteRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Invite saved", "Response"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account or not a workspace admin"),
+			"404": errorResponse("Workspace not found"),
+			"409": errorResponse("Already a member"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/workspaces/{id}/join", &Operation{
+		OperationID: "joinWorkspace",
+		Summary:     "Accept the invite to a workspace for the caller's verified email",
+		Parameters:  []Parameter{idParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Joined", "Response"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account or email not verified"),
+			"404": errorResponse("Invite not found"),
+			"409": errorResponse("Already a member"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/workspaces/{id}/members", &Operation{
+		OperationID: "listMembers",
+		Summary:     "List the members of one of the caller's workspaces",
+		Parameters:  []Parameter{idParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Members", "MemberList"),
+			"401": errorResponse("Missing or invalid token"),
+			"404": errorResponse("Workspace not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodDelete, "/workspaces/{id}/members/{user_id}", &Operation{
+		OperationID: "removeMember",
+		Summary:     "Remove a member from a workspace or leave it",
+		Description: "Workspace admins may remove other members, members may remove themselves. " +
+			"The links of a removed member stay in the workspace.",
+		Parameters: []Parameter{idParam(), {Name: "user_id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}},
+		Responses: map[string]Response{
+			"200": jsonResponse("Member removed", "Response"),
+			"400": errorResponse("Admins cannot leave"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Read-only account or not a workspace admin"),
+			"404": errorResponse("Workspace or member not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/admin/users", &Operation{
+		OperationID: "adminListUsers",
+		Summary:     "List all users",
+		Responses: map[string]Response{
+			"200": jsonResponse("All users", "UserList"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Caller is not an admin"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPatch, "/admin/users/{id}", &Operation{
+		OperationID: "adminUpdateUser",
+		Summary:     "Change the role of a user or disable the account",
+		Description: "Changes apply to tokens issued afterwards. Admins cannot change their own account.",
+		Parameters:  []Parameter{idParam()},
+		RequestBody: jsonBody("UserUpdate"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Updated user", "UserResponse"),
+			"400": errorResponse("Invalid request or own account"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Caller is not an admin"),
+			"404": errorResponse("User not found"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/admin/links", &Operation{
+		OperationID: "adminListLinks",
+		Summary:     "List the links of all users",
+		Parameters:  []Parameter{{Name: "user_id", In: "query", Schema: &Schema{Type: "integer"}}},
+		Responses: map[string]Response{
+			"200": jsonResponse("Links", "AdminLinkList"),
+			"400": errorResponse("Invalid user_id"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Caller is not an admin"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodDelete, "/admin/links/{alias}", &Operation{
+		OperationID: "adminDeleteURL",
+		Summary:     "Delete the alias of any user",
+		Parameters:  []Parameter{aliasParam()},
+		Responses: map[string]Response{
+			"200": jsonResponse("Alias deleted", "Response"),
+			"400": errorResponse("Alias not found"),
+			"401": errorResponse("Missing or invalid token"),
+			"403": errorResponse("Caller is not an admin"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/register", &Operation{
+		OperationID: "register",
+		Summary:     "Register a user and return a token",
+		Description: "The password must satisfy the configured password policy.",
+		RequestBody: jsonBody("AuthRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("User registered", "AuthResponse"),
+			"400": errorResponse("Invalid request or weak password"),
+			"409": errorResponse("User already exists"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodPost, "/login", &Operation{
+		OperationID: "login",
+		Summary:     "Exchange credentials for a token",
+		Description: "Accounts with 2FA get a challenge token instead, see POST /login/2fa. " +
+			"Failed attempts make further attempts on the account wait longer and longer, " +
+			"until the account is locked for a while. Too many failures from one IP lock out the IP. " +
+			"Refused attempts carry a Retry-After header.",
+		RequestBody: jsonBody("AuthRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Logged in", "AuthResponse"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Bad credentials"),
+			"403": errorResponse("Account disabled"),
+			"429": errorResponse("Too many failed attempts"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodPost, "/login/2fa", &Operation{
+		OperationID: "loginTwoFactor",
+		Summary:     "Exchange a challenge token and a TOTP or recovery code for a token",
+		Description: "Challenge tokens expire after 5 minutes. TOTP codes and recovery codes are accepted once. " +
+			"Wrong codes count as failed logins.",
+		RequestBody: jsonBody("TwoFactorRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Logged in", "AuthResponse"),
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Invalid or expired challenge token, or invalid code"),
+			"403": errorResponse("Account disabled"),
+			"429": errorResponse("Too many failed attempts"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodGet, "/auth/oidc/login", &Operation{
+		OperationID: "ssoLogin",
+		Summary:     "Log in at the identity provider",
+		Description: "Redirects to the OpenID Connect provider, which redirects back to GET /auth/oidc/callback. " +
+			"A short-lived cookie keeps the state of the login.",
+		Responses: map[string]Response{
+			"302": {
+				Description: "Redirect to the identity provider",
+				Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri"}}},
+			},
+			"404": errorResponse("Single sign-on not enabled"),
+			"502": errorResponse("Identity provider unavailable"),
+		},
+	})
+	doc.add(http.MethodGet, "/auth/oidc/callback", &Operation{
+		OperationID: "ssoCallback",
+		Summary:     "Exchange the answer of the identity provider for a token",
+		Description: "The account at the provider is linked to the user with its email, who is created on the first login. " +
+			"Only emails verified by the provider and of the allowed domains are accepted. " +
+			"Accounts with 2FA get a challenge token instead, see POST /login/2fa.",
+		Parameters: []Parameter{queryParam("code"), queryParam("state"), queryParam("error")},
+		Responses: map[string]Response{
+			"200": jsonResponse("Logged in", "AuthResponse"),
+			"400": errorResponse("Missing code, invalid state or expired login"),
+			"401": errorResponse("Login refused or failed at the provider"),
+			"403": errorResponse("Email not verified or of another domain, or account disabled"),
+			"404": errorResponse("Single sign-on not enabled"),
+			"500": errorResponse("Internal error"),
+			"502": errorResponse("Identity provider unavailable"),
+		},
+	})
+	doc.add(http.MethodPost, "/me/2fa/setup", &Operation{
+		OperationID: "setupTwoFactor",
+		Summary:     "Create a TOTP secret for the caller",
+		Description: "Add the secret to an authenticator app, then confirm it with POST /me/2fa/enable. " +
+			"A new setup replaces an unconfirmed one.",
+		Responses: map[string]Response{
+			"200": jsonResponse("Secret created", "SetupResponse"),
+			"401": errorResponse("Missing or invalid token"),
+			"409": errorResponse("2FA already enabled"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/me/2fa/enable", &Operation{
+		OperationID: "enableTwoFactor",
+		Summary:     "Enable 2FA with a code of the new secret",
+		Description: "Returns one-time recovery codes, which are not shown again.",
+		RequestBody: jsonBody("EnableRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("2FA enabled", "EnableResponse"),
+			"400": errorResponse("Invalid request, invalid code or 2FA not set up"),
+			"401": errorResponse("Missing or invalid token"),
+			"409": errorResponse("2FA already enabled"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/me/sessions", &Operation{
+		OperationID: "listSessions",
+		Summary:     "List the recent login attempts into the caller's account",
+		Description: "Failed and refused attempts are listed too, newest first.",
+		Responses: map[string]Response{
+			"200": jsonResponse("Login attempts", "SessionsResponse"),
+			"401": errorResponse("Missing or invalid token"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodGet, "/email/verify", &Operation{
+		OperationID: "verifyEmail",
+		Summary:     "Verify an email with the token of a verification link",
+		Description: "Tokens are single-use and expire. Creating links may require a verified email.",
+		Parameters:  []Parameter{{Name: "token", In: "query", Required: true, Schema: &Schema{Type: "string"}}},
+		Responses: map[string]Response{
+			"200": jsonResponse("Email verified", "Response"),
+			"400": errorResponse("Invalid or expired token"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodPost, "/email/verify/resend", &Operation{
+		OperationID: "resendVerification",
+		Summary:     "Send a new verification link to the caller",
+		Responses: map[string]Response{
+			"200": jsonResponse("Link sent", "Response"),
+			"400": errorResponse("Email already verified"),
+			"401": errorResponse("Missing or invalid token"),
+			"500": errorResponse("Internal error"),
+		},
+		Security: secured(),
+	})
+	doc.add(http.MethodPost, "/password/forgot", &Operation{
+		OperationID: "forgotPassword",
+		Summary:     "Email a password reset token",
+		Description: "The answer is the same whether an account with the email exists or not.",
+		RequestBody: jsonBody("ForgotRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Token sent if the account exists", "Response"),
+			"400": errorResponse("Invalid request"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodPost, "/password/reset", &Operation{
+		OperationID: "resetPassword",
+		Summary:     "Set a new password with a reset token",
+		Description: "Tokens are single-use and expire. Resetting also verifies the email.",
+		RequestBody: jsonBody("ResetRequest"),
+		Responses: map[string]Response{
+			"200": jsonResponse("Password changed", "Response"),
+			"400": errorResponse("Invalid request, weak password or invalid or expired token"),
+			"500": errorResponse("Internal error"),
+		},
+	})
+	doc.add(http.MethodGet, "/.well-known/jwks.json", &Operation{
+		OperationID: "jwks",
+		Summary:     "Public keys that verify the tokens of the service",
+		Description: "Tokens name their key in the kid header. The set is empty when tokens are signed with a shared secret.",
+		Responses: map[string]Response{
+			"200": jsonResponse("Key set", "JWKS"),
+		},
+	})
+	doc.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "openapi",
+		Summary:     "This specification",
+		Responses: map[string]Response{
+			"200": {Description: "OpenAPI document", Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{Type: "object"}},
+			}},
+		},
+	})
+
+	return doc
+}
+
+// Has reports whether the operation for method and path is documented.
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
+
+func (d *Document) add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+func secured() []map[string][]string {
+	return []map[string][]string{{bearerAuth: {}}}
+}
+
+func aliasParam() Parameter {
+	return Parameter{Name: "alias", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+}
+
+func idParam() Parameter {
+	return Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}
+}
+
+func queryParam(name string, enum ...string) Parameter {
+	return Parameter{Name: name, In: "query", Schema: &Schema{Type: "string", Enum: enum}}
+}
+
+// linkFileContent describes the link formats of export and import.
+func linkFileContent() map[string]MediaType {
+	return map[string]MediaType{
+		"application/json":     {Schema: &Schema{Type: "array", Items: Ref("Link")}},
+		"application/x-ndjson": {Schema: &Schema{Type: "string"}},
+		"text/csv":             {Schema: &Schema{Type: "string"}},
+	}
+}
+
+func jsonBody(schema string) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": {Schema: Ref(schema)}},
+	}
+}
+
+func jsonResponse(description, schema string) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: Ref(schema)}},
+	}
+}
+
+// errorResponse documents both error shapes negotiated by response.RenderError.
+func errorResponse(description string) Response {
+	return Response{
+		Description: description,
+		Content: map[string]MediaType{
+			"application/json":      {Schema: Ref("Response")},
+			resp.ContentTypeProblem: {Schema: Ref("Problem")},
+		},
+	}
+}
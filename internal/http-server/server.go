@@ -1,58 +1,336 @@
 package http_server
 
 import (
+	"context"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/admin"
 	"url-shortener/internal/http-server/handlers/auth"
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/url"
+	"url-shortener/internal/http-server/handlers/webhooks"
+	"url-shortener/internal/http-server/handlers/workspaces"
 	middleware2 "url-shortener/internal/http-server/middleware"
+	"url-shortener/internal/http-server/openapi"
+	alias_generator "url-shortener/internal/lib/alias-generator"
+	"url-shortener/internal/lib/geoip"
 	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/lib/linkcheck"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/lib/mailer"
+	"url-shortener/internal/lib/metadata"
+	"url-shortener/internal/lib/oidc"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/lib/rules"
+	"url-shortener/internal/lib/webhook"
 	"url-shortener/internal/models"
 )
 
 type URLRepo interface {
-	GetURL(alias string) (string, error)
+	GetLink(alias string) (*models.UrlShortener, error)
+	ListURLs(userId int64) ([]models.UrlShortener, error)
+	WalkURLs(userId int64, fn func(models.UrlShortener) error) error
+	FindDuplicateURL(link models.UrlShortener) (*models.UrlShortener, error)
+	OverwriteURL(link models.UrlShortener) error
+	NextAliasSequence() (int64, error)
+	IncrementClicks(alias string) (int64, error)
+	IncrementVariantClicks(id int64) error
+	SetVariantWeights(urlId int64, weights map[int64]int) error
 	SaveURL(models.UrlShortener) (int64, error)
 	DeleteURL(alias string) error
 	SaveUser(models.User) (int64, error)
 	GetUserByEmail(string) (*models.User, error)
+	GetUserByID(id int64) (*models.User, error)
+	ListUsers() ([]models.User, error)
+	SetUserRole(userId int64, role string) error
+	SetUserDisabled(userId int64, disabled bool) error
+	SaveMetadata(urlId int64, m models.Metadata) error
+	ListAllURLs() ([]models.UrlShortener, error)
+	ListBrokenURLs(userId int64, minFailures int) ([]models.UrlShortener, error)
+	SaveHealth(urlId int64, h models.Health) (int, error)
+	ListExpiredURLs(now time.Time) ([]models.UrlShortener, error)
+	MarkExpiryNotified(urlId int64) error
+	SaveWebhook(models.Webhook) (int64, error)
+	ListWebhooks(userId int64) ([]models.Webhook, error)
+	DeleteWebhook(userId, id int64) error
+	EnqueueWebhookEvent(userId int64, event string, payload []byte, at time.Time) (int64, error)
+	DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(d models.WebhookDelivery) error
+	CreateWorkspace(name string, ownerId int64) (int64, error)
+	ListWorkspaces(userId int64) ([]models.Workspace, error)
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+	ListWorkspaceURLs(workspaceId int64) ([]models.UrlShortener, error)
+	ListMembers(workspaceId int64) ([]models.WorkspaceMember, error)
+	RemoveMember(workspaceId, userId int64) error
+	SaveInvite(models.WorkspaceInvite) error
+	ListInvites(email string) ([]models.WorkspaceInvite, error)
+	AcceptInvite(workspaceId, userId int64, email string) (string, error)
+	SaveUserToken(userId int64, purpose, tokenHash string, expiresAt time.Time) error
+	ConsumeUserToken(purpose, tokenHash string, now time.Time) (int64, error)
+	SetEmailVerified(userId int64) error
+	UpdatePassword(userId int64, password []byte) error
+	SaveLoginEvent(event models.LoginEvent) error
+	LoginFailures(email string, since time.Time) (models.LoginFailures, error)
+	IPLoginFailures(ip string, since time.Time) (models.LoginFailures, error)
+	ListLoginEvents(userId int64, limit int) ([]models.LoginEvent, error)
+	SetTOTPSecret(userId int64, secret string) error
+	EnableTOTP(userId int64, step int64, codeHashes []string) error
+	UseTOTPStep(userId int64, step int64) (bool, error)
+	ConsumeRecoveryCode(userId int64, codeHash string) error
+	ProvisionSSOUser(issuer, subject, email string) (*models.User, error)
 }
 
 type server struct {
 	router *chi.Mux
 	cfg    *config.Config
+	// workers run in the background while the server is running.
+	workers []func(ctx context.Context)
 }
 
-func New(logger *slog.Logger, cfg *config.Config, repo URLRepo) *server {
+// dependencies are built by New from the config and used by the handlers.
+type dependencies struct {
+	aliases  alias_generator.Policy
+	geo      rules.CountryLookup
+	metadata url.MetadataQueue
+	// events and clicks are the webhook dispatcher when webhooks are enabled.
+	events    url.LinkEvents
+	clicks    redirect.ClickEvents
+	emails    *auth.Emails
+	passwords *password.Hasher
+	policy    *password.Policy
+	// guard throttles logins when the lockout is enabled.
+	guard *lockout.Guard
+	// sso logs users in through the identity provider when enabled.
+	sso *auth.SSO
+	// tokens signs and validates the tokens of the users.
+	tokens *jwt_helper.Issuer
+	// proxies may forward the client address.
+	proxies []netip.Prefix
+}
+
+func New(logger *slog.Logger, cfg *config.Config, repo URLRepo) (*server, error) {
 	srv := &server{
 		router: chi.NewRouter(),
 		cfg:    cfg,
 	}
-	srv.initRoutes(logger, repo)
-	jwt_helper.InitJwtHelper(cfg)
-	return srv
+
+	var deps dependencies
+	var err error
+	deps.aliases, err = alias_generator.New(cfg.Alias, repo)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GeoIPPath != "" {
+		db, err := geoip.Open(cfg.GeoIPPath)
+		if err != nil {
+			return nil, fmt.Errorf("load geoip database: %w", err)
+		}
+		deps.geo = db
+	}
+	if cfg.Metadata.Enabled {
+		fetcher := metadata.NewFetcher(metadata.Options{
+			Timeout:  cfg.Metadata.Timeout,
+			MaxBytes: cfg.Metadata.MaxBytes,
+		})
+		queue := metadata.NewQueue(logger, fetcher, repo, cfg.Metadata.QueueSize)
+		deps.metadata = queue
+		srv.workers = append(srv.workers, func(ctx context.Context) {
+			queue.Run(ctx, cfg.Metadata.Workers)
+		})
+	}
+	if cfg.LinkCheck.Enabled {
+		checker := linkcheck.New(logger, repo, linkcheck.Options{
+			Interval:    cfg.LinkCheck.Interval,
+			Concurrency: cfg.LinkCheck.Concurrency,
+			HostDelay:   cfg.LinkCheck.HostDelay,
+			Timeout:     cfg.LinkCheck.Timeout,
+			BrokenAfter: cfg.LinkCheck.BrokenAfter,
+		})
+		srv.workers = append(srv.workers, checker.Run)
+	}
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhook.NewDispatcher(logger, repo, webhook.Options{
+			Workers:         cfg.Webhooks.Workers,
+			Timeout:         cfg.Webhooks.Timeout,
+			PollInterval:    cfg.Webhooks.PollInterval,
+			MaxAttempts:     cfg.Webhooks.MaxAttempts,
+			Backoff:         cfg.Webhooks.Backoff,
+			MaxBackoff:      cfg.Webhooks.MaxBackoff,
+			ClickThresholds: cfg.Webhooks.ClickThresholds,
+		})
+		deps.events = dispatcher
+		deps.clicks = dispatcher
+		srv.workers = append(srv.workers, dispatcher.Run)
+	}
+
+	deps.tokens, err = jwt_helper.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deps.proxies, err = middleware2.ParseProxies(cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	deps.passwords, deps.policy, err = password.New(cfg.Auth.Password)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Auth.Lockout.Enabled {
+		deps.guard = lockout.New(repo, lockout.Options{
+			DelayAfter:   cfg.Auth.Lockout.DelayAfter,
+			Delay:        cfg.Auth.Lockout.Delay,
+			MaxDelay:     cfg.Auth.Lockout.MaxDelay,
+			LockAfter:    cfg.Auth.Lockout.LockAfter,
+			LockDuration: cfg.Auth.Lockout.LockDuration,
+			IPLockAfter:  cfg.Auth.Lockout.IPLockAfter,
+			Window:       cfg.Auth.Lockout.Window,
+		})
+	}
+	if cfg.Auth.OIDC.Enabled {
+		deps.sso = newSSO(cfg.Auth)
+	}
+	m, err := newMailer(logger, cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	deps.emails = &auth.Emails{
+		Mailer:    m,
+		Tokens:    repo,
+		BaseURL:   cfg.Auth.BaseURL,
+		VerifyTTL: cfg.Auth.VerifyTTL,
+		ResetTTL:  cfg.Auth.ResetTTL,
+	}
+
+	srv.initRoutes(logger, repo, deps)
+	return srv, nil
 }
 
-func (s *server) initRoutes(logger *slog.Logger, repo URLRepo) {
+func (s *server) initRoutes(logger *slog.Logger, repo URLRepo, deps dependencies) {
 
 	s.router.Use(middleware.RequestID)
-	s.router.Use(middleware.RealIP)
+	s.router.Use(middleware2.NewRealIPMW(deps.proxies))
 	s.router.Use(middleware2.NewLoggerMW(logger))
 	s.router.Use(middleware.Recoverer)
-	s.router.Use(middleware.URLFormat)
 
-	s.router.Route("/", func(r chi.Router) {
-		r.Use(middleware2.NewAuthMW(logger))
-		r.Post("/url", url.New(logger, repo))
-		r.Delete("/{alias}", redirect.DeleteHandler(logger, repo))
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware2.NewAuthMW(logger, deps.tokens, repo))
+		r.Get("/url", url.ListHandler(logger, repo, s.cfg.LinkCheck.BrokenAfter))
+		r.Get("/url/{alias}/stats", url.StatsHandler(logger, repo))
+		r.Get("/url/export", url.ExportHandler(logger, repo))
+		r.Get("/webhooks", webhooks.ListHandler(logger, repo))
+		r.Get("/workspaces", workspaces.ListHandler(logger, repo))
+		r.Get("/workspaces/{id}/members", workspaces.MembersHandler(logger, repo))
+
+		r.Get("/me/sessions", auth.SessionsHandler(logger, repo))
+		r.Post("/me/2fa/setup", auth.SetupTwoFactorHandler(logger, repo, s.cfg.Auth.TOTPIssuer))
+		r.Post("/me/2fa/enable", auth.EnableTwoFactorHandler(logger, repo))
+		r.Post("/email/verify/resend", auth.ResendVerificationHandler(logger, repo, deps.emails))
+
+		// read-only accounts can look at their links but not change them
+		r.Group(func(r chi.Router) {
+			r.Use(middleware2.RequireRole(logger, models.RoleAdmin, models.RoleMember))
+			r.Group(func(r chi.Router) {
+				if s.cfg.Auth.RequireVerifiedEmail {
+					r.Use(middleware2.RequireVerifiedEmail(logger, repo))
+				}
+				r.Post("/url", url.New(logger, repo, url.Options{
+					Aliases:  deps.aliases,
+					Dedupe:   s.cfg.DedupeURLs,
+					Metadata: deps.metadata,
+					Events:   deps.events,
+				}))
+				r.Post("/url/import", url.ImportHandler(logger, repo, url.Options{
+					Aliases:  deps.aliases,
+					Metadata: deps.metadata,
+					Events:   deps.events,
+				}))
+			})
+			r.Put("/url/{alias}/variants", url.VariantsHandler(logger, repo, deps.events))
+			r.Post("/webhooks", webhooks.CreateHandler(logger, repo))
+			r.Delete("/webhooks/{id}", webhooks.DeleteHandler(logger, repo))
+			r.Post("/workspaces", workspaces.CreateHandler(logger, repo))
+			r.Post("/workspaces/{id}/invites", workspaces.InviteHandler(logger, repo))
+			r.Post("/workspaces/{id}/join", workspaces.JoinHandler(logger, repo))
+			r.Delete("/workspaces/{id}/members/{user_id}", workspaces.RemoveMemberHandler(logger, repo))
+			r.Delete("/{alias}", redirect.DeleteHandler(logger, repo, deps.events))
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware2.RequireRole(logger, models.RoleAdmin))
+			r.Get("/users", admin.UsersHandler(logger, repo))
+			r.Patch("/users/{id}", admin.UpdateUserHandler(logger, repo))
+			r.Get("/links", admin.LinksHandler(logger, repo))
+			r.Delete("/links/{alias}", redirect.DeleteHandler(logger, repo, deps.events))
+		})
 	})
-	s.router.Get("/{alias}", redirect.GetHandler(logger, repo))
-	s.router.Post("/register", auth.RegisterHandler(logger, repo))
-	s.router.Post("/login", auth.LoginHandler(logger, repo))
+	s.router.Get("/{alias}", redirect.GetHandler(logger, repo, redirect.Options{
+		Geo:             deps.geo,
+		PreviewAlways:   s.cfg.Preview.Always,
+		Interstitial:    s.cfg.Preview.Interstitial,
+		InternalDomains: s.cfg.Preview.InternalDomains,
+		Clicks:          deps.clicks,
+	}))
+	s.router.Post("/register", auth.RegisterHandler(logger, repo, deps.tokens, deps.passwords, deps.policy, deps.emails))
+	s.router.Post("/login", auth.LoginHandler(logger, repo, deps.tokens, deps.passwords, deps.guard))
+	s.router.Post("/login/2fa", auth.TwoFactorLoginHandler(logger, repo, deps.tokens, deps.guard))
+	s.router.Get("/auth/oidc/login", auth.OIDCLoginHandler(logger, deps.sso))
+	s.router.Get("/auth/oidc/callback", auth.OIDCCallbackHandler(logger, repo, deps.tokens, deps.sso))
+	s.router.Get("/email/verify", auth.VerifyEmailHandler(logger, repo))
+	s.router.Post("/password/forgot", auth.ForgotPasswordHandler(logger, repo, deps.emails))
+	s.router.Post("/password/reset", auth.ResetPasswordHandler(logger, repo, deps.passwords, deps.policy))
+	s.router.Get("/.well-known/jwks.json", auth.JWKSHandler(deps.tokens))
+	s.router.Get("/openapi.json", openapi.Handler(openapi.Spec()))
+}
+
+// newSSO returns the single sign-on of cfg.OIDC.
+func newSSO(cfg config.Auth) *auth.SSO {
+	redirectURL := cfg.OIDC.RedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(cfg.BaseURL, "/") + "/auth/oidc/callback"
+	}
+	return &auth.SSO{
+		Provider: oidc.New(oidc.Options{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		}),
+		AllowedDomains: cfg.OIDC.AllowedDomains,
+		SecureCookie:   strings.HasPrefix(redirectURL, "https://"),
+	}
+}
+
+// newMailer returns the mailer selected by cfg.Driver. SMTP mails are sent in
+// the background.
+func newMailer(logger *slog.Logger, cfg config.Mail) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return mailer.NewLog(logger), nil
+	case "file":
+		return mailer.NewFile(cfg.Dir, cfg.From)
+	case "smtp":
+		return mailer.NewAsync(logger, mailer.NewSMTP(mailer.SMTPOptions{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		}), cfg.SMTP.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
 }
 
 func (s *server) Run() error {
@@ -63,5 +341,11 @@ func (s *server) Run() error {
 		WriteTimeout:      s.cfg.Timeout,
 		IdleTimeout:       s.cfg.IdleTimeout,
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, worker := range s.workers {
+		go worker(ctx)
+	}
 	return srv.ListenAndServe()
 }
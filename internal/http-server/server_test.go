@@ -0,0 +1,150 @@
+package http_server
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/openapi"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	srv, err := New(slog.New(custom_mocks.NewMockLogger()), &config.Config{JwtSecret: "test"}, nil)
+	require.NoError(t, err)
+	spec := openapi.Spec()
+
+	routes := map[string]bool{}
+	err = chi.Walk(srv.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		routes[method+" "+route] = true
+		require.Truef(t, spec.Has(method, route), "route %s %s is missing from the OpenAPI spec", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			require.Truef(t, routes[strings.ToUpper(method)+" "+path], "documented operation %s %s is not routed", method, path)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	srv, err := New(slog.New(custom_mocks.NewMockLogger()), &config.Config{JwtSecret: "test"}, nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+	require.Contains(t, doc.Components.Schemas["URLRequest"].Required, "url")
+}
+
+// stubRepo serves the user the auth middleware looks up and records the
+// aliases asked for, every other method panics.
+type stubRepo struct {
+	URLRepo
+	user    models.User
+	aliases []string
+}
+
+func (r *stubRepo) GetUserByID(id int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *stubRepo) GetLink(alias string) (*models.UrlShortener, error) {
+	r.aliases = append(r.aliases, alias)
+	return nil, storage.ErrUrlNotFound
+}
+
+func TestRoutesRequireRole(t *testing.T) {
+	repo := &stubRepo{}
+	srv, err := New(slog.New(custom_mocks.NewMockLogger()), &config.Config{JwtSecret: "test"}, repo)
+	require.NoError(t, err)
+	tokens, err := jwt_helper.NewHMAC("test")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		anonymous bool
+		role      string
+		// user is the account at the time of the request, it has role when
+		// nil.
+		user   *models.User
+		method string
+		path   string
+		code   int
+	}{
+		{name: "Member on admin route", role: models.RoleMember, method: http.MethodGet, path: "/admin/users", code: http.StatusForbidden},
+		{name: "Legacy token on admin route", method: http.MethodGet, path: "/admin/links", code: http.StatusForbidden},
+		{name: "Read-only creates link", role: models.RoleReadOnly, method: http.MethodPost, path: "/url", code: http.StatusForbidden},
+		{name: "Read-only deletes link", role: models.RoleReadOnly, method: http.MethodDelete, path: "/docs", code: http.StatusForbidden},
+		{name: "Read-only deletes via admin route", role: models.RoleReadOnly, method: http.MethodDelete, path: "/admin/links/docs", code: http.StatusForbidden},
+		{name: "No token", anonymous: true, method: http.MethodGet, path: "/admin/users", code: http.StatusUnauthorized},
+		{
+			name: "Admin demoted after login", role: models.RoleAdmin, user: &models.User{Id: 1, Role: models.RoleMember},
+			method: http.MethodGet, path: "/admin/users", code: http.StatusForbidden,
+		},
+		{
+			name: "Admin disabled after login", role: models.RoleAdmin, user: &models.User{Id: 1, Role: models.RoleAdmin, Disabled: true},
+			method: http.MethodGet, path: "/admin/users", code: http.StatusForbidden,
+		},
+		{
+			name: "Member demoted after login", role: models.RoleMember, user: &models.User{Id: 1, Role: models.RoleReadOnly},
+			method: http.MethodPost, path: "/url", code: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo.user = models.User{Id: 1, Role: tc.role}
+			if tc.user != nil {
+				repo.user = *tc.user
+			}
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if !tc.anonymous {
+				token, err := tokens.NewToken(models.User{Id: 1, Email: "user@example.com", Role: tc.role})
+				require.NoError(t, err)
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, r)
+			require.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+func TestNewRefusesMissingSecret(t *testing.T) {
+	_, err := New(slog.New(custom_mocks.NewMockLogger()), &config.Config{}, nil)
+	require.ErrorIs(t, err, jwt_helper.ErrNoSecret)
+}
+
+// Paths with an extension are routed as they are: /openapi.json is the spec
+// and /docs.json looks up the alias docs.json, not docs.
+func TestRoutesKeepExtensions(t *testing.T) {
+	repo := &stubRepo{}
+	srv, err := New(slog.New(custom_mocks.NewMockLogger()), &config.Config{JwtSecret: "test"}, repo)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs.json", nil))
+	require.Equal(t, []string{"docs.json"}, repo.aliases)
+}
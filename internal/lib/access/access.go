@@ -0,0 +1,55 @@
+// Package access decides what a user may do with a link. Personal links are
+// only accessible to their creator. Workspace links are visible to and
+// editable by every member of the workspace, but only workspace admins and
+// the creator of a link may delete it. Admins of the service may delete any
+// link.
+package access
+
+import (
+	"errors"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// ErrForbidden is returned to workspace members lacking the permission for
+// an action.
+var ErrForbidden = errors.New("forbidden")
+
+type Action int
+
+const (
+	View Action = iota
+	Edit
+	Delete
+)
+
+// Membership looks up the role of a user in a workspace.
+type Membership interface {
+	WorkspaceRole(workspaceId, userId int64) (string, error)
+}
+
+// Check returns nil when the user of claims may perform action on link.
+// Links the user cannot see are reported as storage.ErrUrlNotFound.
+func Check(m Membership, claims *jwt_helper.UserClaims, link *models.UrlShortener, action Action) error {
+	if action == Delete && claims.HasRole(models.RoleAdmin) {
+		return nil
+	}
+	if link.WorkspaceId == 0 {
+		if link.UserId != claims.Id {
+			return storage.ErrUrlNotFound
+		}
+		return nil
+	}
+	role, err := m.WorkspaceRole(link.WorkspaceId, claims.Id)
+	if errors.Is(err, storage.ErrNotMember) {
+		return storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if action == Delete && role != models.WorkspaceRoleAdmin && link.UserId != claims.Id {
+		return ErrForbidden
+	}
+	return nil
+}
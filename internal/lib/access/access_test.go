@@ -0,0 +1,68 @@
+package access_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/lib/access"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// membership maps workspace ids to the roles of user ids.
+type membership map[int64]map[int64]string
+
+func (m membership) WorkspaceRole(workspaceId, userId int64) (string, error) {
+	if workspaceId == 99 {
+		return "", errors.New("storage down")
+	}
+	role, ok := m[workspaceId][userId]
+	if !ok {
+		return "", storage.ErrNotMember
+	}
+	return role, nil
+}
+
+func TestCheck(t *testing.T) {
+	members := membership{1: {10: models.WorkspaceRoleAdmin, 11: models.WorkspaceRoleMember, 12: models.WorkspaceRoleMember}}
+	personal := &models.UrlShortener{UserId: 11}
+	shared := &models.UrlShortener{UserId: 12, WorkspaceId: 1}
+
+	cases := []struct {
+		name   string
+		claims jwt_helper.UserClaims
+		link   *models.UrlShortener
+		action access.Action
+		err    error
+	}{
+		{name: "Creator edits personal link", claims: jwt_helper.UserClaims{Id: 11}, link: personal, action: access.Edit},
+		{name: "Other user views personal link", claims: jwt_helper.UserClaims{Id: 12}, link: personal, action: access.View, err: storage.ErrUrlNotFound},
+		{name: "Admin views personal link", claims: jwt_helper.UserClaims{Id: 1, Role: models.RoleAdmin}, link: personal, action: access.View, err: storage.ErrUrlNotFound},
+		{name: "Admin deletes personal link", claims: jwt_helper.UserClaims{Id: 1, Role: models.RoleAdmin}, link: personal, action: access.Delete},
+		{name: "Member views workspace link", claims: jwt_helper.UserClaims{Id: 11}, link: shared, action: access.View},
+		{name: "Member edits workspace link", claims: jwt_helper.UserClaims{Id: 11}, link: shared, action: access.Edit},
+		{name: "Member deletes workspace link", claims: jwt_helper.UserClaims{Id: 11}, link: shared, action: access.Delete, err: access.ErrForbidden},
+		{name: "Creator deletes workspace link", claims: jwt_helper.UserClaims{Id: 12}, link: shared, action: access.Delete},
+		{name: "Workspace admin deletes workspace link", claims: jwt_helper.UserClaims{Id: 10}, link: shared, action: access.Delete},
+		{name: "Outsider views workspace link", claims: jwt_helper.UserClaims{Id: 13}, link: shared, action: access.View, err: storage.ErrUrlNotFound},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := access.Check(members, &tc.claims, tc.link, tc.action)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	err := access.Check(members, &jwt_helper.UserClaims{Id: 11}, &models.UrlShortener{WorkspaceId: 99}, access.View)
+	require.EqualError(t, err, "storage down")
+}
@@ -0,0 +1,97 @@
+package alias_generator
+
+import (
+	"errors"
+	"fmt"
+	"url-shortener/internal/config"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+)
+
+const (
+	KindRandom     = "random"
+	KindSequential = "sequential"
+	KindHashids    = "hashids"
+	KindWords      = "words"
+)
+
+const (
+	DefaultLength        = 8
+	DefaultWords         = 3
+	DefaultMaxAttempts   = 10
+	DefaultEscalateAfter = 3
+)
+
+var ErrExhausted = errors.New("no free alias found")
+
+// AliasGenerator produces candidate aliases. extra grows with the number of
+// collisions so that generators can escalate to longer aliases when their
+// keyspace gets dense.
+type AliasGenerator interface {
+	Generate(extra int) (string, error)
+}
+
+// Sequence hands out increasing numbers that are never reused.
+type Sequence interface {
+	NextAliasSequence() (int64, error)
+}
+
+// Policy bounds the number of attempts to find a free alias.
+type Policy struct {
+	Generator     AliasGenerator
+	MaxAttempts   int
+	EscalateAfter int
+}
+
+// Next returns the candidate for the zero-based attempt, or ErrExhausted once
+// MaxAttempts candidates were handed out.
+func (p Policy) Next(attempt int) (string, error) {
+	if attempt >= p.MaxAttempts {
+		return "", ErrExhausted
+	}
+	extra := 0
+	if p.EscalateAfter > 0 {
+		extra = attempt / p.EscalateAfter
+	}
+	for {
+		alias, err := p.Generator.Generate(extra)
+		if err != nil || !custom_validators.IsReservedAlias(alias) {
+			return alias, err
+		}
+	}
+}
+
+// New builds the policy selected in cfg.
+func New(cfg config.Alias, seq Sequence) (Policy, error) {
+	policy := Policy{
+		MaxAttempts:   cfg.MaxAttempts,
+		EscalateAfter: cfg.EscalateAfter,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultMaxAttempts
+	}
+	if policy.EscalateAfter <= 0 {
+		policy.EscalateAfter = DefaultEscalateAfter
+	}
+	length := cfg.Length
+	if length <= 0 {
+		length = DefaultLength
+	}
+
+	switch cfg.Generator {
+	case KindRandom, "":
+		policy.Generator = NewRandom(length)
+	case KindSequential:
+		policy.Generator = NewSequential(seq)
+	case KindHashids:
+		policy.Generator = NewHashids(seq, cfg.Salt, length)
+	case KindWords:
+		words := cfg.Words
+		if words <= 0 {
+			words = DefaultWords
+		}
+		policy.Generator = NewWords(words)
+	default:
+		return Policy{}, fmt.Errorf("unknown alias generator %q", cfg.Generator)
+	}
+	return policy, nil
+}
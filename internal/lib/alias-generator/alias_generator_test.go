@@ -0,0 +1,90 @@
+package alias_generator_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+	"url-shortener/internal/config"
+	alias_generator "url-shortener/internal/lib/alias-generator"
+)
+
+type counter struct {
+	n int64
+}
+
+func (c *counter) NextAliasSequence() (int64, error) {
+	c.n++
+	return c.n, nil
+}
+
+func TestSequential(t *testing.T) {
+	gen := alias_generator.NewSequential(&counter{n: 59})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		alias, err := gen.Generate(0)
+		require.NoError(t, err)
+		got = append(got, alias)
+	}
+	require.Equal(t, []string{"Y", "Z", "10", "11"}, got)
+}
+
+func TestHashidsCollisionFree(t *testing.T) {
+	gen := alias_generator.NewHashids(&counter{}, "pepper", 3)
+	other := alias_generator.NewHashids(&counter{}, "salt", 3)
+
+	seen := map[string]bool{}
+	lengths := map[int]bool{}
+	for i := 0; i < 62*62*62+100; i++ {
+		alias, err := gen.Generate(0)
+		require.NoError(t, err)
+		require.False(t, seen[alias], "duplicate alias %s", alias)
+		seen[alias] = true
+		lengths[len(alias)] = true
+	}
+	require.Equal(t, map[int]bool{3: true, 4: true}, lengths, "length grows once the keyspace is used up")
+
+	first, err := other.Generate(0)
+	require.NoError(t, err)
+	require.NotEqual(t, first, alias(t, alias_generator.NewHashids(&counter{}, "pepper", 3)), "salt changes the mapping")
+}
+
+func alias(t *testing.T, gen alias_generator.AliasGenerator) string {
+	a, err := gen.Generate(0)
+	require.NoError(t, err)
+	return a
+}
+
+func TestRandomAndWordsEscalate(t *testing.T) {
+	random := alias_generator.NewRandom(6)
+	require.Regexp(t, regexp.MustCompile(`^[a-zA-Z0-9]{6}$`), alias(t, random))
+	long, err := random.Generate(2)
+	require.NoError(t, err)
+	require.Len(t, long, 8)
+
+	words := alias_generator.NewWords(2)
+	require.Len(t, strings.Split(alias(t, words), "-"), 2)
+	more, err := words.Generate(1)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(more, "-"), 3)
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := alias_generator.New(config.Alias{Length: 4, MaxAttempts: 5, EscalateAfter: 2}, nil)
+	require.NoError(t, err)
+
+	var lengths []int
+	for attempt := 0; ; attempt++ {
+		a, err := policy.Next(attempt)
+		if err != nil {
+			require.ErrorIs(t, err, alias_generator.ErrExhausted)
+			break
+		}
+		lengths = append(lengths, len(a))
+	}
+	require.Equal(t, []int{4, 4, 5, 5, 6}, lengths)
+
+	_, err = alias_generator.New(config.Alias{Generator: "unknown"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package alias_generator
+
+import "url-shortener/internal/lib/random"
+
+type randomGenerator struct {
+	length int
+}
+
+// NewRandom generates crypto-random base62 aliases of the given length,
+// one character longer per escalation step.
+func NewRandom(length int) AliasGenerator {
+	return randomGenerator{length: length}
+}
+
+func (g randomGenerator) Generate(extra int) (string, error) {
+	return random.NewRandomString(g.length + extra), nil
+}
@@ -0,0 +1,120 @@
+package alias_generator
+
+import (
+	"errors"
+	"math/bits"
+)
+
+const base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// maxHashidsLength keeps 62^length within an uint64.
+const maxHashidsLength = 10
+
+type sequential struct {
+	seq Sequence
+}
+
+// NewSequential encodes numbers of seq in base62. Aliases are short and never
+// collide with each other, only with custom aliases, in which case the next
+// number is used.
+func NewSequential(seq Sequence) AliasGenerator {
+	return sequential{seq: seq}
+}
+
+func (g sequential) Generate(int) (string, error) {
+	n, err := g.seq.NextAliasSequence()
+	if err != nil {
+		return "", err
+	}
+	return encode(uint64(n), base62, 0), nil
+}
+
+type hashids struct {
+	seq       Sequence
+	alphabet  string
+	offset    uint64
+	minLength int
+}
+
+// multiplier is coprime to 62, so multiplying by it permutes [0, 62^n).
+const multiplier = 1_580_030_173
+
+// NewHashids obfuscates numbers of seq: every number is mapped by a
+// salt-dependent permutation of [0, 62^n) and written with a salt-shuffled
+// alphabet. The mapping is a bijection, so aliases stay collision-free while
+// not revealing how many links exist.
+func NewHashids(seq Sequence, salt string, minLength int) AliasGenerator {
+	var offset uint64 = 14695981039346656037
+	for i := 0; i < len(salt); i++ {
+		offset ^= uint64(salt[i])
+		offset *= 1099511628211
+	}
+	return hashids{
+		seq:       seq,
+		alphabet:  shuffle(base62, salt),
+		offset:    offset,
+		minLength: min(max(minLength, 1), maxHashidsLength),
+	}
+}
+
+func (g hashids) Generate(int) (string, error) {
+	n, err := g.seq.NextAliasSequence()
+	if err != nil {
+		return "", err
+	}
+	id := uint64(n)
+	length, space := g.minLength, pow62(g.minLength)
+	for id >= space {
+		if length == maxHashidsLength {
+			return "", errors.New("sequence exceeds the hashids keyspace")
+		}
+		length++
+		space = pow62(length)
+	}
+	hi, lo := bits.Mul64(id, multiplier)
+	permuted := bits.Rem64(hi, lo, space)
+	permuted = (permuted + g.offset%space) % space
+	return encode(permuted, g.alphabet, length), nil
+}
+
+// encode writes n in base len(alphabet), left padded to length.
+func encode(n uint64, alphabet string, length int) string {
+	base := uint64(len(alphabet))
+	var buf []byte
+	for n > 0 || len(buf) == 0 {
+		buf = append(buf, alphabet[n%base])
+		n /= base
+	}
+	for len(buf) < length {
+		buf = append(buf, alphabet[0])
+	}
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
+
+func pow62(n int) uint64 {
+	res := uint64(1)
+	for i := 0; i < n; i++ {
+		res *= 62
+	}
+	return res
+}
+
+// shuffle is the consistent shuffle used by hashids.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+	res := []byte(alphabet)
+	for i, v, p := len(res)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		c := int(salt[v])
+		p += c
+		j := (c + v + p) % i
+		res[i], res[j] = res[j], res[i]
+		v++
+	}
+	return string(res)
+}
@@ -0,0 +1,67 @@
+package alias_generator
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+var adjectives = strings.Fields(`
+	able bold brave bright brisk calm clever cool cosmic crisp curly daring
+	dusty eager early easy epic fair fancy fast fierce fine fluffy fresh
+	friendly funny fuzzy gentle giant glad golden grand green happy hasty
+	heavy hidden honest huge humble icy jolly keen kind large lazy little
+	lively loud lucky magic merry mighty misty modern narrow neat nimble
+	noble odd old plain polite proud quick quiet rapid rare ready red rich
+	round royal rusty sandy shiny silent silly simple sleepy slow small smart
+	smooth snowy soft solid spicy steady still stormy strong sunny super sweet
+	swift tall tidy tiny tough vast warm wild windy wise witty young zesty
+`)
+
+var nouns = strings.Fields(`
+	anchor apple arrow badger banana beacon bear beaver bison breeze brook
+	cactus canyon castle cedar cherry cloud comet coral crane creek crystal
+	dolphin dragon eagle ember falcon feather fern field finch fjord forest
+	fox galaxy garden gecko glacier grape harbor hawk heron hill island
+	jaguar kettle koala lagoon lake lantern lemon leopard lion lotus maple
+	meadow meteor mango moon moose mountain nebula oak ocean orchid otter owl
+	panda parrot peach pebble pepper pine planet pony prairie puffin quartz
+	rabbit raven reef river robin rocket saddle salmon shell sparrow spruce
+	squirrel star stone summit tiger tulip turtle valley violet walrus whale
+	willow wolf zebra
+`)
+
+type words struct {
+	count int
+}
+
+// NewWords generates human readable aliases like "brave-misty-otter": count-1
+// adjectives followed by a noun. Every escalation step adds an adjective.
+func NewWords(count int) AliasGenerator {
+	return words{count: max(count, 1)}
+}
+
+func (g words) Generate(extra int) (string, error) {
+	n := g.count + extra
+	parts := make([]string, 0, n)
+	for i := 0; i < n-1; i++ {
+		w, err := pick(adjectives)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, w)
+	}
+	w, err := pick(nouns)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append(parts, w), "-"), nil
+}
+
+func pick(list []string) (string, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(list))))
+	if err != nil {
+		return "", err
+	}
+	return list[i.Int64()], nil
+}
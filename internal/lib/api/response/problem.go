@@ -0,0 +1,91 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+const (
+	// ProblemTypeBlank is the RFC 9457 default type: the problem has no
+	// semantics beyond its HTTP status code.
+	ProblemTypeBlank      = "about:blank"
+	ProblemTypeValidation = "/problems/validation-error"
+)
+
+// Problem is an RFC 9457 problem details document.
+type Problem struct {
+	Type     string                         `json:"type"`
+	Title    string                         `json:"title"`
+	Status   int                            `json:"status"`
+	Detail   string                         `json:"detail,omitempty"`
+	Instance string                         `json:"instance,omitempty"`
+	Errors   []custom_validators.FieldError `json:"errors,omitempty"`
+}
+
+// WantsProblem reports whether the client asked for application/problem+json
+// in its Accept header. Clients that did not keep the legacy Response shape.
+func WantsProblem(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != ContentTypeProblem {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// RenderError writes an error with the given status code, either as a problem
+// document or as the legacy Response envelope depending on the Accept header.
+func RenderError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if !WantsProblem(r) {
+		render.Status(r, status)
+		render.JSON(w, r, Error(msg))
+		return
+	}
+	writeProblem(w, r, Problem{
+		Type:   ProblemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: msg,
+	})
+}
+
+// RenderValidationError writes a 400 response for a request that failed
+// decoding or validation. Field errors produced by
+// custom_validators.ValidationError are listed in the problem's errors array.
+func RenderValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErrs custom_validators.ValidationErrors
+	if !WantsProblem(r) || !errors.As(err, &fieldErrs) {
+		RenderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeProblem(w, r, Problem{
+		Type:   ProblemTypeValidation,
+		Title:  "Request validation failed",
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+		Errors: fieldErrs,
+	})
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Instance = middleware.GetReqID(r.Context())
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
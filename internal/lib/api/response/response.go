@@ -10,8 +10,6 @@ const (
 	StatusError = "error"
 )
 
-const AliasFixedLength = 8
-
 func OK() Response {
 	return Response{
 		Status: StatusOK,
@@ -1,37 +1,68 @@
 package custom_validators
 
 import (
-	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"strings"
 )
 
+// FieldError describes a single failed validation rule of a request field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+// ValidationErrors is returned by ValidationError and keeps the per-field
+// details so that they can be rendered in problem responses.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Detail)
+	}
+	return strings.Join(msgs, ", ")
+}
+
 func AliasValidation(fl validator.FieldLevel) bool {
-	value := fl.Field().String()
-	switch value {
-	case "url", "register", "login":
-		return false
-	default:
+	return !IsReservedAlias(fl.Field().String())
+}
+
+// PreviewSuffix appended to an alias shows the preview page of the link.
+const PreviewSuffix = "+"
+
+// IsReservedAlias reports whether alias would shadow one of the API routes
+// or the preview page of another alias.
+func IsReservedAlias(alias string) bool {
+	if strings.HasSuffix(alias, PreviewSuffix) {
 		return true
 	}
+	switch alias {
+	case "url", "register", "login", "openapi.json", "webhooks", "workspaces", "admin":
+		return true
+	default:
+		return false
+	}
 }
 
 func ValidationError(errs validator.ValidationErrors) error {
-	var errMessages []string
+	var fieldErrs ValidationErrors
 
 	for _, err := range errs {
+		fe := FieldError{Field: err.Field(), Rule: err.ActualTag()}
 		switch err.ActualTag() {
 		case "required":
-			errMessages = append(errMessages, fmt.Sprintf("field %s is required", err.Field()))
+			fe.Detail = fmt.Sprintf("field %s is required", err.Field())
 		case "url":
-			errMessages = append(errMessages, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+			fe.Detail = fmt.Sprintf("field %s is not a valid URL", err.Field())
 		case "isValidAlias":
-			errMessages = append(errMessages, "bad alias")
+			fe.Detail = "bad alias"
 		default:
-			errMessages = append(errMessages, fmt.Sprintf("field %s is not valid", err.Field()))
+			fe.Detail = fmt.Sprintf("field %s is not valid", err.Field())
 		}
+		fieldErrs = append(fieldErrs, fe)
 	}
 
-	return errors.New(strings.Join(errMessages, ", "))
+	return fieldErrs
 }
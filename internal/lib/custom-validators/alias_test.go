@@ -0,0 +1,28 @@
+package custom_validators_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	custom_validators "url-shortener/internal/lib/custom-validators"
+)
+
+func TestIsReservedAlias(t *testing.T) {
+	cases := []struct {
+		alias    string
+		reserved bool
+	}{
+		{alias: "url", reserved: true},
+		{alias: "login", reserved: true},
+		{alias: "webhooks", reserved: true},
+		{alias: "workspaces", reserved: true},
+		{alias: "admin", reserved: true},
+		{alias: "docs+", reserved: true},
+		{alias: "docs", reserved: false},
+		{alias: "workspace", reserved: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.alias, func(t *testing.T) {
+			require.Equal(t, tc.reserved, custom_validators.IsReservedAlias(tc.alias))
+		})
+	}
+}
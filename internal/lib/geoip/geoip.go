@@ -0,0 +1,131 @@
+// Package geoip resolves client addresses to countries using a local CSV
+// database, so that lookups need neither network access nor a vendor SDK.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrBadRecord = errors.New("bad geoip record")
+
+type ipRange struct {
+	first, last netip.Addr
+	country     string
+}
+
+// DB is an in-memory country database. The zero value knows no addresses.
+type DB struct {
+	ranges []ipRange
+}
+
+// Open loads the CSV file at path, see Load for the format.
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load reads records of either "network,country" with the network in CIDR
+// notation or "first_ip,last_ip,country", which is the layout of the freely
+// available DB-IP country lite files. Empty lines, lines starting with # and
+// a header line are skipped. Countries are ISO 3166-1 alpha-2 codes.
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	db := &DB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rng, err := parseRecord(record)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		db.ranges = append(db.ranges, rng)
+	}
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].first.Less(db.ranges[j].first)
+	})
+	return db, nil
+}
+
+func parseRecord(record []string) (ipRange, error) {
+	var rng ipRange
+	switch len(record) {
+	case 2:
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return rng, errors.Join(ErrBadRecord, err)
+		}
+		prefix = prefix.Masked()
+		rng.first, rng.last = prefix.Addr(), lastAddr(prefix)
+	case 3:
+		first, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return rng, errors.Join(ErrBadRecord, err)
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return rng, errors.Join(ErrBadRecord, err)
+		}
+		if first.Is4() != last.Is4() || last.Less(first) {
+			return rng, fmt.Errorf("%w: invalid range %s-%s", ErrBadRecord, first, last)
+		}
+		rng.first, rng.last = first, last
+	default:
+		return rng, fmt.Errorf("%w: expected 2 or 3 fields, got %d", ErrBadRecord, len(record))
+	}
+	country := strings.ToUpper(strings.TrimSpace(record[len(record)-1]))
+	if len(country) != 2 {
+		return rng, fmt.Errorf("%w: invalid country %q", ErrBadRecord, country)
+	}
+	rng.country = country
+	return rng, nil
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Country returns the country of ip or an empty string when it is unknown.
+func (db *DB) Country(ip netip.Addr) string {
+	if db == nil || !ip.IsValid() {
+		return ""
+	}
+	ip = ip.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return ip.Less(db.ranges[i].first)
+	})
+	if i == 0 {
+		return ""
+	}
+	rng := db.ranges[i-1]
+	if ip.Is4() != rng.first.Is4() || rng.last.Less(ip) {
+		return ""
+	}
+	return rng.country
+}
@@ -0,0 +1,51 @@
+package geoip_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/netip"
+	"strings"
+	"testing"
+	"url-shortener/internal/lib/geoip"
+)
+
+const testDB = `network,country
+# documentation ranges
+192.0.2.0/24,de
+198.51.100.0,198.51.100.127,US
+2001:db8::/32,FR
+`
+
+func TestCountry(t *testing.T) {
+	db, err := geoip.Load(strings.NewReader(testDB))
+	require.NoError(t, err)
+
+	cases := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "192.0.2.0", want: "DE"},
+		{ip: "192.0.2.255", want: "DE"},
+		{ip: "192.0.3.0", want: ""},
+		{ip: "198.51.100.127", want: "US"},
+		{ip: "198.51.100.128", want: ""},
+		{ip: "::ffff:192.0.2.10", want: "DE"},
+		{ip: "2001:db8::1", want: "FR"},
+		{ip: "2001:db9::1", want: ""},
+		{ip: "10.0.0.1", want: ""},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.ip, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, db.Country(netip.MustParseAddr(tc.ip)))
+		})
+	}
+}
+
+func TestLoadBadRecord(t *testing.T) {
+	_, err := geoip.Load(strings.NewReader("192.0.2.0/24,DE\nnot-an-ip,US\n"))
+	require.ErrorIs(t, err, geoip.ErrBadRecord)
+}
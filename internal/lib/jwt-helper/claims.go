@@ -2,14 +2,34 @@ package jwt_helper
 
 import (
 	"github.com/golang-jwt/jwt/v5"
+	"slices"
 	"strconv"
 	"time"
+	"url-shortener/internal/models"
 )
 
 type UserClaims struct {
 	Id    int64  `json:"uid"`
 	Email string `json:"email"`
-	Exp   int64  `json:"exp"`
+	// Role is empty in tokens issued before roles were introduced.
+	Role string `json:"role,omitempty"`
+	Exp  int64  `json:"exp"`
+	// Purpose is empty in access tokens and PurposeTwoFactor in challenge
+	// tokens, which only grant the second step of a login.
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// UserRole returns the role of the token, members for tokens without one.
+func (c *UserClaims) UserRole() string {
+	if c.Role == "" {
+		return models.RoleMember
+	}
+	return c.Role
+}
+
+// HasRole reports whether the role of the token is one of roles.
+func (c *UserClaims) HasRole(roles ...string) bool {
+	return slices.Contains(roles, c.UserRole())
 }
 
 func (c *UserClaims) GetExpirationTime() (*jwt.NumericDate, error) {
@@ -0,0 +1,16 @@
+package jwt_helper
+
+import "context"
+
+type ctxKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of a validated token.
+func WithClaims(ctx context.Context, claims *UserClaims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims.
+func ClaimsFromContext(ctx context.Context) (*UserClaims, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(*UserClaims)
+	return claims, ok
+}
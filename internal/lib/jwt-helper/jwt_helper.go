@@ -2,7 +2,9 @@ package jwt_helper
 
 import (
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"strings"
 	"time"
 	"url-shortener/internal/config"
 	"url-shortener/internal/models"
@@ -10,42 +12,145 @@ import (
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
+	// ErrNoSecret is returned by New for a config without jwt_secret or keys.
+	ErrNoSecret = errors.New("jwt_secret is empty and no jwt keys are configured")
 )
 
-var secret string
+// PurposeTwoFactor marks challenge tokens.
+const PurposeTwoFactor = "2fa"
 
-func InitJwtHelper(cfg *config.Config) {
-	secret = cfg.JwtSecret
+// TokenTTL is how long access tokens are valid.
+const TokenTTL = 3 * time.Hour
+
+// ChallengeTTL is the time left to send the second factor after the
+// password.
+const ChallengeTTL = 5 * time.Minute
+
+// TokenIssuer signs and validates the tokens of the service.
+type TokenIssuer interface {
+	NewToken(user models.User) (string, error)
+	// NewChallengeToken returns a short-lived token that user exchanges for
+	// an access token with a second factor.
+	NewChallengeToken(user models.User) (string, error)
+	// ValidateToken validates an access token.
+	ValidateToken(token string) (*UserClaims, error)
+	// ValidateChallengeToken validates a token of NewChallengeToken.
+	ValidateChallengeToken(token string) (*UserClaims, error)
+}
+
+// Issuer is a TokenIssuer with a signing key and the keys it accepts by kid.
+type Issuer struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// New returns the issuer configured by cfg: with the keys of cfg.JWT if
+// there are any, with HS256 and cfg.JwtSecret otherwise.
+func New(cfg *config.Config) (*Issuer, error) {
+	if len(cfg.JWT.Keys) == 0 {
+		return NewHMAC(cfg.JwtSecret)
+	}
+	keys := make([]*Key, 0, len(cfg.JWT.Keys))
+	for _, k := range cfg.JWT.Keys {
+		key, err := LoadKey(k.ID, k.Path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewIssuer(cfg.JWT.SigningKey, keys...)
+}
+
+// NewHMAC returns an issuer that signs and verifies with HS256 and secret.
+func NewHMAC(secret string) (*Issuer, error) {
+	if strings.TrimSpace(secret) == "" {
+		return nil, ErrNoSecret
+	}
+	key := &Key{Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &Issuer{signing: key, keys: map[string]*Key{"": key}}, nil
+}
+
+// NewIssuer returns an issuer that signs with the key with id signingKey
+// and verifies with all keys.
+func NewIssuer(signingKey string, keys ...*Key) (*Issuer, error) {
+	issuer := &Issuer{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("jwt key without id")
+		}
+		if _, ok := issuer.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key %q", key.ID)
+		}
+		issuer.keys[key.ID] = key
+	}
+	signing, ok := issuer.keys[signingKey]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKey)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKey)
+	}
+	issuer.signing = signing
+	return issuer, nil
 }
 
-func NewToken(user models.User) (string, error) {
-	//token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-	//	"uid":   user.Id,
-	//	"email": user.Email,
-	//	"exp":   time.Now().Add(time.Hour * 3).Unix(),
-	//})
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &UserClaims{
+func (i *Issuer) NewToken(user models.User) (string, error) {
+	return i.sign(&UserClaims{
 		Email: user.Email,
 		Id:    user.Id,
-		Exp:   time.Now().Add(time.Hour * 3).Unix(),
+		Role:  user.Role,
+		Exp:   time.Now().Add(TokenTTL).Unix(),
 	})
-	signed, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
+}
+
+func (i *Issuer) NewChallengeToken(user models.User) (string, error) {
+	return i.sign(&UserClaims{
+		Email:   user.Email,
+		Id:      user.Id,
+		Exp:     time.Now().Add(ChallengeTTL).Unix(),
+		Purpose: PurposeTwoFactor,
+	})
+}
+
+func (i *Issuer) ValidateToken(token string) (*UserClaims, error) {
+	return i.validate(token, "")
+}
+
+func (i *Issuer) ValidateChallengeToken(token string) (*UserClaims, error) {
+	return i.validate(token, PurposeTwoFactor)
+}
+
+func (i *Issuer) sign(claims *UserClaims) (string, error) {
+	token := jwt.NewWithClaims(i.signing.Method, claims)
+	if i.signing.ID != "" {
+		token.Header["kid"] = i.signing.ID
 	}
-	return signed, nil
+	return token.SignedString(i.signing.private)
 }
 
-func ValidateToken(token string) (*UserClaims, error) {
+func (i *Issuer) validate(token, purpose string) (*UserClaims, error) {
 	parser := jwt.NewParser()
 	claims, err := parser.ParseWithClaims(token, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := i.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// the algorithm of the key, not the one of the token, decides
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("algorithm %s does not match key %q", token.Method.Alg(), kid)
+		}
+		return key.public, nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, errors.Join(ErrInvalidToken, err)
 	}
 	if !claims.Valid {
 		return nil, ErrInvalidToken
 	}
-	return claims.Claims.(*UserClaims), nil
+	userClaims := claims.Claims.(*UserClaims)
+	if userClaims.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
+	return userClaims, nil
 }
@@ -0,0 +1,191 @@
+package jwt_helper_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	jwt_helper "url-shortener/internal/lib/jwt-helper"
+	"url-shortener/internal/models"
+)
+
+// writeKey writes key as PEM into a temporary directory and returns the path.
+func writeKey(t *testing.T, name string, key any) string {
+	t.Helper()
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), name+".pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func TestNewRefusesEmptySecret(t *testing.T) {
+	_, err := jwt_helper.New(&config.Config{})
+	require.ErrorIs(t, err, jwt_helper.ErrNoSecret)
+	_, err = jwt_helper.New(&config.Config{JwtSecret: "  "})
+	require.ErrorIs(t, err, jwt_helper.ErrNoSecret)
+}
+
+func TestHMAC(t *testing.T) {
+	issuer, err := jwt_helper.New(&config.Config{JwtSecret: "secret"})
+	require.NoError(t, err)
+	user := models.User{Id: 1, Email: "user@example.com", Role: models.RoleAdmin}
+
+	token, err := issuer.NewToken(user)
+	require.NoError(t, err)
+	claims, err := issuer.ValidateToken(token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), claims.Id)
+	require.Equal(t, models.RoleAdmin, claims.Role)
+
+	other, err := jwt_helper.NewHMAC("other")
+	require.NoError(t, err)
+	_, err = other.ValidateToken(token)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken)
+
+	challenge, err := issuer.NewChallengeToken(user)
+	require.NoError(t, err)
+	_, err = issuer.ValidateToken(challenge)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken, "challenge tokens are no access tokens")
+	_, err = issuer.ValidateChallengeToken(challenge)
+	require.NoError(t, err)
+
+	require.Empty(t, issuer.JWKS().Keys, "the secret is not published")
+}
+
+func TestRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	user := models.User{Id: 1, Email: "user@example.com"}
+
+	// the RSA key signs, the Ed25519 key is published ahead of rotation
+	before, err := jwt_helper.New(&config.Config{JWT: config.JWT{
+		Keys: []config.JWTKey{
+			{ID: "2024", Path: writeKey(t, "2024", rsaKey)},
+			{ID: "2025", Path: writeKey(t, "2025", edKey)},
+		},
+		SigningKey: "2024",
+	}})
+	require.NoError(t, err)
+	oldToken, err := before.NewToken(user)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &jwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, "RS256", parsed.Method.Alg())
+	require.Equal(t, "2024", parsed.Header["kid"])
+
+	set := before.JWKS()
+	require.Len(t, set.Keys, 2)
+	require.Equal(t, jwt_helper.JWK{Kty: "RSA", Kid: "2024", Use: "sig", Alg: "RS256", N: set.Keys[0].N, E: "AQAB"}, set.Keys[0])
+	require.Equal(t, "OKP", set.Keys[1].Kty)
+	require.Equal(t, "EdDSA", set.Keys[1].Alg)
+
+	// after the rotation the old key only verifies
+	after, err := jwt_helper.New(&config.Config{JWT: config.JWT{
+		Keys: []config.JWTKey{
+			{ID: "2024", Path: writeKey(t, "2024", &rsaKey.PublicKey)},
+			{ID: "2025", Path: writeKey(t, "2025", edKey)},
+		},
+		SigningKey: "2025",
+	}})
+	require.NoError(t, err)
+	_, err = after.ValidateToken(oldToken)
+	require.NoError(t, err, "tokens of the old key stay valid")
+	newToken, err := after.NewToken(user)
+	require.NoError(t, err)
+	_, err = before.ValidateToken(newToken)
+	require.NoError(t, err)
+
+	// once the old key is removed its tokens are refused
+	_, err = jwt_helper.NewIssuer("2025", mustParse(t, "2025", edPub))
+	require.Error(t, err, "public keys cannot sign")
+	final, err := jwt_helper.New(&config.Config{JWT: config.JWT{
+		Keys:       []config.JWTKey{{ID: "2025", Path: writeKey(t, "2025", edKey)}},
+		SigningKey: "2025",
+	}})
+	require.NoError(t, err)
+	_, err = final.ValidateToken(oldToken)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken)
+	_, err = final.ValidateToken(newToken)
+	require.NoError(t, err)
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer, err := jwt_helper.New(&config.Config{JWT: config.JWT{
+		Keys:       []config.JWTKey{{ID: "k", Path: writeKey(t, "k", rsaKey)}},
+		SigningKey: "k",
+	}})
+	require.NoError(t, err)
+	claims := &jwt_helper.UserClaims{Id: 1, Exp: time.Now().Add(time.Hour).Unix()}
+
+	// HS256 with the public key as secret must not pass as RS256
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "k"
+	token, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	_, err = issuer.ValidateToken(token)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken)
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	unknown.Header["kid"] = "other"
+	token, err = unknown.SignedString(rsaKey)
+	require.NoError(t, err)
+	_, err = issuer.ValidateToken(token)
+	require.ErrorIs(t, err, jwt_helper.ErrInvalidToken)
+}
+
+func TestConfigErrors(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		jwt  config.JWT
+	}{
+		{name: "missing file", jwt: config.JWT{Keys: []config.JWTKey{{ID: "k", Path: "missing.pem"}}, SigningKey: "k"}},
+		{name: "small RSA key", jwt: config.JWT{Keys: []config.JWTKey{{ID: "k", Path: writeKey(t, "k", small)}}, SigningKey: "k"}},
+		{name: "unknown signing key", jwt: config.JWT{Keys: []config.JWTKey{{ID: "k", Path: writeKey(t, "k", key)}}, SigningKey: "other"}},
+		{name: "duplicate id", jwt: config.JWT{Keys: []config.JWTKey{
+			{ID: "k", Path: writeKey(t, "a", key)},
+			{ID: "k", Path: writeKey(t, "b", key)},
+		}, SigningKey: "k"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jwt_helper.New(&config.Config{JwtSecret: "secret", JWT: tc.jwt})
+			require.Error(t, err)
+		})
+	}
+}
+
+func mustParse(t *testing.T, id string, key any) *jwt_helper.Key {
+	t.Helper()
+	parsed, err := jwt_helper.LoadKey(id, writeKey(t, id, key))
+	require.NoError(t, err)
+	return parsed
+}
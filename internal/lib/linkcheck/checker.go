@@ -0,0 +1,257 @@
+// Package linkcheck periodically checks link destinations and records which
+// of them are broken.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/safehttp"
+	"url-shortener/internal/models"
+)
+
+const (
+	DefaultInterval    = 24 * time.Hour
+	DefaultConcurrency = 4
+	DefaultHostDelay   = time.Second
+	DefaultBrokenAfter = 3
+
+	userAgent = "url-shortener-linkcheck/1.0 (+dead link check)"
+)
+
+type Store interface {
+	ListAllURLs() ([]models.UrlShortener, error)
+	SaveHealth(urlId int64, h models.Health) (int, error)
+}
+
+// Options configures a Checker. Zero values use the defaults.
+type Options struct {
+	// Interval is the time between two checks of the same link.
+	Interval time.Duration
+	// Concurrency limits the number of requests in flight.
+	Concurrency int
+	// HostDelay is the minimum time between two requests to the same host.
+	HostDelay time.Duration
+	Timeout   time.Duration
+	// BrokenAfter is the number of failed checks in a row after which a link
+	// is reported as broken.
+	BrokenAfter int
+	// AllowPrivate permits loopback, private and link-local destinations.
+	// It disables the SSRF protection and is meant for tests.
+	AllowPrivate bool
+}
+
+// Checker requests every link destination once per interval. A check fails
+// when the destination cannot be reached or answers with an error status;
+// HEAD requests answered with an error are retried with GET because some
+// servers do not implement HEAD.
+type Checker struct {
+	log    *slog.Logger
+	store  Store
+	client *http.Client
+	opts   Options
+}
+
+func New(log *slog.Logger, store Store, opts Options) *Checker {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.HostDelay <= 0 {
+		opts.HostDelay = DefaultHostDelay
+	}
+	if opts.BrokenAfter <= 0 {
+		opts.BrokenAfter = DefaultBrokenAfter
+	}
+	return &Checker{
+		log:    log.With("component", "linkcheck"),
+		store:  store,
+		client: safehttp.NewClient(safehttp.Options{Timeout: opts.Timeout, AllowPrivate: opts.AllowPrivate}),
+		opts:   opts,
+	}
+}
+
+// Run checks the links right away and then once per interval until ctx is
+// done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.CheckAll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			c.log.Error("failed to check links", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every link that has not been checked during the last half
+// interval, so that restarts do not check all links again.
+func (c *Checker) CheckAll(ctx context.Context) error {
+	links, err := c.store.ListAllURLs()
+	if err != nil {
+		return err
+	}
+	due := make([]models.UrlShortener, 0, len(links))
+	for _, link := range links {
+		if link.Health == nil || time.Since(link.Health.CheckedAt) >= c.opts.Interval/2 {
+			due = append(due, link)
+		}
+	}
+	c.log.Info("checking links", "due", len(due), "total", len(links))
+
+	hosts := newHostLimiter(c.opts.HostDelay)
+	jobs := make(chan models.UrlShortener)
+	var wg sync.WaitGroup
+	for range c.opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				c.process(ctx, hosts, link)
+			}
+		}()
+	}
+send:
+	for _, link := range interleaveHosts(due) {
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (c *Checker) process(ctx context.Context, hosts *hostLimiter, link models.UrlShortener) {
+	health := c.check(ctx, hosts, link.Url)
+	if ctx.Err() != nil {
+		// the result says nothing about the destination
+		return
+	}
+	failures, err := c.store.SaveHealth(link.Id, health)
+	if err != nil {
+		c.log.Error("failed to save link health", "url_id", link.Id, "err", err)
+		return
+	}
+	if failures == c.opts.BrokenAfter {
+		c.log.Warn("link is broken", "url_id", link.Id, "alias", link.Alias,
+			"status", health.StatusCode, "err", health.Error)
+	}
+}
+
+// check requests target with HEAD and, if that fails with an error status,
+// with GET.
+func (c *Checker) check(ctx context.Context, hosts *hostLimiter, target string) models.Health {
+	health := models.Health{CheckedAt: time.Now()}
+	u, err := url.Parse(target)
+	if err == nil {
+		err = safehttp.CheckScheme(u)
+	}
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		if err := hosts.wait(ctx, u.Hostname()); err != nil {
+			health.Error = err.Error()
+			return health
+		}
+		health.StatusCode, health.Latency, err = c.request(ctx, method, u)
+		health.CheckedAt = time.Now()
+		if err != nil {
+			health.Error = err.Error()
+			return health
+		}
+		if !health.Failed() {
+			return health
+		}
+	}
+	return health
+}
+
+func (c *Checker) request(ctx context.Context, method string, u *url.URL) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	start := time.Now()
+	res, err := c.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return 0, latency, err
+	}
+	res.Body.Close()
+	return res.StatusCode, latency, nil
+}
+
+// interleaveHosts orders links so that links of the same host are spread
+// out and the host delay does not keep all workers waiting for one host.
+func interleaveHosts(links []models.UrlShortener) []models.UrlShortener {
+	var hosts []string
+	byHost := map[string][]models.UrlShortener{}
+	for _, link := range links {
+		host := ""
+		if u, err := url.Parse(link.Url); err == nil {
+			host = u.Hostname()
+		}
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], link)
+	}
+	ordered := make([]models.UrlShortener, 0, len(links))
+	for len(ordered) < len(links) {
+		for _, host := range hosts {
+			if queue := byHost[host]; len(queue) > 0 {
+				ordered = append(ordered, queue[0])
+				byHost[host] = queue[1:]
+			}
+		}
+	}
+	return ordered
+}
+
+// hostLimiter spaces requests to the same host by a fixed delay.
+type hostLimiter struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{delay: delay, next: map[string]time.Time{}}
+}
+
+// wait reserves the next request slot of host and blocks until it starts.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.delay)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package linkcheck_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/lib/linkcheck"
+	"url-shortener/internal/models"
+)
+
+type store struct {
+	mu     sync.Mutex
+	links  []models.UrlShortener
+	health map[int64]models.Health
+}
+
+func (s *store) ListAllURLs() ([]models.UrlShortener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links := make([]models.UrlShortener, len(s.links))
+	for i, link := range s.links {
+		if h, ok := s.health[link.Id]; ok {
+			link.Health = &h
+		}
+		links[i] = link
+	}
+	return links, nil
+}
+
+func (s *store) SaveHealth(urlId int64, h models.Health) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.Failures = 0
+	if h.Failed() {
+		h.Failures = s.health[urlId].Failures + 1
+	}
+	s.health[urlId] = h
+	return h.Failures, nil
+}
+
+func newDestination(t *testing.T) (*httptest.Server, *[]time.Time) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/ok":
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestCheckAll(t *testing.T) {
+	srv, _ := newDestination(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	s := &store{
+		links: []models.UrlShortener{
+			{Id: 1, Alias: "ok", Url: srv.URL + "/ok"},
+			{Id: 2, Alias: "no-head", Url: srv.URL + "/no-head"},
+			{Id: 3, Alias: "missing", Url: srv.URL + "/missing"},
+			{Id: 4, Alias: "down", Url: closed.URL},
+			{Id: 5, Alias: "ftp", Url: "ftp://example.com/file"},
+		},
+		health: map[int64]models.Health{},
+	}
+	checker := linkcheck.New(slog.New(custom_mocks.NewMockLogger()), s, linkcheck.Options{
+		Interval:     time.Hour,
+		HostDelay:    time.Millisecond,
+		Timeout:      time.Second,
+		AllowPrivate: true,
+	})
+	require.NoError(t, checker.CheckAll(context.Background()))
+
+	require.Equal(t, http.StatusOK, s.health[1].StatusCode)
+	require.Equal(t, http.StatusOK, s.health[2].StatusCode)
+	require.Equal(t, http.StatusNotFound, s.health[3].StatusCode)
+	require.NotEmpty(t, s.health[4].Error)
+	require.NotEmpty(t, s.health[5].Error)
+	for id, failures := range map[int64]int{1: 0, 2: 0, 3: 1, 4: 1, 5: 1} {
+		require.Equal(t, failures, s.health[id].Failures, "link %d", id)
+		require.False(t, s.health[id].CheckedAt.IsZero())
+	}
+
+	// links checked during the last half interval are skipped
+	s.health[3] = models.Health{StatusCode: 404, Failures: 1, CheckedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, checker.CheckAll(context.Background()))
+	require.Equal(t, 2, s.health[3].Failures)
+	require.Equal(t, 1, s.health[4].Failures)
+}
+
+func TestCheckAllHostDelay(t *testing.T) {
+	srv, requests := newDestination(t)
+	s := &store{health: map[int64]models.Health{}}
+	for i := range 4 {
+		s.links = append(s.links, models.UrlShortener{Id: int64(i + 1), Url: srv.URL + "/ok"})
+	}
+	delay := 20 * time.Millisecond
+	checker := linkcheck.New(slog.New(custom_mocks.NewMockLogger()), s, linkcheck.Options{
+		Concurrency:  4,
+		HostDelay:    delay,
+		AllowPrivate: true,
+	})
+	require.NoError(t, checker.CheckAll(context.Background()))
+
+	require.Len(t, *requests, 4)
+	for i := 1; i < len(*requests); i++ {
+		// allow for the time between the reserved slot and the request
+		require.GreaterOrEqual(t, (*requests)[i].Sub((*requests)[i-1]), delay-5*time.Millisecond)
+	}
+}
+
+func TestCheckAllStopsWithContext(t *testing.T) {
+	srv, requests := newDestination(t)
+	s := &store{health: map[int64]models.Health{}}
+	for i := range 10 {
+		s.links = append(s.links, models.UrlShortener{Id: int64(i + 1), Url: srv.URL + "/ok"})
+	}
+	checker := linkcheck.New(slog.New(custom_mocks.NewMockLogger()), s, linkcheck.Options{
+		HostDelay:    time.Hour,
+		AllowPrivate: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, checker.CheckAll(ctx), context.DeadlineExceeded)
+	require.Len(t, *requests, 1)
+	require.Len(t, s.health, 1)
+}
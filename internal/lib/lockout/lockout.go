@@ -0,0 +1,110 @@
+// Package lockout slows down password guessing. Failed logins of an account
+// make the following attempts wait longer and longer until the account is
+// locked for a while; too many failures from one IP lock out the IP.
+package lockout
+
+import (
+	"time"
+	"url-shortener/internal/models"
+)
+
+const (
+	DefaultDelayAfter   = 3
+	DefaultDelay        = time.Second
+	DefaultMaxDelay     = time.Minute
+	DefaultLockAfter    = 10
+	DefaultLockDuration = 15 * time.Minute
+	DefaultIPLockAfter  = 50
+	DefaultWindow       = time.Hour
+)
+
+type Store interface {
+	LoginFailures(email string, since time.Time) (models.LoginFailures, error)
+	IPLoginFailures(ip string, since time.Time) (models.LoginFailures, error)
+}
+
+// Options configures a Guard. Zero values use the defaults.
+type Options struct {
+	// DelayAfter is the number of failures of an account after which every
+	// further attempt must wait Delay after the last failure. The delay
+	// doubles with every further failure up to MaxDelay.
+	DelayAfter int
+	Delay      time.Duration
+	MaxDelay   time.Duration
+	// LockAfter failures lock the account for LockDuration after the last
+	// one, IPLockAfter failures from one IP lock out the IP the same way.
+	LockAfter    int
+	LockDuration time.Duration
+	IPLockAfter  int
+	// Window is how long failures are remembered. It is at least
+	// LockDuration.
+	Window time.Duration
+}
+
+// Guard decides whether a login attempt may go ahead.
+type Guard struct {
+	store Store
+	opts  Options
+}
+
+func New(store Store, opts Options) *Guard {
+	if opts.DelayAfter <= 0 {
+		opts.DelayAfter = DefaultDelayAfter
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = DefaultDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.LockAfter <= 0 {
+		opts.LockAfter = DefaultLockAfter
+	}
+	if opts.LockDuration <= 0 {
+		opts.LockDuration = DefaultLockDuration
+	}
+	if opts.IPLockAfter <= 0 {
+		opts.IPLockAfter = DefaultIPLockAfter
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	opts.Window = max(opts.Window, opts.LockDuration, opts.MaxDelay)
+	return &Guard{store: store, opts: opts}
+}
+
+// Wait returns how long a login with email from ip has to wait, zero when it
+// may go ahead now. locked tells a lockout apart from a progressive delay.
+func (g *Guard) Wait(email, ip string, now time.Time) (wait time.Duration, locked bool, err error) {
+	since := now.Add(-g.opts.Window)
+	ipFailures, err := g.store.IPLoginFailures(ip, since)
+	if err != nil {
+		return 0, false, err
+	}
+	if ipFailures.Count >= g.opts.IPLockAfter {
+		if wait := ipFailures.Last.Add(g.opts.LockDuration).Sub(now); wait > 0 {
+			return wait, true, nil
+		}
+	}
+
+	failures, err := g.store.LoginFailures(email, since)
+	if err != nil {
+		return 0, false, err
+	}
+	if failures.Count >= g.opts.LockAfter {
+		if wait := failures.Last.Add(g.opts.LockDuration).Sub(now); wait > 0 {
+			return wait, true, nil
+		}
+		// the next failure locks the account again
+		return 0, false, nil
+	}
+	if failures.Count < g.opts.DelayAfter {
+		return 0, false, nil
+	}
+	delay := g.opts.Delay
+	for i := g.opts.DelayAfter; i < failures.Count && delay < g.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, g.opts.MaxDelay)
+	return max(failures.Last.Add(delay).Sub(now), 0), false, nil
+}
@@ -0,0 +1,79 @@
+package lockout_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/lockout"
+	"url-shortener/internal/models"
+)
+
+// store returns fixed failures and records the window it was asked for.
+type store struct {
+	account models.LoginFailures
+	ip      models.LoginFailures
+	since   time.Time
+}
+
+func (s *store) LoginFailures(email string, since time.Time) (models.LoginFailures, error) {
+	s.since = since
+	return s.account, nil
+}
+
+func (s *store) IPLoginFailures(ip string, since time.Time) (models.LoginFailures, error) {
+	return s.ip, nil
+}
+
+func TestWait(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	opts := lockout.Options{
+		DelayAfter:   3,
+		Delay:        time.Second,
+		MaxDelay:     10 * time.Second,
+		LockAfter:    10,
+		LockDuration: 15 * time.Minute,
+		IPLockAfter:  50,
+	}
+	failed := func(count int, ago time.Duration) models.LoginFailures {
+		return models.LoginFailures{Count: count, Last: now.Add(-ago)}
+	}
+
+	cases := []struct {
+		name    string
+		account models.LoginFailures
+		ip      models.LoginFailures
+		wait    time.Duration
+		locked  bool
+	}{
+		{name: "no failures"},
+		{name: "below delay threshold", account: failed(2, 0)},
+		{name: "first delay", account: failed(3, 0), wait: time.Second},
+		{name: "delay partly over", account: failed(3, 400*time.Millisecond), wait: 600 * time.Millisecond},
+		{name: "delay over", account: failed(3, 2*time.Second)},
+		{name: "delay doubles", account: failed(5, 0), wait: 4 * time.Second},
+		{name: "delay capped", account: failed(9, 0), wait: 10 * time.Second},
+		{name: "account locked", account: failed(10, time.Minute), wait: 14 * time.Minute, locked: true},
+		{name: "account lock over", account: failed(12, 16*time.Minute)},
+		{name: "ip locked", ip: failed(50, 5*time.Minute), wait: 10 * time.Minute, locked: true},
+		{name: "ip below threshold", ip: failed(49, 0)},
+		{name: "ip lock over", account: failed(3, 0), ip: failed(60, time.Hour), wait: time.Second},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			guard := lockout.New(&store{account: tc.account, ip: tc.ip}, opts)
+			wait, locked, err := guard.Wait("user@example.com", "192.0.2.1", now)
+			require.NoError(t, err)
+			require.Equal(t, tc.wait, wait)
+			require.Equal(t, tc.locked, locked)
+		})
+	}
+}
+
+func TestWindowCoversLock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := &store{}
+	guard := lockout.New(s, lockout.Options{LockDuration: 2 * time.Hour, Window: time.Minute})
+	_, _, err := guard.Wait("user@example.com", "192.0.2.1", now)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-2*time.Hour), s.since)
+}
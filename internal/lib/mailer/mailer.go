@@ -0,0 +1,159 @@
+// Package mailer sends the emails of the account flows.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPOptions configures NewSMTP.
+type SMTPOptions struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth when set, which
+	// net/smtp only allows over TLS or to localhost.
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends messages to a relay, upgrading the connection with STARTTLS
+// when the server supports it.
+type SMTP struct {
+	opts SMTPOptions
+}
+
+func NewSMTP(opts SMTPOptions) *SMTP {
+	return &SMTP{opts: opts}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+	}
+	// smtp.SendMail has no context, the deadline is only checked up front
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := format(s.opts.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(addr, auth, s.opts.From, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("send mail to %s: %w", addr, err)
+	}
+	return nil
+}
+
+// File writes every message as an .eml file into a directory, for local
+// testing.
+type File struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+func NewFile(dir, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(f.from, msg, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405"), f.seq.Add(1))
+	return os.WriteFile(filepath.Join(f.dir, name), data, 0o600)
+}
+
+// Log writes messages to the log instead of sending them, for local testing.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (l *Log) Send(_ context.Context, msg Message) error {
+	l.log.Info("email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// Async sends messages in the background so that slow relays do not delay
+// responses and the response time does not reveal whether a mail was sent.
+// Failures are logged.
+type Async struct {
+	log     *slog.Logger
+	next    Mailer
+	timeout time.Duration
+}
+
+// DefaultTimeout bounds the sending of a message by Async.
+const DefaultTimeout = 30 * time.Second
+
+func NewAsync(log *slog.Logger, next Mailer, timeout time.Duration) *Async {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Async{log: log, next: next, timeout: timeout}
+}
+
+func (a *Async) Send(_ context.Context, msg Message) error {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+		defer cancel()
+		if err := a.next.Send(ctx, msg); err != nil {
+			a.log.Error("failed to send email", "subject", msg.Subject, "err", err)
+		}
+	}()
+	return nil
+}
+
+// ErrHeader is returned for addresses that would inject header lines.
+var ErrHeader = errors.New("line break in address")
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(from+msg.To, "\r\n") {
+		return nil, ErrHeader
+	}
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
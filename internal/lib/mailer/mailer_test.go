@@ -0,0 +1,111 @@
+package mailer_test
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"url-shortener/internal/lib/mailer"
+)
+
+// smtpServer accepts one message and sends its envelope and data to the
+// returned channel.
+func smtpServer(t *testing.T) (string, int, <-chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					data = strings.TrimRight(data, "\r\n")
+					if data == "." {
+						break
+					}
+					lines = append(lines, data)
+				}
+				reply("250 OK")
+			case "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, p, received
+}
+
+func TestSMTP(t *testing.T) {
+	host, port, received := smtpServer(t)
+	m := mailer.NewSMTP(mailer.SMTPOptions{Host: host, Port: port, From: "no-reply@example.com"})
+
+	err := m.Send(context.Background(), mailer.Message{To: "user@example.com", Subject: "Verify your email", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+
+	lines := <-received
+	require.Equal(t, "MAIL FROM:<no-reply@example.com>", strings.SplitN(lines[0], " BODY", 2)[0])
+	require.Equal(t, "RCPT TO:<user@example.com>", lines[1])
+	require.Contains(t, lines, "To: user@example.com")
+	require.Contains(t, lines, "Subject: Verify your email")
+	require.Equal(t, []string{"line 1", "line 2"}, lines[len(lines)-2:])
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := mailer.NewFile(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), mailer.Message{To: "user@example.com", Subject: "Größe", Body: "hi"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: user@example.com\r\n")
+	require.Contains(t, string(data), "Subject: =?utf-8?q?Gr=C3=B6=C3=9Fe?=\r\n")
+	require.True(t, strings.HasSuffix(string(data), "\r\n\r\nhi"))
+
+	err = m.Send(context.Background(), mailer.Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "x"})
+	require.ErrorIs(t, err, mailer.ErrHeader)
+}
@@ -0,0 +1,118 @@
+// Package metadata fetches the title, description, OpenGraph image and
+// favicon of link destinations.
+package metadata
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/safehttp"
+	"url-shortener/internal/models"
+)
+
+const (
+	DefaultMaxBytes = 1 << 20
+
+	userAgent = "url-shortener-metadata/1.0 (+link preview)"
+)
+
+// Options configures a Fetcher. Zero values use the defaults.
+type Options struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	// AllowPrivate permits loopback, private and link-local destinations.
+	// It disables the SSRF protection and is meant for tests.
+	AllowPrivate bool
+}
+
+// Fetcher downloads destination pages through a safehttp client, so only
+// public addresses are reached unless Options.AllowPrivate is set.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	client := safehttp.NewClient(safehttp.Options{
+		Timeout:      opts.Timeout,
+		MaxRedirects: opts.MaxRedirects,
+		AllowPrivate: opts.AllowPrivate,
+	})
+	return &Fetcher{client: client, maxBytes: opts.MaxBytes}
+}
+
+// Fetch downloads target and extracts its metadata. The result always
+// carries the fetch time. Failures are returned as errors and recorded in
+// Metadata.Error, HTTP error statuses are not failures.
+func (f *Fetcher) Fetch(ctx context.Context, target string) (models.Metadata, error) {
+	meta := models.Metadata{FetchedAt: time.Now()}
+	err := f.fetch(ctx, target, &meta)
+	if err != nil {
+		meta.Error = err.Error()
+	}
+	return meta, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, target string, meta *models.Metadata) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if err := safehttp.CheckScheme(u); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	meta.FinalURL = res.Request.URL.String()
+	meta.StatusCode = res.StatusCode
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil
+	}
+	body := io.LimitReader(res.Body, f.maxBytes)
+	page := parseHead(body)
+	meta.Title = firstNonEmpty(page.ogTitle, page.title)
+	meta.Description = firstNonEmpty(page.ogDescription, page.description)
+	meta.ImageURL = resolve(res.Request.URL, page.ogImage)
+	meta.FaviconURL = resolve(res.Request.URL, firstNonEmpty(page.icon, "/favicon.ico"))
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
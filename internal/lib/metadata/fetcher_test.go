@@ -0,0 +1,200 @@
+package metadata_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/lib/metadata"
+	"url-shortener/internal/lib/safehttp"
+	"url-shortener/internal/models"
+)
+
+const page = `<!DOCTYPE html>
+<html><head>
+<title>
+  Example   Domain
+</title>
+<meta name="description" content="Plain description">
+<meta property="og:description" content="Open Graph description">
+<meta property="og:image" content="/img/cover.png">
+<link rel="shortcut icon" href="https://cdn.example.com/icon.png">
+</head>
+<body><meta property="og:title" content="ignored after head"></body></html>`
+
+func newDestination(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1000)+"<title>Too far</title></head></html>")
+	})
+	mux.HandleFunc("/file.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		fmt.Fprint(w, "<title>not html</title>")
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetch(t *testing.T) {
+	srv := newDestination(t)
+	fetcher := metadata.NewFetcher(metadata.Options{AllowPrivate: true, MaxBytes: 4096, Timeout: 100 * time.Millisecond})
+
+	cases := []struct {
+		name string
+		path string
+		want models.Metadata
+		err  bool
+	}{
+		{
+			name: "Page",
+			path: "/page",
+			want: models.Metadata{
+				Title:       "Example Domain",
+				Description: "Open Graph description",
+				ImageURL:    srv.URL + "/img/cover.png",
+				FaviconURL:  "https://cdn.example.com/icon.png",
+				FinalURL:    srv.URL + "/page",
+				StatusCode:  http.StatusOK,
+			},
+		},
+		{
+			name: "Redirect",
+			path: "/moved",
+			want: models.Metadata{
+				Title:       "Example Domain",
+				Description: "Open Graph description",
+				ImageURL:    srv.URL + "/img/cover.png",
+				FaviconURL:  "https://cdn.example.com/icon.png",
+				FinalURL:    srv.URL + "/page",
+				StatusCode:  http.StatusOK,
+			},
+		},
+		{
+			name: "Not found",
+			path: "/missing",
+			want: models.Metadata{FinalURL: srv.URL + "/missing", StatusCode: http.StatusNotFound},
+		},
+		{
+			name: "Size limit",
+			path: "/big",
+			want: models.Metadata{FinalURL: srv.URL + "/big", StatusCode: http.StatusOK, FaviconURL: srv.URL + "/favicon.ico"},
+		},
+		{
+			name: "Not HTML",
+			path: "/file.pdf",
+			want: models.Metadata{FinalURL: srv.URL + "/file.pdf", StatusCode: http.StatusOK},
+		},
+		{name: "Redirect loop", path: "/loop", err: true},
+		{name: "Timeout", path: "/slow", err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			meta, err := fetcher.Fetch(context.Background(), srv.URL+tc.path)
+			require.False(t, meta.FetchedAt.IsZero())
+			if tc.err {
+				require.Error(t, err)
+				require.NotEmpty(t, meta.Error)
+				return
+			}
+			require.NoError(t, err)
+			meta.FetchedAt = time.Time{}
+			require.Equal(t, tc.want, meta)
+		})
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := newDestination(t)
+	fetcher := metadata.NewFetcher(metadata.Options{})
+
+	_, err := fetcher.Fetch(context.Background(), srv.URL+"/page")
+	require.ErrorIs(t, err, safehttp.ErrForbiddenAddress)
+
+	_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+	require.ErrorIs(t, err, safehttp.ErrScheme)
+}
+
+func TestFetchChecksRedirectScheme(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	fetcher := metadata.NewFetcher(metadata.Options{AllowPrivate: true})
+	_, err := fetcher.Fetch(context.Background(), srv.URL)
+	require.ErrorIs(t, err, safehttp.ErrScheme)
+}
+
+type saver struct {
+	mu    sync.Mutex
+	saved map[int64]models.Metadata
+	done  chan struct{}
+}
+
+func (s *saver) SaveMetadata(urlId int64, m models.Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[urlId] = m
+	s.done <- struct{}{}
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	srv := newDestination(t)
+	s := &saver{saved: map[int64]models.Metadata{}, done: make(chan struct{}, 2)}
+	fetcher := metadata.NewFetcher(metadata.Options{AllowPrivate: true})
+	q := metadata.NewQueue(slog.New(custom_mocks.NewMockLogger()), fetcher, s, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx, 2)
+		close(stopped)
+	}()
+
+	q.Enqueue(1, srv.URL+"/page")
+	q.Enqueue(2, "http://127.0.0.1:1/unreachable")
+	for range 2 {
+		select {
+		case <-s.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("metadata was not saved")
+		}
+	}
+	cancel()
+	<-stopped
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Equal(t, "Example Domain", s.saved[1].Title)
+	require.NotEmpty(t, s.saved[2].Error)
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"strings"
+)
+
+// maxTextLen bounds the stored title and description.
+const maxTextLen = 512
+
+type head struct {
+	title, description     string
+	ogTitle, ogDescription string
+	ogImage                string
+	icon                   string
+}
+
+// parseHead reads the metadata from the head of an HTML document and stops
+// at the body.
+func parseHead(r io.Reader) head {
+	var h head
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return h
+		case html.TextToken:
+			if inTitle && h.title == "" {
+				h.title = truncate(strings.Join(strings.Fields(string(z.Text())), " "))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				return h
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			switch tag {
+			case atom.Body:
+				return h
+			case atom.Title:
+				inTitle = true
+			case atom.Meta, atom.Link:
+				if hasAttr {
+					h.addTag(tag, attributes(z))
+				}
+			}
+		}
+	}
+}
+
+func (h *head) addTag(tag atom.Atom, attrs map[string]string) {
+	if tag == atom.Link {
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			if rel == "icon" && h.icon == "" {
+				h.icon = attrs["href"]
+			}
+		}
+		return
+	}
+	content := truncate(attrs["content"])
+	if strings.EqualFold(attrs["name"], "description") {
+		h.description = content
+	}
+	switch strings.ToLower(attrs["property"]) {
+	case "og:title":
+		h.ogTitle = content
+	case "og:description":
+		h.ogDescription = content
+	case "og:image", "og:image:url":
+		if h.ogImage == "" {
+			h.ogImage = content
+		}
+	}
+}
+
+func attributes(z *html.Tokenizer) map[string]string {
+	attrs := map[string]string{}
+	for {
+		key, value, more := z.TagAttr()
+		attrs[string(key)] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxTextLen {
+		return s
+	}
+	// cut at a rune boundary
+	for i := maxTextLen; i > 0; i-- {
+		if s[i]&0xC0 != 0x80 {
+			return s[:i]
+		}
+	}
+	return ""
+}
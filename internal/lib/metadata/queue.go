@@ -0,0 +1,78 @@
+package metadata
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"url-shortener/internal/models"
+)
+
+type Saver interface {
+	SaveMetadata(urlId int64, m models.Metadata) error
+}
+
+type job struct {
+	urlId  int64
+	target string
+}
+
+// Queue fetches metadata in the background so that creating links does not
+// wait for destinations.
+type Queue struct {
+	log     *slog.Logger
+	fetcher *Fetcher
+	saver   Saver
+	jobs    chan job
+}
+
+func NewQueue(log *slog.Logger, fetcher *Fetcher, saver Saver, size int) *Queue {
+	return &Queue{
+		log:     log.With("component", "metadata"),
+		fetcher: fetcher,
+		saver:   saver,
+		jobs:    make(chan job, size),
+	}
+}
+
+// Enqueue schedules a fetch of target for the link with urlId. It never
+// blocks: when the queue is full the fetch is dropped.
+func (q *Queue) Enqueue(urlId int64, target string) {
+	select {
+	case q.jobs <- job{urlId: urlId, target: target}:
+	default:
+		q.log.Warn("metadata queue is full, dropping fetch", "url_id", urlId)
+	}
+}
+
+// Run processes the queue with the given number of workers until ctx is done.
+func (q *Queue) Run(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-q.jobs:
+					q.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) process(ctx context.Context, j job) {
+	meta, err := q.fetcher.Fetch(ctx, j.target)
+	if err != nil {
+		q.log.Info("failed to fetch metadata", "url_id", j.urlId, "err", err)
+	}
+	if err := q.saver.SaveMetadata(j.urlId, meta); err != nil {
+		q.log.Error("failed to save metadata", "url_id", j.urlId, "err", err)
+	}
+}
@@ -0,0 +1,278 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	// Leeway is the clock skew tolerated when checking ID tokens.
+	Leeway = time.Minute
+	// minKeyRefresh limits how often an unknown key id refetches the keys.
+	minKeyRefresh = time.Minute
+	maxResponse   = 1 << 20
+)
+
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+var (
+	// ErrExchange is returned when the provider refuses an authorization code.
+	ErrExchange = errors.New("code exchange failed")
+	// ErrInvalidIDToken is returned for ID tokens that fail verification.
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Options configure a Provider.
+type Options struct {
+	// Issuer is the URL of the provider, its configuration is discovered at
+	// Issuer/.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered with the provider.
+	RedirectURL string
+	// Scopes default to DefaultScopes.
+	Scopes []string
+	// Timeout bounds every request to the provider, DefaultTimeout when zero.
+	Timeout time.Duration
+}
+
+// Claims are the claims of a verified ID token.
+type Claims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+	// AuthorizedParty is the client the token was issued to when it has
+	// several audiences.
+	AuthorizedParty string `json:"azp,omitempty"`
+}
+
+// configuration is the part of the discovery document used by Provider.
+type configuration struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider logs users in with the authorization code flow with PKCE of an
+// OpenID Connect provider. The configuration of the provider is discovered
+// on first use, so a provider that is down does not keep the service from
+// starting.
+type Provider struct {
+	opts   Options
+	client *http.Client
+
+	mu          sync.Mutex
+	config      *configuration
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+func New(opts Options) *Provider {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = DefaultScopes
+	}
+	return &Provider{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+// AuthCodeURL returns the URL of the provider the user logs in at. state,
+// nonce and verifier are random values kept by the caller until the
+// callback, verifier is sent to the provider as its S256 challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(config.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse authorization endpoint: %w", err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.opts.ClientID)
+	q.Set("redirect_uri", p.opts.RedirectURL)
+	q.Set("scope", strings.Join(p.opts.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code for an ID token and returns its
+// verified claims. nonce and verifier are the values of AuthCodeURL.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.opts.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic, the default client authentication of the spec
+	req.SetBasicAuth(url.QueryEscape(p.opts.ClientID), url.QueryEscape(p.opts.ClientSecret))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponse)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("%w: status %d: %v", ErrExchange, res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("%w: status %d: %s %s", ErrExchange, res.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id token in response", ErrExchange)
+	}
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token.
+func (p *Provider) Verify(ctx context.Context, idToken, nonce string) (*Claims, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims Claims
+	_, err = jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, config, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithAudience(p.opts.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(Leeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.opts.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+// discover fetches the configuration of the provider once. Failures are not
+// cached.
+func (p *Provider) discover(ctx context.Context) (*configuration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config != nil {
+		return p.config, nil
+	}
+
+	var config configuration
+	if err := p.get(ctx, strings.TrimSuffix(p.opts.Issuer, "/")+"/.well-known/openid-configuration", &config); err != nil {
+		return nil, fmt.Errorf("discover provider: %w", err)
+	}
+	if config.Issuer != p.opts.Issuer {
+		return nil, fmt.Errorf("discover provider: issuer %q does not match %q", config.Issuer, p.opts.Issuer)
+	}
+	if config.AuthorizationEndpoint == "" || config.TokenEndpoint == "" || config.JWKSURI == "" {
+		return nil, errors.New("discover provider: incomplete configuration")
+	}
+	p.config = &config
+	return p.config, nil
+}
+
+// key returns the signing key with kid, refetching the keys of the provider
+// when kid is unknown. Tokens without kid are accepted if the provider has a
+// single key.
+func (p *Provider) key(ctx context.Context, config *configuration, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < minKeyRefresh {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	var set jwks
+	if err := p.get(ctx, config.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetch keys: %w", err)
+	}
+	p.keys = set.keys()
+	p.keysFetched = time.Now()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (p *Provider) lookup(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) get(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, res.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponse)).Decode(v)
+}
@@ -0,0 +1,135 @@
+package oidc_test
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/oidc"
+	"url-shortener/internal/lib/oidc/oidctest"
+)
+
+func newProvider(server *oidctest.Server, secret string) *oidc.Provider {
+	return oidc.New(oidc.Options{
+		Issuer:       server.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: secret,
+		RedirectURL:  "http://localhost/auth/oidc/callback",
+	})
+}
+
+// authorize follows the redirect of the provider back to the callback and
+// returns the query of the callback.
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	callback, err := url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback.Query()
+}
+
+func TestLogin(t *testing.T) {
+	server := oidctest.NewServer(t)
+	server.SetUser(oidctest.User{Subject: "42", Email: "user@example.com", EmailVerified: true})
+	provider := newProvider(server, oidctest.ClientSecret)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "openid email profile", u.Query().Get("scope"))
+
+	callback := authorize(t, authURL)
+	require.Equal(t, "state", callback.Get("state"))
+
+	_, err = provider.Exchange(ctx, callback.Get("code"), "other-verifier-other-verifier-other-verifier", "nonce")
+	require.ErrorIs(t, err, oidc.ErrExchange, "PKCE binds the code to the verifier")
+
+	callback = authorize(t, authURL)
+	_, err = provider.Exchange(ctx, callback.Get("code"), "verifier-verifier-verifier-verifier-verifier", "other")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken, "nonce mismatch")
+
+	callback = authorize(t, authURL)
+	claims, err := provider.Exchange(ctx, callback.Get("code"), "verifier-verifier-verifier-verifier-verifier", "nonce")
+	require.NoError(t, err)
+	require.Equal(t, "42", claims.Subject)
+	require.Equal(t, server.URL, claims.Issuer)
+	require.Equal(t, "user@example.com", claims.Email)
+	require.True(t, claims.EmailVerified)
+
+	_, err = provider.Exchange(ctx, callback.Get("code"), "verifier-verifier-verifier-verifier-verifier", "nonce")
+	require.ErrorIs(t, err, oidc.ErrExchange, "codes are single-use")
+
+	callback = authorize(t, authURL)
+	_, err = newProvider(server, "wrong").Exchange(ctx, callback.Get("code"), "verifier-verifier-verifier-verifier-verifier", "nonce")
+	require.ErrorIs(t, err, oidc.ErrExchange)
+}
+
+func TestVerify(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := newProvider(server, oidctest.ClientSecret)
+	user := oidctest.User{Subject: "42", Email: "user@example.com"}
+
+	cases := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		ok     bool
+	}{
+		{name: "valid", modify: func(jwt.MapClaims) {}, ok: true},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "no expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "other issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "other audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "several audiences", modify: func(c jwt.MapClaims) { c["aud"] = []string{oidctest.ClientID, "other"} }},
+		{name: "several audiences with azp", modify: func(c jwt.MapClaims) {
+			c["aud"] = []string{oidctest.ClientID, "other"}
+			c["azp"] = oidctest.ClientID
+		}, ok: true},
+		{name: "no subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := server.Claims(user, "nonce")
+			tc.modify(claims)
+			_, err := provider.Verify(context.Background(), server.Sign(claims), "nonce")
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+			}
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, server.Claims(user, "nonce")).
+			SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = provider.Verify(context.Background(), token, "nonce")
+		require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, server.Claims(user, "nonce")).
+			SignedString([]byte(oidctest.ClientSecret))
+		require.NoError(t, err)
+		_, err = provider.Verify(context.Background(), token, "nonce")
+		require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := oidc.New(oidc.Options{Issuer: server.URL + "/", ClientID: oidctest.ClientID})
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	require.ErrorContains(t, err, "does not match")
+}
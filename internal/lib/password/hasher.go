@@ -0,0 +1,167 @@
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+const (
+	DefaultBcryptCost    = 12
+	DefaultArgon2Time    = 3
+	DefaultArgon2Memory  = 64 * 1024
+	DefaultArgon2Threads = 2
+
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+	argon2Prefix  = "$argon2id$"
+)
+
+var (
+	ErrMismatch    = errors.New("password does not match")
+	ErrUnknownHash = errors.New("unknown password hash format")
+)
+
+// Options configures a Hasher. Zero values use the defaults.
+type Options struct {
+	// Algorithm is bcrypt or argon2id, new hashes use it.
+	Algorithm  string
+	BcryptCost int
+	// Argon2Time is the number of passes over Argon2Memory KiB of memory
+	// with Argon2Threads lanes.
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes of both algorithms, so that the algorithm can be changed without
+// invalidating the stored passwords.
+type Hasher struct {
+	opts Options
+}
+
+func NewHasher(opts Options) (*Hasher, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = Bcrypt
+	}
+	if opts.Algorithm != Bcrypt && opts.Algorithm != Argon2id {
+		return nil, fmt.Errorf("unknown password hash algorithm %q", opts.Algorithm)
+	}
+	if opts.BcryptCost == 0 {
+		opts.BcryptCost = DefaultBcryptCost
+	}
+	if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d out of range", opts.BcryptCost)
+	}
+	if opts.Argon2Time == 0 {
+		opts.Argon2Time = DefaultArgon2Time
+	}
+	if opts.Argon2Memory == 0 {
+		opts.Argon2Memory = DefaultArgon2Memory
+	}
+	if opts.Argon2Threads == 0 {
+		opts.Argon2Threads = DefaultArgon2Threads
+	}
+	return &Hasher{opts: opts}, nil
+}
+
+// Hash returns the hash of password in the bcrypt or PHC string format.
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	if h.opts.Algorithm == Bcrypt {
+		return bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	p := argon2Params{
+		time:    h.opts.Argon2Time,
+		memory:  h.opts.Argon2Memory,
+		threads: h.opts.Argon2Threads,
+	}
+	return p.encode(salt, p.key(password, salt, argon2KeyLen)), nil
+}
+
+// Verify returns ErrMismatch unless hash is a hash of password.
+func (h *Hasher) Verify(hash []byte, password string) error {
+	if !bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		return err
+	}
+	p, salt, key, err := decodeArgon2(string(hash))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, p.key(password, salt, uint32(len(key)))) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than new hashes.
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	if !bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		cost, err := bcrypt.Cost(hash)
+		return err != nil || h.opts.Algorithm != Bcrypt || cost != h.opts.BcryptCost
+	}
+	p, _, key, err := decodeArgon2(string(hash))
+	return err != nil || h.opts.Algorithm != Argon2id || len(key) != argon2KeyLen ||
+		p != argon2Params{time: h.opts.Argon2Time, memory: h.opts.Argon2Memory, threads: h.opts.Argon2Threads}
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func (p argon2Params) key(password string, salt []byte, keyLen uint32) []byte {
+	return argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, keyLen)
+}
+
+func (p argon2Params) encode(salt, key []byte) []byte {
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)))
+}
+
+// decodeArgon2 parses $argon2id$v=19$m=65536,t=3,p=2$salt$key.
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnknownHash
+	}
+	return p, salt, key, nil
+}
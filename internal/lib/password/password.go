@@ -0,0 +1,37 @@
+// Package password checks new passwords against a policy and hashes them
+// with bcrypt or argon2id.
+package password
+
+import (
+	"fmt"
+	"url-shortener/internal/config"
+)
+
+// New builds the hasher and the policy configured in cfg, loading the list
+// of common passwords if one is set.
+func New(cfg config.Password) (*Hasher, *Policy, error) {
+	hasher, err := NewHasher(Options{
+		Algorithm:     cfg.Hash,
+		BcryptCost:    cfg.BcryptCost,
+		Argon2Time:    cfg.Argon2.Time,
+		Argon2Memory:  cfg.Argon2.Memory,
+		Argon2Threads: cfg.Argon2.Threads,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	policy := &Policy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
+	if cfg.CommonList != "" {
+		if policy.Common, err = LoadCommon(cfg.CommonList); err != nil {
+			return nil, nil, fmt.Errorf("load common passwords: %w", err)
+		}
+	}
+	return hasher, policy, nil
+}
@@ -0,0 +1,163 @@
+package password_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"url-shortener/internal/lib/password"
+)
+
+// cheap keeps the argon2id tests fast.
+var cheap = password.Options{Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1, BcryptCost: bcrypt.MinCost}
+
+func newHasher(t *testing.T, opts password.Options) *password.Hasher {
+	hasher, err := password.NewHasher(opts)
+	require.NoError(t, err)
+	return hasher
+}
+
+func TestHasher(t *testing.T) {
+	for _, algorithm := range []string{password.Bcrypt, password.Argon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			opts := cheap
+			opts.Algorithm = algorithm
+			hasher := newHasher(t, opts)
+
+			hash, err := hasher.Hash("correct horse")
+			require.NoError(t, err)
+			require.NoError(t, hasher.Verify(hash, "correct horse"))
+			require.ErrorIs(t, hasher.Verify(hash, "battery staple"), password.ErrMismatch)
+			require.False(t, hasher.NeedsRehash(hash))
+
+			again, err := hasher.Hash("correct horse")
+			require.NoError(t, err)
+			require.NotEqual(t, hash, again)
+		})
+	}
+}
+
+func TestHasherArgon2Format(t *testing.T) {
+	opts := cheap
+	opts.Algorithm = password.Argon2id
+	hash, err := newHasher(t, opts).Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=64,t=1,p=1$"), string(hash))
+
+	require.True(t, newHasher(t, opts).NeedsRehash([]byte("$argon2id$v=19$broken")))
+	require.ErrorIs(t, newHasher(t, opts).Verify([]byte("$argon2id$v=19$broken"), "x"), password.ErrUnknownHash)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptOpts := cheap
+	bcryptOpts.Algorithm = password.Bcrypt
+	argonOpts := cheap
+	argonOpts.Algorithm = password.Argon2id
+
+	bcryptHash, err := newHasher(t, bcryptOpts).Hash("correct horse")
+	require.NoError(t, err)
+	argonHash, err := newHasher(t, argonOpts).Hash("correct horse")
+	require.NoError(t, err)
+
+	stronger := bcryptOpts
+	stronger.BcryptCost++
+	moreMemory := argonOpts
+	moreMemory.Argon2Memory *= 2
+
+	cases := []struct {
+		name string
+		opts password.Options
+		hash []byte
+		want bool
+	}{
+		{name: "same bcrypt cost", opts: bcryptOpts, hash: bcryptHash, want: false},
+		{name: "higher bcrypt cost", opts: stronger, hash: bcryptHash, want: true},
+		{name: "bcrypt to argon2id", opts: argonOpts, hash: bcryptHash, want: true},
+		{name: "argon2id to bcrypt", opts: bcryptOpts, hash: argonHash, want: true},
+		{name: "same argon2id params", opts: argonOpts, hash: argonHash, want: false},
+		{name: "more argon2id memory", opts: moreMemory, hash: argonHash, want: true},
+		{name: "garbage", opts: bcryptOpts, hash: []byte("plain"), want: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hasher := newHasher(t, tc.opts)
+			require.Equal(t, tc.want, hasher.NeedsRehash(tc.hash))
+			// hashes of the other algorithm still verify
+			if tc.name != "garbage" {
+				require.NoError(t, hasher.Verify(tc.hash, "correct horse"))
+			}
+		})
+	}
+}
+
+func TestNewHasherInvalid(t *testing.T) {
+	_, err := password.NewHasher(password.Options{Algorithm: "md5"})
+	require.Error(t, err)
+	_, err = password.NewHasher(password.Options{BcryptCost: bcrypt.MaxCost + 1})
+	require.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	policy := &password.Policy{
+		MinLength:     10,
+		MaxLength:     20,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		Common:        map[string]struct{}{"password1!a": {}},
+	}
+	cases := []struct {
+		password string
+		want     string
+	}{
+		{password: "Correct-horse-1"},
+		{password: "Éclair-été-42"},
+		{password: "Short-1", want: "at least 10 characters"},
+		{password: "Much-too-long-password-1", want: "at most 20 bytes"},
+		{password: "correct-horse-1", want: "uppercase"},
+		{password: "CORRECT-HORSE-1", want: "lowercase"},
+		{password: "Correct-horse-x", want: "digit"},
+		{password: "Correcthorse1", want: "symbol"},
+		{password: "PASSWORD1!a", want: "too common"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.password, func(t *testing.T) {
+			err := policy.Check(tc.password)
+			if tc.want == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, password.ErrWeak)
+			require.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	require.ErrorIs(t, (&password.Policy{}).Check("123456789"), password.ErrWeak)
+	require.NoError(t, (&password.Policy{}).Check("1234567890"))
+	require.ErrorIs(t, (&password.Policy{}).Check(strings.Repeat("a", 73)), password.ErrWeak)
+
+	var policy *password.Policy
+	require.NoError(t, policy.Check(""))
+}
+
+func TestLoadCommon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# common\n\nPassword123\n  qwertyuiop  \n"), 0o600))
+
+	common, err := password.LoadCommon(path)
+	require.NoError(t, err)
+	require.Len(t, common, 2)
+
+	policy := &password.Policy{Common: common}
+	require.ErrorIs(t, policy.Check("password123"), password.ErrWeak)
+	require.ErrorIs(t, policy.Check("QwertyUiop"), password.ErrWeak)
+	require.NoError(t, policy.Check("qwertyuiop1"))
+
+	_, err = password.LoadCommon(filepath.Join(t.TempDir(), "missing.txt"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
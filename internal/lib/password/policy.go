@@ -0,0 +1,111 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength = 10
+	// DefaultMaxLength is the longest password bcrypt accepts, in bytes.
+	DefaultMaxLength = 72
+)
+
+// ErrWeak is wrapped by the errors of Policy.Check, whose messages say what
+// is missing.
+var ErrWeak = errors.New("weak password")
+
+// Policy describes the passwords accepted for new accounts and password
+// changes. The zero value accepts any password of DefaultMinLength to
+// DefaultMaxLength.
+type Policy struct {
+	// MinLength is in characters, MaxLength in bytes.
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Common holds lowercased passwords that are rejected as too easy to
+	// guess, see LoadCommon.
+	Common map[string]struct{}
+}
+
+// Check returns an error wrapping ErrWeak when password breaks the policy.
+// A nil policy accepts any password.
+func (p *Policy) Check(password string) error {
+	if p == nil {
+		return nil
+	}
+	minLength, maxLength := p.MinLength, p.MaxLength
+	if minLength <= 0 {
+		minLength = DefaultMinLength
+	}
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeak, minLength)
+	}
+	if len(password) > maxLength {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrWeak, maxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("%w: must contain an uppercase letter", ErrWeak)
+	case p.RequireLower && !lower:
+		return fmt.Errorf("%w: must contain a lowercase letter", ErrWeak)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("%w: must contain a digit", ErrWeak)
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("%w: must contain a symbol", ErrWeak)
+	}
+
+	if _, ok := p.Common[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: too common", ErrWeak)
+	}
+	return nil
+}
+
+// LoadCommon reads a list of common or breached passwords, one per line.
+// Empty lines and lines starting with # are skipped.
+func LoadCommon(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	common := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		common[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return common, nil
+}
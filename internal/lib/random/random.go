@@ -1,15 +1,30 @@
 package random
 
 import (
-	"math/rand"
+	"crypto/rand"
 )
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// maxByte is the largest multiple of len(letters) that fits into a byte, bytes
+// at or above it are rejected so that every letter is equally likely.
+const maxByte = 256 - 256%len(letters)
+
+// NewRandomString returns a cryptographically random base62 string.
 func NewRandomString(length int) string {
-	b := make([]rune, length)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+	b := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(b) < length {
+		_, _ = rand.Read(buf)
+		for _, c := range buf {
+			if int(c) >= maxByte {
+				continue
+			}
+			b = append(b, letters[int(c)%len(letters)])
+			if len(b) == length {
+				break
+			}
+		}
 	}
 	return string(b)
 }
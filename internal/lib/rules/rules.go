@@ -0,0 +1,158 @@
+// Package rules picks the destination of a link from its conditional redirect
+// rules based on who is visiting.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"url-shortener/internal/models"
+)
+
+const (
+	OSIOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSOther   = "other"
+
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+var (
+	ErrNoConditions = errors.New("rule has no conditions")
+	ErrBadWindow    = errors.New("rule ends before it starts")
+)
+
+// CountryLookup resolves client addresses to ISO 3166-1 alpha-2 codes.
+type CountryLookup interface {
+	Country(ip netip.Addr) string
+}
+
+// Visitor holds the request properties rules match on.
+type Visitor struct {
+	OS     string
+	Device string
+	// Language is the most preferred language of Accept-Language, lowercased.
+	Language string
+	IP       netip.Addr
+	Country  string
+	Time     time.Time
+}
+
+// NewVisitor describes the client of r. The country is only looked up when
+// geo is not nil.
+func NewVisitor(r *http.Request, geo CountryLookup, now time.Time) Visitor {
+	v := Visitor{Time: now}
+	v.OS, v.Device = ParseUserAgent(r.UserAgent())
+	v.Language = PreferredLanguage(r.Header.Get("Accept-Language"))
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		v.IP = ip.Unmap()
+		if geo != nil {
+			v.Country = geo.Country(v.IP)
+		}
+	}
+	return v
+}
+
+// Select returns the target of the first rule matching v.
+func Select(rules []models.Rule, v Visitor) (string, bool) {
+	for _, rule := range rules {
+		if Match(rule, v) {
+			return rule.Target, true
+		}
+	}
+	return "", false
+}
+
+// Match reports whether v satisfies every condition of rule.
+func Match(rule models.Rule, v Visitor) bool {
+	if len(rule.OS) > 0 && !slices.Contains(rule.OS, v.OS) {
+		return false
+	}
+	if len(rule.Devices) > 0 && !slices.Contains(rule.Devices, v.Device) {
+		return false
+	}
+	if len(rule.Languages) > 0 && !slices.ContainsFunc(rule.Languages, func(lang string) bool {
+		return matchLanguage(lang, v.Language)
+	}) {
+		return false
+	}
+	if len(rule.CIDRs) > 0 && !slices.ContainsFunc(rule.CIDRs, func(cidr string) bool {
+		prefix, err := netip.ParsePrefix(cidr)
+		return err == nil && v.IP.IsValid() && prefix.Contains(v.IP)
+	}) {
+		return false
+	}
+	if len(rule.Countries) > 0 && !slices.ContainsFunc(rule.Countries, func(country string) bool {
+		return v.Country != "" && strings.EqualFold(country, v.Country)
+	}) {
+		return false
+	}
+	if !rule.Start.IsZero() && v.Time.Before(rule.Start) {
+		return false
+	}
+	if !rule.End.IsZero() && !v.Time.Before(rule.End) {
+		return false
+	}
+	return true
+}
+
+// matchLanguage matches a rule language like "en" against the visitor
+// language "en-us", or "pt-BR" exactly against "pt-br".
+func matchLanguage(rule, visitor string) bool {
+	rule = strings.ToLower(rule)
+	return visitor == rule || strings.HasPrefix(visitor, rule+"-")
+}
+
+// Validate checks what cannot be expressed by struct tags: a rule needs at
+// least one condition, a sane time window and well-formed values.
+func Validate(rule models.Rule) error {
+	if len(rule.OS)+len(rule.Devices)+len(rule.Languages)+len(rule.CIDRs)+len(rule.Countries) == 0 &&
+		rule.Start.IsZero() && rule.End.IsZero() {
+		return ErrNoConditions
+	}
+	if !rule.Start.IsZero() && !rule.End.IsZero() && !rule.Start.Before(rule.End) {
+		return ErrBadWindow
+	}
+	for _, os := range rule.OS {
+		if !slices.Contains([]string{OSIOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSOther}, os) {
+			return fmt.Errorf("unknown os %q", os)
+		}
+	}
+	for _, device := range rule.Devices {
+		if !slices.Contains([]string{DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot}, device) {
+			return fmt.Errorf("unknown device %q", device)
+		}
+	}
+	for _, cidr := range rule.CIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid cidr %q", cidr)
+		}
+	}
+	for _, country := range rule.Countries {
+		if len(country) != 2 {
+			return fmt.Errorf("invalid country %q", country)
+		}
+	}
+	u, err := url.Parse(rule.Target)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("invalid target %q", rule.Target)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package rules_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/rules"
+	"url-shortener/internal/models"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaIPad    = "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	uaTablet  = "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	uaWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	uaMac     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15"
+	uaLinux   = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+	uaBot     = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		ua     string
+		os     string
+		device string
+	}{
+		{ua: uaIPhone, os: rules.OSIOS, device: rules.DeviceMobile},
+		{ua: uaIPad, os: rules.OSIOS, device: rules.DeviceTablet},
+		{ua: uaAndroid, os: rules.OSAndroid, device: rules.DeviceMobile},
+		{ua: uaTablet, os: rules.OSAndroid, device: rules.DeviceTablet},
+		{ua: uaWindows, os: rules.OSWindows, device: rules.DeviceDesktop},
+		{ua: uaMac, os: rules.OSMacOS, device: rules.DeviceDesktop},
+		{ua: uaLinux, os: rules.OSLinux, device: rules.DeviceDesktop},
+		{ua: uaBot, os: rules.OSOther, device: rules.DeviceBot},
+		{ua: "", os: rules.OSOther, device: rules.DeviceDesktop},
+	}
+
+	for _, tc := range cases {
+		os, device := rules.ParseUserAgent(tc.ua)
+		require.Equal(t, tc.os, os, tc.ua)
+		require.Equal(t, tc.device, device, tc.ua)
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                           "",
+		"de-DE,de;q=0.9,en;q=0.8":    "de-de",
+		"en;q=0.5, fr-CA":            "fr-ca",
+		"*;q=1, pt-BR;q=0.7":         "pt-br",
+		"en;q=bad, es;q=0.1":         "es",
+		"  EN-gb ; q=0.9 , ja;q=0.2": "en-gb",
+	}
+	for header, want := range cases {
+		require.Equal(t, want, rules.PreferredLanguage(header), header)
+	}
+}
+
+type countries map[string]string
+
+func (c countries) Country(ip netip.Addr) string {
+	return c[ip.String()]
+}
+
+func TestNewVisitor(t *testing.T) {
+	r := httptest.NewRequest("GET", "/alias", nil)
+	r.RemoteAddr = "192.0.2.10:4321"
+	r.Header.Set("User-Agent", uaIPhone)
+	r.Header.Set("Accept-Language", "de-AT, en;q=0.5")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	v := rules.NewVisitor(r, countries{"192.0.2.10": "AT"}, now)
+	require.Equal(t, rules.Visitor{
+		OS:       rules.OSIOS,
+		Device:   rules.DeviceMobile,
+		Language: "de-at",
+		IP:       netip.MustParseAddr("192.0.2.10"),
+		Country:  "AT",
+		Time:     now,
+	}, v)
+
+	r.RemoteAddr = "not an address"
+	require.False(t, rules.NewVisitor(r, nil, now).IP.IsValid())
+}
+
+func TestMatch(t *testing.T) {
+	noon := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	visitor := rules.Visitor{
+		OS:       rules.OSAndroid,
+		Device:   rules.DeviceMobile,
+		Language: "pt-br",
+		IP:       netip.MustParseAddr("198.51.100.7"),
+		Country:  "BR",
+		Time:     noon,
+	}
+
+	cases := []struct {
+		name  string
+		rule  models.Rule
+		match bool
+	}{
+		{name: "OS", rule: models.Rule{OS: []string{rules.OSIOS, rules.OSAndroid}}, match: true},
+		{name: "Other OS", rule: models.Rule{OS: []string{rules.OSIOS}}},
+		{name: "Device", rule: models.Rule{Devices: []string{rules.DeviceMobile}}, match: true},
+		{name: "Other device", rule: models.Rule{Devices: []string{rules.DeviceDesktop}}},
+		{name: "Language prefix", rule: models.Rule{Languages: []string{"pt"}}, match: true},
+		{name: "Language exact", rule: models.Rule{Languages: []string{"pt-BR"}}, match: true},
+		{name: "Other region", rule: models.Rule{Languages: []string{"pt-PT"}}},
+		{name: "Prefix is not a language", rule: models.Rule{Languages: []string{"p"}}},
+		{name: "CIDR", rule: models.Rule{CIDRs: []string{"10.0.0.0/8", "198.51.100.0/24"}}, match: true},
+		{name: "Other CIDR", rule: models.Rule{CIDRs: []string{"198.51.101.0/24"}}},
+		{name: "Country", rule: models.Rule{Countries: []string{"br"}}, match: true},
+		{name: "Other country", rule: models.Rule{Countries: []string{"PT"}}},
+		{name: "Inside window", rule: models.Rule{Start: noon.Add(-time.Hour), End: noon.Add(time.Hour)}, match: true},
+		{name: "Open start", rule: models.Rule{End: noon.Add(time.Hour)}, match: true},
+		{name: "Not started", rule: models.Rule{Start: noon.Add(time.Minute)}},
+		{name: "End is exclusive", rule: models.Rule{End: noon}},
+		{
+			name: "All conditions",
+			rule: models.Rule{
+				OS:        []string{rules.OSAndroid},
+				Countries: []string{"BR"},
+				Languages: []string{"pt"},
+			},
+			match: true,
+		},
+		{
+			name: "One condition fails",
+			rule: models.Rule{
+				OS:        []string{rules.OSAndroid},
+				Countries: []string{"US"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.match, rules.Match(tc.rule, visitor))
+		})
+	}
+}
+
+func TestMatchUnknownCountry(t *testing.T) {
+	rule := models.Rule{Countries: []string{"US"}}
+	require.False(t, rules.Match(rule, rules.Visitor{}))
+}
+
+func TestSelect(t *testing.T) {
+	list := []models.Rule{
+		{OS: []string{rules.OSIOS}, Target: "https://apps.apple.com/app/id1"},
+		{OS: []string{rules.OSAndroid}, Target: "https://play.google.com/store/apps/details?id=app"},
+		{Devices: []string{rules.DeviceMobile}, Target: "https://m.example.com"},
+	}
+
+	cases := []struct {
+		ua     string
+		target string
+		ok     bool
+	}{
+		{ua: uaIPhone, target: "https://apps.apple.com/app/id1", ok: true},
+		{ua: uaAndroid, target: "https://play.google.com/store/apps/details?id=app", ok: true},
+		{ua: "Opera/9.80 (J2ME/MIDP; Opera Mini) Mobile", target: "https://m.example.com", ok: true},
+		{ua: uaWindows},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("User-Agent", tc.ua)
+
+		target, ok := rules.Select(list, rules.NewVisitor(r, nil, time.Now()))
+		require.Equal(t, tc.ok, ok, tc.ua)
+		require.Equal(t, tc.target, target, tc.ua)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name string
+		rule models.Rule
+		err  bool
+	}{
+		{name: "Valid", rule: models.Rule{OS: []string{rules.OSIOS}, Target: "https://example.com"}},
+		{name: "No conditions", rule: models.Rule{Target: "https://example.com"}, err: true},
+		{name: "Window only", rule: models.Rule{Start: now, Target: "https://example.com"}},
+		{name: "Reversed window", rule: models.Rule{Start: now, End: now, Target: "https://example.com"}, err: true},
+		{name: "Unknown OS", rule: models.Rule{OS: []string{"beos"}, Target: "https://example.com"}, err: true},
+		{name: "Unknown device", rule: models.Rule{Devices: []string{"watch"}, Target: "https://example.com"}, err: true},
+		{name: "Bad CIDR", rule: models.Rule{CIDRs: []string{"10.0.0.0/33"}, Target: "https://example.com"}, err: true},
+		{name: "Bad country", rule: models.Rule{Countries: []string{"USA"}, Target: "https://example.com"}, err: true},
+		{name: "Relative target", rule: models.Rule{OS: []string{rules.OSIOS}, Target: "/app"}, err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := rules.Validate(tc.rule)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+)
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "facebookexternalhit"}
+
+// ParseUserAgent classifies a User-Agent header into one of the OS and device
+// constants. It only looks for the well-known platform tokens, which is all
+// redirect rules need.
+func ParseUserAgent(ua string) (os, device string) {
+	lower := strings.ToLower(ua)
+	for _, marker := range botMarkers {
+		if strings.Contains(lower, marker) {
+			return OSOther, DeviceBot
+		}
+	}
+
+	switch {
+	case strings.Contains(lower, "ipad"):
+		return OSIOS, DeviceTablet
+	case strings.Contains(lower, "iphone"), strings.Contains(lower, "ipod"):
+		return OSIOS, DeviceMobile
+	case strings.Contains(lower, "android"):
+		// Android tablets leave out the Mobile token.
+		if strings.Contains(lower, "mobile") {
+			return OSAndroid, DeviceMobile
+		}
+		return OSAndroid, DeviceTablet
+	case strings.Contains(lower, "windows phone"):
+		return OSWindows, DeviceMobile
+	case strings.Contains(lower, "windows"):
+		return OSWindows, DeviceDesktop
+	case strings.Contains(lower, "macintosh"), strings.Contains(lower, "mac os x"):
+		return OSMacOS, DeviceDesktop
+	case strings.Contains(lower, "linux"), strings.Contains(lower, "x11"), strings.Contains(lower, "cros"):
+		return OSLinux, DeviceDesktop
+	}
+	if strings.Contains(lower, "mobile") {
+		return OSOther, DeviceMobile
+	}
+	return OSOther, DeviceDesktop
+}
+
+// PreferredLanguage returns the lowercased language tag with the highest
+// quality in an Accept-Language header, or an empty string.
+func PreferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,118 @@
+// Package safehttp builds HTTP clients for requests to user supplied URLs
+// that refuse to connect to internal addresses.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxRedirects = 5
+)
+
+var (
+	ErrForbiddenAddress = errors.New("destination address is not allowed")
+	ErrScheme           = errors.New("only http and https destinations are fetched")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Options configures NewClient. Zero values use the defaults.
+type Options struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate permits loopback, private and link-local destinations.
+	// It disables the SSRF protection and is meant for tests.
+	AllowPrivate bool
+}
+
+// NewClient returns a client that only connects to public addresses unless
+// Options.AllowPrivate is set: the check runs on the resolved address of
+// every connection, so DNS names and redirects cannot be used to reach
+// internal services. Redirects to schemes other than http and https fail
+// with ErrScheme.
+func NewClient(opts Options) *http.Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublic(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		// a proxy would make the connection check useless
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return CheckScheme(req.URL)
+		},
+	}
+}
+
+// CheckScheme returns ErrScheme unless u is an http or https URL.
+func CheckScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrScheme
+	}
+	return nil
+}
+
+// IsPublic reports whether addr is a globally routable unicast address.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonPublic lists special purpose ranges that IsGlobalUnicast and IsPrivate
+// let through.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
@@ -0,0 +1,44 @@
+package safehttp_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"url-shortener/internal/lib/safehttp"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fc00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	}
+	for addr, want := range cases {
+		require.Equal(t, want, safehttp.IsPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	_, err := safehttp.NewClient(safehttp.Options{}).Get(srv.URL)
+	require.ErrorIs(t, err, safehttp.ErrForbiddenAddress)
+
+	res, err := safehttp.NewClient(safehttp.Options{AllowPrivate: true}).Get(srv.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+}
@@ -0,0 +1,123 @@
+// Package totp implements the time-based one-time passwords of RFC 6238 as
+// used by authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPeriod = 30 * time.Second
+	DefaultDigits = 6
+	// Skew is the number of periods before and after the current one whose
+	// codes are accepted, to allow for clock drift and typing time.
+	Skew = 1
+
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Options select the variant of the algorithm. Zero values use the defaults
+// understood by every authenticator app: SHA-1, 6 digits and 30 seconds.
+type Options struct {
+	Period time.Duration
+	Digits int
+	Hash   func() hash.Hash
+}
+
+func (o Options) withDefaults() Options {
+	if o.Period <= 0 {
+		o.Period = DefaultPeriod
+	}
+	if o.Digits <= 0 {
+		o.Digits = DefaultDigits
+	}
+	if o.Hash == nil {
+		o.Hash = sha1.New
+	}
+	return o
+}
+
+// NewSecret returns a random base32 secret of 160 bits.
+func NewSecret() (string, error) {
+	key := make([]byte, secretSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(key), nil
+}
+
+// Decode decodes a base32 secret, ignoring case, spaces and padding.
+func Decode(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// Step returns the number of the period t falls into.
+func Step(t time.Time, opts Options) int64 {
+	return t.Unix() / int64(opts.withDefaults().Period/time.Second)
+}
+
+// Code returns the code of key for step.
+func Code(key []byte, step int64, opts Options) string {
+	opts = opts.withDefaults()
+	mac := hmac.New(opts.Hash, key)
+	_ = binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range opts.Digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", opts.Digits, value%mod)
+}
+
+// Validate reports whether code is the code of secret at now, give or take
+// Skew periods, and returns the step of the code. Callers should refuse
+// steps at or before the last accepted one so that codes cannot be replayed.
+func Validate(secret, code string, now time.Time) (int64, bool) {
+	key, err := Decode(secret)
+	if err != nil || len(code) != DefaultDigits {
+		return 0, false
+	}
+	step := Step(now, Options{})
+	for i := -Skew; i <= Skew; i++ {
+		want := Code(key, step+int64(i), Options{})
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth URI of secret, usually shown as a QR code, that
+// adds the account to an authenticator app.
+func URI(issuer, account, secret string) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + account,
+	}
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(DefaultDigits))
+	q.Set("period", fmt.Sprint(int(DefaultPeriod/time.Second)))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
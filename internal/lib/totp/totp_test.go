@@ -0,0 +1,112 @@
+package totp_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"github.com/stretchr/testify/require"
+	"hash"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/totp"
+)
+
+// TestRFC6238 checks the test vectors of RFC 6238 appendix B.
+func TestRFC6238(t *testing.T) {
+	keys := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	hashes := map[string]func() hash.Hash{
+		"SHA1":   sha1.New,
+		"SHA256": sha256.New,
+		"SHA512": sha512.New,
+	}
+	cases := []struct {
+		unix int64
+		mode string
+		code string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.mode+"/"+tc.code, func(t *testing.T) {
+			opts := totp.Options{Digits: 8, Hash: hashes[tc.mode]}
+			step := totp.Step(time.Unix(tc.unix, 0), opts)
+			require.Equal(t, tc.code, totp.Code(keys[tc.mode], step, opts))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+	key, err := totp.Decode(secret)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 12, 0, 15, 0, time.UTC)
+	step := totp.Step(now, totp.Options{})
+
+	cases := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{name: "current", offset: 0, ok: true},
+		{name: "previous", offset: -1, ok: true},
+		{name: "next", offset: 1, ok: true},
+		{name: "too old", offset: -2},
+		{name: "too new", offset: 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code := totp.Code(key, step+tc.offset, totp.Options{})
+			got, ok := totp.Validate(secret, code, now)
+			require.Equal(t, tc.ok, ok)
+			if tc.ok {
+				require.Equal(t, step+tc.offset, got)
+			}
+		})
+	}
+
+	code := totp.Code(key, step, totp.Options{})
+	_, ok := totp.Validate(strings.ToLower(secret), code, now)
+	require.True(t, ok, "secrets are case-insensitive")
+	_, ok = totp.Validate(secret, code+"0", now)
+	require.False(t, ok)
+	_, ok = totp.Validate("not base32!", code, now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("url-shortener", "user@example.com", "JBSWY3DPEHPK3PXP")
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/url-shortener:user@example.com", u.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	require.Equal(t, "url-shortener", u.Query().Get("issuer"))
+	require.Equal(t, "6", u.Query().Get("digits"))
+}
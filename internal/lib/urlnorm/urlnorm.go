@@ -0,0 +1,113 @@
+// Package urlnorm normalizes URLs so that different spellings of the same
+// destination compare equal.
+package urlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+var ErrNotAbsolute = errors.New("url is not absolute")
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize lowercases scheme and host, strips the default port, sorts query
+// parameters by key, resolves dot segments and removes the trailing slash of
+// non-root paths. An empty path becomes "/". Fragments are kept as they are.
+func Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", ErrNotAbsolute
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if defaultPorts[u.Scheme] == port {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.RawPath == "" {
+		u.Path = normalizePath(u.Path)
+	} else if len(u.Path) > 1 {
+		// escaped slashes would change meaning when cleaned, only trim the end
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+
+	u.RawQuery = normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// Hash returns a hex SHA-256 of the normalized URL, suitable for an index.
+func Hash(raw string) (string, error) {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeQuery re-encodes the pairs of raw and sorts them by key. Values of
+// a repeated key keep their order, which may matter to the destination, and
+// pairs that do not parse are kept as written instead of being dropped.
+func normalizeQuery(raw string) string {
+	type pair struct {
+		key     string
+		encoded string
+	}
+	var pairs []pair
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(part, "=")
+		key, keyErr := url.QueryUnescape(rawKey)
+		value, valueErr := url.QueryUnescape(rawValue)
+		if keyErr != nil || valueErr != nil || strings.Contains(part, ";") {
+			pairs = append(pairs, pair{key: rawKey, encoded: part})
+			continue
+		}
+		pairs = append(pairs, pair{key: key, encoded: url.QueryEscape(key) + "=" + url.QueryEscape(value)})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p.encoded
+	}
+	return strings.Join(encoded, "&")
+}
+
+func normalizePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "." {
+		return "/"
+	}
+	return cleaned
+}
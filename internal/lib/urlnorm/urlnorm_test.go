@@ -0,0 +1,72 @@
+package urlnorm_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/lib/urlnorm"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+		err  bool
+	}{
+		{name: "Scheme and host case", in: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "Default http port", in: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "Default https port", in: "https://example.com:443", want: "https://example.com/"},
+		{name: "Other port kept", in: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{name: "Query sorted by key", in: "https://example.com/?b=2&a=1&a=0", want: "https://example.com/?a=1&a=0&b=2"},
+		{name: "Query escaping", in: "https://example.com/?q=a%20b&x", want: "https://example.com/?q=a+b&x="},
+		{name: "Semicolon pair kept", in: "https://x.com/p?a=1;b=2", want: "https://x.com/p?a=1;b=2"},
+		{name: "Bad escape kept", in: "https://x.com/p?%zz=1", want: "https://x.com/p?%zz=1"},
+		{name: "Bad escape sorted as written", in: "https://x.com/p?b=1&%zz=1&a=1", want: "https://x.com/p?%zz=1&a=1&b=1"},
+		{name: "Empty query dropped", in: "https://example.com/a?", want: "https://example.com/a"},
+		{name: "Trailing slash", in: "https://example.com/a/b/", want: "https://example.com/a/b"},
+		{name: "Dot segments", in: "https://example.com/a/./b/../c", want: "https://example.com/a/c"},
+		{name: "Fragment kept", in: "https://example.com/#Top", want: "https://example.com/#Top"},
+		{name: "IPv6 host", in: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "Relative", in: "/just/a/path", err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := urlnorm.Normalize(tc.in)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHashEqualForEquivalentURLs(t *testing.T) {
+	a, err := urlnorm.Hash("HTTP://example.com:80/docs/?b=1&a=2")
+	require.NoError(t, err)
+	b, err := urlnorm.Hash("http://EXAMPLE.com/docs?a=2&b=1")
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+}
+
+func TestHashKeepsQueryMeaning(t *testing.T) {
+	plain, err := urlnorm.Hash("https://x.com/p")
+	require.NoError(t, err)
+	for _, raw := range []string{"https://x.com/p?a=1;b=2", "https://x.com/p?%zz=1"} {
+		h, err := urlnorm.Hash(raw)
+		require.NoError(t, err)
+		require.NotEqual(t, plain, h, raw)
+	}
+
+	a, err := urlnorm.Hash("https://x.com/p?a=1&a=0")
+	require.NoError(t, err)
+	b, err := urlnorm.Hash("https://x.com/p?a=0&a=1")
+	require.NoError(t, err)
+	require.NotEqual(t, a, b, "the order of repeated values is kept")
+}
@@ -0,0 +1,79 @@
+// Package utm merges campaign and passthrough query parameters into link
+// destinations.
+package utm
+
+import (
+	"net/url"
+	"strings"
+	"url-shortener/internal/models"
+)
+
+// Values returns the non-empty UTM parameters of p.
+func Values(p models.UTM) url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   p.Source,
+		"utm_medium":   p.Medium,
+		"utm_campaign": p.Campaign,
+		"utm_term":     p.Term,
+		"utm_content":  p.Content,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values
+}
+
+// Merge adds params to the query string of target. Parameters already present
+// in target are replaced when override is set and kept otherwise. The order
+// and encoding of the untouched parameters of target are preserved, and target
+// is returned unchanged when there is nothing to add.
+func Merge(target string, params url.Values, override bool) (string, error) {
+	if len(params) == 0 {
+		return target, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	var pairs []string
+	if u.RawQuery != "" {
+		pairs = strings.Split(u.RawQuery, "&")
+	}
+	existing := map[string]bool{}
+	for _, pair := range pairs {
+		if key, ok := queryKey(pair); ok {
+			existing[key] = true
+		}
+	}
+
+	add := url.Values{}
+	for key, values := range params {
+		if existing[key] && !override {
+			continue
+		}
+		add[key] = values
+	}
+	if len(add) == 0 {
+		return target, nil
+	}
+
+	kept := make([]string, 0, len(pairs)+1)
+	for _, pair := range pairs {
+		if key, ok := queryKey(pair); ok && add.Has(key) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	kept = append(kept, add.Encode())
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+func queryKey(pair string) (string, bool) {
+	key, _, _ := strings.Cut(pair, "=")
+	key, err := url.QueryUnescape(key)
+	return key, err == nil && key != ""
+}
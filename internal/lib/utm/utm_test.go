@@ -0,0 +1,86 @@
+package utm_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"url-shortener/internal/lib/utm"
+	"url-shortener/internal/models"
+)
+
+func TestValues(t *testing.T) {
+	values := utm.Values(models.UTM{Source: "newsletter", Campaign: "spring sale"})
+	require.Equal(t, "utm_campaign=spring+sale&utm_source=newsletter", values.Encode())
+	require.Empty(t, utm.Values(models.UTM{}))
+}
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name     string
+		target   string
+		params   url.Values
+		override bool
+		want     string
+	}{
+		{
+			name:   "No params",
+			target: "https://example.com/a?z=1&b=2",
+			want:   "https://example.com/a?z=1&b=2",
+		},
+		{
+			name:   "Adds query",
+			target: "https://example.com/a",
+			params: url.Values{"utm_source": {"mail"}},
+			want:   "https://example.com/a?utm_source=mail",
+		},
+		{
+			name:   "Keeps existing order",
+			target: "https://example.com/a?z=1&b=2",
+			params: url.Values{"utm_medium": {"email"}, "utm_source": {"mail"}},
+			want:   "https://example.com/a?z=1&b=2&utm_medium=email&utm_source=mail",
+		},
+		{
+			name:     "Overrides existing",
+			target:   "https://example.com/?utm_source=old&id=7",
+			params:   url.Values{"utm_source": {"new"}},
+			override: true,
+			want:     "https://example.com/?id=7&utm_source=new",
+		},
+		{
+			name:   "Keeps existing without override",
+			target: "https://example.com/?utm_source=old&id=7",
+			params: url.Values{"utm_source": {"new"}, "ref": {"x"}},
+			want:   "https://example.com/?utm_source=old&id=7&ref=x",
+		},
+		{
+			name:   "Repeated values",
+			target: "https://example.com/",
+			params: url.Values{"tag": {"a", "b"}},
+			want:   "https://example.com/?tag=a&tag=b",
+		},
+		{
+			name:   "Fragment kept",
+			target: "https://example.com/page?x=1#section",
+			params: url.Values{"y": {"2"}},
+			want:   "https://example.com/page?x=1&y=2#section",
+		},
+		{
+			name:   "Empty query marker",
+			target: "https://example.com/page?",
+			params: url.Values{"y": {"2"}},
+			want:   "https://example.com/page?y=2",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := utm.Merge(tc.target, tc.params, tc.override)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
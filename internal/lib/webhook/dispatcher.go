@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/safehttp"
+	"url-shortener/internal/models"
+)
+
+const (
+	DefaultWorkers      = 4
+	DefaultTimeout      = 10 * time.Second
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultBackoff      = 30 * time.Second
+	DefaultMaxBackoff   = 6 * time.Hour
+
+	batchSize = 100
+	userAgent = "url-shortener-webhook/1.0"
+)
+
+type Store interface {
+	EnqueueWebhookEvent(userId int64, event string, payload []byte, at time.Time) (int64, error)
+	DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(d models.WebhookDelivery) error
+	ListExpiredURLs(now time.Time) ([]models.UrlShortener, error)
+	MarkExpiryNotified(urlId int64) error
+}
+
+// Options configures a Dispatcher. Zero values use the defaults.
+type Options struct {
+	// Workers limits the number of deliveries in flight.
+	Workers int
+	Timeout time.Duration
+	// PollInterval is the time between two looks at the queue and at expired
+	// links.
+	PollInterval time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is given
+	// up.
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt. It doubles with
+	// every further failure up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// ClickThresholds are the click counts that trigger link.clicks.
+	ClickThresholds []int64
+	// AllowPrivate permits loopback, private and link-local endpoints.
+	// It disables the SSRF protection and is meant for tests.
+	AllowPrivate bool
+}
+
+// Dispatcher queues link events for the webhooks of the link owner and
+// delivers them in the background.
+type Dispatcher struct {
+	log    *slog.Logger
+	store  Store
+	client *http.Client
+	opts   Options
+	wake   chan struct{}
+}
+
+func NewDispatcher(log *slog.Logger, store Store, opts Options) *Dispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Dispatcher{
+		log:   log.With("component", "webhook"),
+		store: store,
+		client: safehttp.NewClient(safehttp.Options{
+			Timeout:      opts.Timeout,
+			AllowPrivate: opts.AllowPrivate,
+		}),
+		opts: opts,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Publish queues event about link for the webhooks of its owner. Failures are
+// logged, they never fail the operation the event is about.
+func (d *Dispatcher) Publish(event string, link models.UrlShortener) {
+	d.publish(event, link, 0)
+}
+
+// Clicked queues link.clicks when clicks, the click count of link after a
+// click, is one of the thresholds.
+func (d *Dispatcher) Clicked(link models.UrlShortener, clicks int64) {
+	if !slices.Contains(d.opts.ClickThresholds, clicks) {
+		return
+	}
+	link.Clicks = clicks
+	d.publish(EventLinkClicks, link, clicks)
+}
+
+func (d *Dispatcher) publish(event string, link models.UrlShortener, threshold int64) {
+	if link.UserId == 0 {
+		return
+	}
+	now := time.Now()
+	data := newLink(link)
+	data.Threshold = threshold
+	payload, err := json.Marshal(Payload{ID: randomHex(16), Type: event, CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		d.log.Error("failed to encode event", "event", event, "err", err)
+		return
+	}
+	n, err := d.store.EnqueueWebhookEvent(link.UserId, event, payload, now)
+	if err != nil {
+		d.log.Error("failed to queue event", "event", event, "alias", link.Alias, "err", err)
+		return
+	}
+	if n > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers queued events and publishes link.expired until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.PublishExpired()
+		d.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// PublishExpired publishes link.expired once for every link that expired.
+func (d *Dispatcher) PublishExpired() {
+	links, err := d.store.ListExpiredURLs(time.Now())
+	if err != nil {
+		d.log.Error("failed to list expired links", "err", err)
+		return
+	}
+	for _, link := range links {
+		d.Publish(EventLinkExpired, link)
+		if err := d.store.MarkExpiryNotified(link.Id); err != nil {
+			d.log.Error("failed to mark expiry as notified", "url_id", link.Id, "err", err)
+		}
+	}
+}
+
+// DeliverDue sends the deliveries whose next attempt is due.
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.store.DueWebhookDeliveries(time.Now(), batchSize)
+		if err != nil {
+			d.log.Error("failed to load webhook deliveries", "err", err)
+			return
+		}
+		sem := make(chan struct{}, d.opts.Workers)
+		var wg sync.WaitGroup
+		for _, delivery := range due {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				d.deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// shutting down, the attempt is repeated after the restart
+		return
+	}
+	delivery.Attempts++
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		d.log.Warn("giving up webhook delivery", "delivery_id", delivery.Id, "webhook_id", delivery.WebhookId, "err", err)
+	default:
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		d.log.Info("webhook delivery failed", "delivery_id", delivery.Id, "attempt", delivery.Attempts, "err", err)
+	}
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		d.log.Error("failed to update webhook delivery", "delivery_id", delivery.Id, "err", err)
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.Backoff
+	for i := 1; i < attempts && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.opts.MaxBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	if err := safehttp.CheckScheme(req.URL); err != nil {
+		return err
+	}
+	var payload struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(delivery.Payload, &payload)
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderID, payload.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered with status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+)
+
+// store keeps webhooks and deliveries in memory.
+type store struct {
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery
+	expired    []models.UrlShortener
+	notified   []int64
+}
+
+func (s *store) EnqueueWebhookEvent(userId int64, event string, payload []byte, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, w := range s.webhooks {
+		if w.UserId != userId || (len(w.Events) > 0 && !slices.Contains(w.Events, event)) {
+			continue
+		}
+		s.deliveries = append(s.deliveries, models.WebhookDelivery{
+			Id: int64(len(s.deliveries) + 1), WebhookId: w.Id, URL: w.URL, Secret: w.Secret,
+			Event: event, Payload: payload, Status: models.DeliveryPending, NextAttemptAt: at, CreatedAt: at,
+		})
+		n++
+	}
+	return n, nil
+}
+
+func (s *store) DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (s *store) UpdateWebhookDelivery(d models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[d.Id-1] = d
+	return nil
+}
+
+func (s *store) ListExpiredURLs(now time.Time) ([]models.UrlShortener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []models.UrlShortener
+	for _, link := range s.expired {
+		if link.Expired(now) && !slices.Contains(s.notified, link.Id) {
+			expired = append(expired, link)
+		}
+	}
+	return expired, nil
+}
+
+func (s *store) MarkExpiryNotified(urlId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notified = append(s.notified, urlId)
+	return nil
+}
+
+func (s *store) delivery(i int) models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deliveries[i]
+}
+
+type received struct {
+	header  http.Header
+	payload webhook.Payload
+}
+
+// newReceiver answers with the given statuses in turn and 204 after them.
+func newReceiver(t *testing.T, secret string, statuses ...int) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		timestamp, err := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		require.True(t, webhook.Verify(secret, timestamp, body, r.Header.Get(webhook.HeaderSignature)))
+
+		var payload webhook.Payload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, received{header: r.Header, payload: payload})
+		if len(got) <= len(statuses) {
+			w.WriteHeader(statuses[len(got)-1])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(got)
+	}
+}
+
+func newDispatcher(s *store, opts webhook.Options) *webhook.Dispatcher {
+	opts.AllowPrivate = true
+	return webhook.NewDispatcher(slog.New(custom_mocks.NewMockLogger()), s, opts)
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"link.created"}`)
+	signature := webhook.Sign("secret", 1735689600, body)
+
+	require.Equal(t, "sha256=", signature[:7])
+	require.Len(t, signature, 7+64)
+	require.True(t, webhook.Verify("secret", 1735689600, body, signature))
+	require.False(t, webhook.Verify("other", 1735689600, body, signature))
+	require.False(t, webhook.Verify("secret", 1735689601, body, signature))
+	require.False(t, webhook.Verify("secret", 1735689600, []byte(`{"type":"link.deleted"}`), signature))
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	srv, got := newReceiver(t, "s1")
+	s := &store{webhooks: []models.Webhook{
+		{Id: 1, UserId: 1, URL: srv.URL, Secret: "s1"},
+		{Id: 2, UserId: 1, URL: srv.URL, Secret: "s2", Events: []string{webhook.EventLinkDeleted}},
+		{Id: 3, UserId: 2, URL: srv.URL, Secret: "s3"},
+	}}
+	d := newDispatcher(s, webhook.Options{})
+
+	link := models.UrlShortener{Id: 7, Alias: "docs", Url: "https://example.com", UserId: 1}
+	d.Publish(webhook.EventLinkCreated, link)
+	d.Publish(webhook.EventLinkCreated, models.UrlShortener{Alias: "anonymous", Url: "https://example.com"})
+	require.Len(t, s.deliveries, 1)
+
+	d.DeliverDue(context.Background())
+
+	require.Len(t, got(), 1)
+	r := got()[0]
+	require.Equal(t, webhook.EventLinkCreated, r.header.Get(webhook.HeaderEvent))
+	require.Equal(t, r.payload.ID, r.header.Get(webhook.HeaderID))
+	require.Equal(t, webhook.EventLinkCreated, r.payload.Type)
+	require.Equal(t, "docs", r.payload.Data.Alias)
+	require.Equal(t, "https://example.com", r.payload.Data.URL)
+
+	delivered := s.delivery(0)
+	require.Equal(t, models.DeliveryDelivered, delivered.Status)
+	require.Equal(t, 1, delivered.Attempts)
+	require.False(t, delivered.DeliveredAt.IsZero())
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	srv, got := newReceiver(t, "s1", http.StatusInternalServerError, http.StatusBadGateway)
+	s := &store{webhooks: []models.Webhook{{Id: 1, UserId: 1, URL: srv.URL, Secret: "s1"}}}
+	backoff := 20 * time.Millisecond
+	d := newDispatcher(s, webhook.Options{Backoff: backoff, MaxBackoff: time.Hour})
+
+	d.Publish(webhook.EventLinkUpdated, models.UrlShortener{Alias: "docs", UserId: 1})
+	d.DeliverDue(context.Background())
+	first := s.delivery(0)
+	require.Equal(t, models.DeliveryPending, first.Status)
+	require.Equal(t, 1, first.Attempts)
+	require.Contains(t, first.LastError, "500")
+
+	// not due before the backoff
+	d.DeliverDue(context.Background())
+	require.Len(t, got(), 1)
+
+	time.Sleep(backoff)
+	d.DeliverDue(context.Background())
+	second := s.delivery(0)
+	require.Equal(t, 2, second.Attempts)
+	require.Contains(t, second.LastError, "502")
+	// the delay doubles after every failure
+	require.GreaterOrEqual(t, second.NextAttemptAt.Sub(first.NextAttemptAt), 2*backoff)
+
+	time.Sleep(2 * backoff)
+	d.DeliverDue(context.Background())
+	require.Equal(t, models.DeliveryDelivered, s.delivery(0).Status)
+	require.Len(t, got(), 3)
+	// every attempt carries the same event id
+	require.Equal(t, got()[0].payload.ID, got()[2].payload.ID)
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	srv, got := newReceiver(t, "s1", http.StatusGone, http.StatusGone)
+	s := &store{webhooks: []models.Webhook{{Id: 1, UserId: 1, URL: srv.URL, Secret: "s1"}}}
+	d := newDispatcher(s, webhook.Options{Backoff: time.Millisecond, MaxAttempts: 2})
+
+	d.Publish(webhook.EventLinkDeleted, models.UrlShortener{Alias: "docs", UserId: 1})
+	d.DeliverDue(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	d.DeliverDue(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	d.DeliverDue(context.Background())
+
+	require.Len(t, got(), 2)
+	require.Equal(t, models.DeliveryFailed, s.delivery(0).Status)
+}
+
+func TestDispatcherClickThresholds(t *testing.T) {
+	srv, got := newReceiver(t, "s1")
+	s := &store{webhooks: []models.Webhook{{Id: 1, UserId: 1, URL: srv.URL, Secret: "s1"}}}
+	d := newDispatcher(s, webhook.Options{ClickThresholds: []int64{10, 100}})
+
+	link := models.UrlShortener{Alias: "docs", UserId: 1}
+	for clicks := int64(1); clicks <= 120; clicks++ {
+		d.Clicked(link, clicks)
+	}
+	d.DeliverDue(context.Background())
+
+	var thresholds []int64
+	for _, r := range got() {
+		require.Equal(t, webhook.EventLinkClicks, r.payload.Type)
+		require.Equal(t, r.payload.Data.Threshold, r.payload.Data.Clicks)
+		thresholds = append(thresholds, r.payload.Data.Threshold)
+	}
+	// deliveries run concurrently and arrive in any order
+	require.ElementsMatch(t, []int64{10, 100}, thresholds)
+}
+
+func TestDispatcherPublishesExpired(t *testing.T) {
+	srv, got := newReceiver(t, "s1")
+	expiresAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	s := &store{
+		webhooks: []models.Webhook{{Id: 1, UserId: 1, URL: srv.URL, Secret: "s1", Events: []string{webhook.EventLinkExpired}}},
+		expired: []models.UrlShortener{
+			{Id: 1, Alias: "sale", UserId: 1, ExpiresAt: expiresAt},
+			{Id: 2, Alias: "later", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+	}
+	d := newDispatcher(s, webhook.Options{PollInterval: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(stopped)
+	}()
+	require.Eventually(t, func() bool { return len(got()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-stopped
+
+	r := got()[0]
+	require.Equal(t, webhook.EventLinkExpired, r.payload.Type)
+	require.Equal(t, "sale", r.payload.Data.Alias)
+	require.Equal(t, expiresAt, *r.payload.Data.ExpiresAt)
+	require.Equal(t, []int64{1}, s.notified)
+}
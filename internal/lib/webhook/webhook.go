@@ -0,0 +1,99 @@
+// Package webhook delivers signed link events to the endpoints registered by
+// link owners. Events are queued in the storage and retried with exponential
+// backoff until the endpoint accepts them.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+	"url-shortener/internal/models"
+)
+
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	EventLinkExpired = "link.expired"
+	// EventLinkClicks is sent when the clicks of a link reach one of the
+	// configured thresholds.
+	EventLinkClicks = "link.clicks"
+)
+
+// Events lists every event a webhook can subscribe to.
+var Events = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkExpired, EventLinkClicks}
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	// ID identifies the event and is the same for all webhooks and attempts.
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      Link      `json:"data"`
+}
+
+// Link describes the link an event is about.
+type Link struct {
+	Alias     string     `json:"alias"`
+	URL       string     `json:"url"`
+	Title     string     `json:"title,omitempty"`
+	Clicks    int64      `json:"clicks"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Threshold is the click count reached, set for link.clicks only.
+	Threshold int64 `json:"threshold,omitempty"`
+}
+
+func newLink(link models.UrlShortener) Link {
+	l := Link{
+		Alias:     link.Alias,
+		URL:       link.Url,
+		Title:     link.Title,
+		Clicks:    link.Clicks,
+		CreatedAt: link.CreatedAt,
+	}
+	if !link.ExpiresAt.IsZero() {
+		l.ExpiresAt = &link.ExpiresAt
+	}
+	return l
+}
+
+// Sign returns the signature header value of body sent at timestamp: the hex
+// encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with secret, prefixed
+// with "sha256=". Receivers should compare it in constant time and reject
+// old timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at
+// timestamp.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	return randomHex(32)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
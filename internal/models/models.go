@@ -1,13 +1,241 @@
 package models
 
+import "time"
+
 type UrlShortener struct {
-	Id    int64
-	Alias string
-	Url   string
+	Id      int64
+	Alias   string
+	Url     string
+	UrlHash string
+	// UserId is the creator of the link.
+	UserId int64
+	// WorkspaceId is the workspace owning the link, zero for personal links.
+	WorkspaceId int64
+	CreatedAt   time.Time
+	Clicks      int64
+	UTM         UTM
+	// ForwardQuery appends the query string of the short link request to Url.
+	ForwardQuery bool
+	// Rules are evaluated in order on redirect, the first matching rule
+	// replaces Url. They are only loaded for single links.
+	Rules []Rule
+	// Variants split the traffic that no rule matched by weight. They are
+	// only loaded for single links.
+	Variants []Variant
+	// StickyVariants keeps serving a visitor the same variant.
+	StickyVariants bool
+	Title          string
+	// Preview shows a page describing the destination instead of redirecting.
+	Preview bool
+	// Metadata is nil until the destination has been fetched.
+	Metadata *Metadata
+	// Health is nil until the destination has been checked.
+	Health *Health
+	// ExpiresAt is the end of the lifetime of the link, zero for links that
+	// never expire.
+	ExpiresAt time.Time
+}
+
+// Metadata describes the destination page of a link as fetched in the
+// background after the link was created.
+type Metadata struct {
+	Title       string
+	Description string
+	ImageURL    string
+	FaviconURL  string
+	// FinalURL and StatusCode are taken after following redirects.
+	FinalURL   string
+	StatusCode int
+	// Error is set when the page could not be fetched.
+	Error     string
+	FetchedAt time.Time
+}
+
+// Variant is one weighted destination of an A/B split link.
+type Variant struct {
+	Id     int64
+	Target string
+	Weight int
+	Clicks int64
+}
+
+// Rule sends visitors matching all of its non-empty conditions to Target.
+// Each condition matches when any of its values does.
+type Rule struct {
+	OS        []string
+	Devices   []string
+	Languages []string
+	CIDRs     []string
+	Countries []string
+	// Start and End bound the time window of the rule, zero means unbounded.
+	Start  time.Time
+	End    time.Time
+	Target string
+}
+
+// UTM holds the campaign parameters merged into the destination on redirect.
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
 }
 
 type User struct {
 	Id       int64
 	Email    string
 	Password []byte
+	Disabled bool
+	// Role is one of the Role constants, RoleMember when empty.
+	Role          string
+	EmailVerified bool
+	// TOTPSecret is set by the 2FA setup and only asked for at login once
+	// TOTPEnabled. TOTPLastStep is the step of the last accepted code.
+	TOTPSecret   string
+	TOTPEnabled  bool
+	TOTPLastStep int64
+}
+
+// Purposes of the single-use tokens sent to users by email.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// Results of login attempts. Only LoginBadCredentials counts as a failure
+// towards throttling.
+const (
+	LoginSuccess        = "success"
+	LoginBadCredentials = "bad_credentials"
+	LoginDisabled       = "disabled"
+	LoginThrottled      = "throttled"
+	// LoginTwoFactor is a right password of an account with 2FA, the login
+	// goes on with a code.
+	LoginTwoFactor = "two_factor"
+)
+
+// LoginEvent is an audit record of a login attempt. UserId is zero for
+// emails without an account.
+type LoginEvent struct {
+	Id        int64
+	UserId    int64
+	Email     string
+	IP        string
+	UserAgent string
+	Result    string
+	CreatedAt time.Time
+}
+
+// LoginFailures sums up recent failed login attempts.
+type LoginFailures struct {
+	Count int
+	Last  time.Time
+}
+
+// Roles grant access to the API. Read-only users may only use the GET
+// endpoints, admins may also moderate the links and accounts of all users.
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+// Roles lists every role.
+var Roles = []string{RoleAdmin, RoleMember, RoleReadOnly}
+
+// ValidRole reports whether role is one of the Role constants.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember || role == RoleReadOnly
+}
+
+// Workspace shares the links it owns between its members.
+type Workspace struct {
+	Id        int64
+	Name      string
+	CreatedAt time.Time
+	// Role is the role of the user the workspace was listed for.
+	Role string
+}
+
+// WorkspaceMember is a user with a role in a workspace.
+type WorkspaceMember struct {
+	WorkspaceId int64
+	UserId      int64
+	Email       string
+	// Role is one of the WorkspaceRole constants.
+	Role      string
+	CreatedAt time.Time
+}
+
+// WorkspaceInvite lets the user with Email join a workspace with Role.
+type WorkspaceInvite struct {
+	WorkspaceId int64
+	Email       string
+	Role        string
+	InvitedBy   int64
+	CreatedAt   time.Time
+}
+
+// Workspace members may create, view and edit the links of the workspace.
+// Workspace admins may also delete them and manage the members.
+const (
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// Health is the result of the latest dead-link check of a destination.
+type Health struct {
+	StatusCode int
+	Latency    time.Duration
+	// Error is set when the destination could not be reached.
+	Error string
+	// Failures counts the checks that failed in a row up to CheckedAt.
+	Failures  int
+	CheckedAt time.Time
+}
+
+// Failed reports whether the check found the destination unreachable or
+// answering with an error status.
+func (h Health) Failed() bool {
+	return h.Error != "" || h.StatusCode >= 400
+}
+
+// Expired reports whether the link is expired at now.
+func (u UrlShortener) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
+}
+
+// Webhook is an endpoint that receives signed link events of its owner.
+type Webhook struct {
+	Id     int64
+	UserId int64
+	URL    string
+	Secret string
+	// Events the webhook is subscribed to, every event when empty.
+	Events    []string
+	CreatedAt time.Time
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is an event queued for a webhook. URL and Secret are those
+// of the webhook.
+type WebhookDelivery struct {
+	Id            int64
+	WebhookId     int64
+	URL           string
+	Secret        string
+	Event         string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   time.Time
 }
@@ -0,0 +1,29 @@
+package sqlite
+
+import "url-shortener/internal/models"
+
+// SaveHealth stores the result of a dead-link check of the link with urlId.
+// The failure count of h is ignored: it is increased for failed checks and
+// reset by successful ones. The stored count is returned.
+func (s *Storage) SaveHealth(urlId int64, h models.Health) (int, error) {
+	failed := 0
+	if h.Failed() {
+		failed = 1
+	}
+	var failures int
+	err := s.db.QueryRow(`INSERT INTO link_health (url_id, status_code, latency_ms, check_error, failures, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url_id) DO UPDATE SET status_code = excluded.status_code, latency_ms = excluded.latency_ms,
+		check_error = excluded.check_error, checked_at = excluded.checked_at,
+		failures = CASE WHEN excluded.failures = 0 THEN 0 ELSE link_health.failures + 1 END
+		RETURNING failures`,
+		urlId, h.StatusCode, h.Latency.Milliseconds(), h.Error, failed, h.CheckedAt.UTC()).Scan(&failures)
+	return failures, err
+}
+
+// ListBrokenURLs returns the links of userId whose latest minFailures or more
+// checks failed.
+func (s *Storage) ListBrokenURLs(userId int64, minFailures int) ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT "+linkColumns+" FROM "+linkTables+" WHERE user_id = ? AND h.failures >= ? ORDER BY id",
+		userId, minFailures)
+}
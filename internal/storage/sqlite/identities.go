@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"url-shortener/internal/models"
+)
+
+// ProvisionSSOUser returns the user linked to the account subject of the
+// identity provider issuer. A provider account seen for the first time is
+// linked to the user with email, who is created without a password if there
+// is none. The email of the user counts as verified.
+func (s *Storage) ProvisionSSOUser(issuer, subject, email string) (*models.User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userId int64
+	err = tx.QueryRow("SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?", issuer, subject).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRow("SELECT id FROM users WHERE email = ?", email).Scan(&userId)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRow("INSERT INTO users(email, password, role, email_verified) VALUES (?, ?, ?, 1) RETURNING id",
+				email, []byte{}, models.RoleMember).Scan(&userId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES (?, ?, ?, ?)",
+			issuer, subject, userId, time.Now().UTC())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE users SET email_verified = 1 WHERE id = ?", userId); err != nil {
+		return nil, err
+	}
+	user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", userId))
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
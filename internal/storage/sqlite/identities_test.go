@@ -0,0 +1,34 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/models"
+)
+
+func TestProvisionSSOUser(t *testing.T) {
+	s := newStorage(t)
+
+	user, err := s.ProvisionSSOUser("https://idp.example.com", "1", "new@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "new@example.com", user.Email)
+	require.Equal(t, models.RoleMember, user.Role)
+	require.True(t, user.EmailVerified)
+	require.Empty(t, user.Password, "provisioned users have no password")
+
+	again, err := s.ProvisionSSOUser("https://idp.example.com", "1", "renamed@example.com")
+	require.NoError(t, err)
+	require.Equal(t, user.Id, again.Id, "the subject identifies the user")
+
+	other, err := s.ProvisionSSOUser("https://other.example.com", "1", "other@example.com")
+	require.NoError(t, err)
+	require.NotEqual(t, user.Id, other.Id, "subjects are per issuer")
+
+	userId, err := s.SaveUser(models.User{Email: "local@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+	linked, err := s.ProvisionSSOUser("https://idp.example.com", "2", "local@example.com")
+	require.NoError(t, err)
+	require.Equal(t, userId, linked.Id, "existing users are linked by email")
+	require.Equal(t, []byte("hash"), linked.Password)
+	require.True(t, linked.EmailVerified)
+}
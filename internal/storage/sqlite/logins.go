@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"url-shortener/internal/models"
+)
+
+// SaveLoginEvent records a login attempt.
+func (s *Storage) SaveLoginEvent(e models.LoginEvent) error {
+	userId := sql.NullInt64{Int64: e.UserId, Valid: e.UserId != 0}
+	_, err := s.db.Exec("INSERT INTO login_events (user_id, email, ip, user_agent, result, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userId, e.Email, e.IP, e.UserAgent, e.Result, e.CreatedAt.UTC())
+	return err
+}
+
+// LoginFailures sums up the failed logins with email after since and after
+// the last successful login with email.
+func (s *Storage) LoginFailures(email string, since time.Time) (models.LoginFailures, error) {
+	var lastSuccess time.Time
+	err := s.db.QueryRow("SELECT created_at FROM login_events WHERE email = ? AND result = ? ORDER BY created_at DESC LIMIT 1",
+		email, models.LoginSuccess).Scan(&lastSuccess)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return models.LoginFailures{}, err
+	}
+	if lastSuccess.After(since) {
+		since = lastSuccess
+	}
+	return s.loginFailures("email", email, since)
+}
+
+// IPLoginFailures sums up the failed logins from ip after since, whatever
+// the account.
+func (s *Storage) IPLoginFailures(ip string, since time.Time) (models.LoginFailures, error) {
+	return s.loginFailures("ip", ip, since)
+}
+
+func (s *Storage) loginFailures(column, value string, since time.Time) (models.LoginFailures, error) {
+	var failures models.LoginFailures
+	err := s.db.QueryRow("SELECT COUNT(*) FROM login_events WHERE "+column+" = ? AND result = ? AND created_at > ?",
+		value, models.LoginBadCredentials, since.UTC()).Scan(&failures.Count)
+	if err != nil || failures.Count == 0 {
+		return failures, err
+	}
+	err = s.db.QueryRow("SELECT created_at FROM login_events WHERE "+column+" = ? AND result = ? ORDER BY created_at DESC LIMIT 1",
+		value, models.LoginBadCredentials).Scan(&failures.Last)
+	return failures, err
+}
+
+// ListLoginEvents returns the last limit login attempts into the account of
+// userId, newest first.
+func (s *Storage) ListLoginEvents(userId int64, limit int) ([]models.LoginEvent, error) {
+	rows, err := s.db.Query(`SELECT id, user_id, email, ip, user_agent, result, created_at FROM login_events
+		WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.LoginEvent
+	for rows.Next() {
+		var e models.LoginEvent
+		if err := rows.Scan(&e.Id, &e.UserId, &e.Email, &e.IP, &e.UserAgent, &e.Result, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
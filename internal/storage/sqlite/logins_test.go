@@ -0,0 +1,58 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"url-shortener/internal/models"
+)
+
+func TestLoginEvents(t *testing.T) {
+	s := newStorage(t)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	userId, err := s.SaveUser(models.User{Email: "user@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+
+	events := []models.LoginEvent{
+		{UserId: userId, Email: "user@example.com", IP: "192.0.2.1", Result: models.LoginBadCredentials, CreatedAt: start},
+		{UserId: userId, Email: "user@example.com", IP: "192.0.2.1", Result: models.LoginSuccess, CreatedAt: start.Add(time.Minute)},
+		{UserId: userId, Email: "user@example.com", IP: "192.0.2.1", Result: models.LoginBadCredentials, CreatedAt: start.Add(2 * time.Minute)},
+		{UserId: userId, Email: "user@example.com", IP: "192.0.2.2", Result: models.LoginBadCredentials, CreatedAt: start.Add(3 * time.Minute)},
+		{UserId: userId, Email: "user@example.com", IP: "192.0.2.2", Result: models.LoginThrottled, CreatedAt: start.Add(4 * time.Minute)},
+		{Email: "nobody@example.com", IP: "192.0.2.1", UserAgent: "curl/8.0", Result: models.LoginBadCredentials, CreatedAt: start.Add(5 * time.Minute)},
+	}
+	for _, e := range events {
+		require.NoError(t, s.SaveLoginEvent(e))
+	}
+
+	failures, err := s.LoginFailures("user@example.com", start.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, failures.Count, "failures before the last success are forgotten")
+	require.True(t, start.Add(3*time.Minute).Equal(failures.Last))
+
+	failures, err = s.LoginFailures("user@example.com", start.Add(150*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 1, failures.Count)
+
+	failures, err = s.LoginFailures("other@example.com", start.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, failures.Count)
+	require.True(t, failures.Last.IsZero())
+
+	failures, err = s.IPLoginFailures("192.0.2.1", start.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 3, failures.Count, "successes do not reset the failures of an IP")
+	require.True(t, start.Add(5*time.Minute).Equal(failures.Last))
+
+	listed, err := s.ListLoginEvents(userId, 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 5, "attempts with unknown emails are not listed")
+	require.Equal(t, models.LoginThrottled, listed[0].Result)
+	require.Equal(t, "192.0.2.2", listed[0].IP)
+	require.Equal(t, models.LoginSuccess, listed[3].Result)
+
+	listed, err = s.ListLoginEvents(userId, 2)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+}
@@ -0,0 +1,16 @@
+package sqlite
+
+import "url-shortener/internal/models"
+
+// SaveMetadata stores the fetched metadata of the link with urlId, replacing
+// earlier results.
+func (s *Storage) SaveMetadata(urlId int64, m models.Metadata) error {
+	_, err := s.db.Exec(`INSERT INTO url_metadata (url_id, page_title, description, image_url, favicon_url,
+		final_url, status_code, fetch_error, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url_id) DO UPDATE SET page_title = excluded.page_title, description = excluded.description,
+		image_url = excluded.image_url, favicon_url = excluded.favicon_url, final_url = excluded.final_url,
+		status_code = excluded.status_code, fetch_error = excluded.fetch_error, fetched_at = excluded.fetched_at`,
+		urlId, m.Title, m.Description, m.ImageURL, m.FaviconURL, m.FinalURL, m.StatusCode, m.Error, m.FetchedAt.UTC())
+	return err
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	sqlite "github.com/mattn/go-sqlite3"
+	"os"
+	"time"
+	"url-shortener/migrations"
+)
+
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+	ErrSchemaDirty  = errors.New("database schema is dirty")
+	ErrLockTimeout  = errors.New("timed out waiting for the migration lock")
+)
+
+const (
+	// a lock older than this was left behind by a crashed process
+	lockStaleAfter = 10 * time.Minute
+	// waiting outlasts a stale lock, so a crashed process never blocks startup
+	lockTimeout = lockStaleAfter + time.Minute
+	lockRetry   = 500 * time.Millisecond
+)
+
+// EmbeddedSource returns the migrations embedded into the binary.
+func EmbeddedSource() (source.Driver, error) {
+	return iofs.New(migrations.FS, ".")
+}
+
+// Migrate applies the pending embedded migrations to the database at
+// storagePath. A lock row serializes replicas starting at the same time, and
+// a database already migrated past the newest embedded version is refused.
+func Migrate(storagePath, table string) error {
+	lockDB, err := sql.Open("sqlite3", storagePath)
+	if err != nil {
+		return err
+	}
+	defer lockDB.Close()
+	release, err := acquireLock(lockDB, table+"_lock")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// the migrate driver closes its db together with m, so the lock uses its own handle
+	m, latest, err := newMigrate(storagePath, table)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if err := checkVersion(m, latest); err != nil {
+		return err
+	}
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// CheckSchema refuses a dirty database and one migrated past the newest
+// embedded version without applying anything, for servers started without
+// auto migration.
+func CheckSchema(storagePath, table string) error {
+	m, latest, err := newMigrate(storagePath, table)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return checkVersion(m, latest)
+}
+
+// newMigrate opens the database at storagePath with the embedded migrations
+// and returns their latest version.
+func newMigrate(storagePath, table string) (*migrate.Migrate, uint, error) {
+	db, err := sql.Open("sqlite3", storagePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	src, err := EmbeddedSource()
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	latest, err := LatestVersion(src)
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{MigrationsTable: table})
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite3", driver)
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	return m, latest, nil
+}
+
+func checkVersion(m *migrate.Migrate, latest uint) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrSchemaDirty, version)
+	}
+	if version > latest {
+		return fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, version, latest)
+	}
+	return nil
+}
+
+// LatestVersion returns the highest migration version of src.
+func LatestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+func acquireLock(db *sql.DB, table string) (func(), error) {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS " + table + " (id INTEGER PRIMARY KEY CHECK (id = 1), locked_at TIMESTAMP NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+	release := func() {
+		_, _ = db.Exec("DELETE FROM " + table + " WHERE id = 1")
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		_, err := db.Exec("INSERT INTO "+table+" (id, locked_at) VALUES (1, ?)", time.Now().UTC())
+		if err == nil {
+			return release, nil
+		}
+		var sqliteErr sqlite.Error
+		if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite.ErrConstraint {
+			return nil, err
+		}
+		if _, err := db.Exec("DELETE FROM "+table+" WHERE locked_at < ?", time.Now().Add(-lockStaleAfter).UTC()); err != nil {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		time.Sleep(lockRetry)
+	}
+}
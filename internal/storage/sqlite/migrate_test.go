@@ -0,0 +1,57 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestMigrate(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"), "migrating twice must be a no-op")
+
+	s, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+	_, err = s.SaveUser(models.User{Email: "user@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+
+	db, err := sql.Open("sqlite3", storagePath)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE migrations SET version = 1000")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.ErrorIs(t, sqlite.Migrate(storagePath, "migrations"), sqlite.ErrSchemaTooNew)
+	require.ErrorIs(t, sqlite.CheckSchema(storagePath, "migrations"), sqlite.ErrSchemaTooNew)
+}
+
+func TestCheckSchema(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, sqlite.CheckSchema(storagePath, "migrations"), "an empty database is not refused")
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+	require.NoError(t, sqlite.CheckSchema(storagePath, "migrations"))
+}
+
+func TestMigrateClearsStaleLock(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	db, err := sql.Open("sqlite3", storagePath)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE migrations_lock (id INTEGER PRIMARY KEY CHECK (id = 1), locked_at TIMESTAMP NOT NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO migrations_lock (id, locked_at) VALUES (1, ?)", time.Now().Add(-time.Hour).UTC())
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"), "a lock left by a crash must not block")
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+	"url-shortener/internal/models"
+)
+
+// ruleConditions is the JSON stored in link_rules.conditions.
+type ruleConditions struct {
+	OS        []string  `json:"os,omitempty"`
+	Devices   []string  `json:"devices,omitempty"`
+	Languages []string  `json:"languages,omitempty"`
+	CIDRs     []string  `json:"cidrs,omitempty"`
+	Countries []string  `json:"countries,omitempty"`
+	Start     time.Time `json:"start,omitzero"`
+	End       time.Time `json:"end,omitzero"`
+}
+
+func saveRules(tx *sql.Tx, urlId int64, rules []models.Rule) error {
+	for i, rule := range rules {
+		conditions, err := json.Marshal(ruleConditions{
+			OS:        rule.OS,
+			Devices:   rule.Devices,
+			Languages: rule.Languages,
+			CIDRs:     rule.CIDRs,
+			Countries: rule.Countries,
+			Start:     rule.Start,
+			End:       rule.End,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO link_rules (url_id, position, conditions, target) VALUES (?, ?, ?, ?)",
+			urlId, i, string(conditions), rule.Target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) getRules(urlId int64) ([]models.Rule, error) {
+	rows, err := s.db.Query("SELECT conditions, target FROM link_rules WHERE url_id = ? ORDER BY position", urlId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.Rule
+	for rows.Next() {
+		var raw, target string
+		if err := rows.Scan(&raw, &target); err != nil {
+			return nil, err
+		}
+		var c ruleConditions
+		if err := json.Unmarshal([]byte(raw), &c); err != nil {
+			return nil, err
+		}
+		rules = append(rules, models.Rule{
+			OS:        c.OS,
+			Devices:   c.Devices,
+			Languages: c.Languages,
+			CIDRs:     c.CIDRs,
+			Countries: c.Countries,
+			Start:     c.Start,
+			End:       c.End,
+			Target:    target,
+		})
+	}
+	return rules, rows.Err()
+}
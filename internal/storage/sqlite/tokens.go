@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+// SaveUserToken stores the hash of a single-use token of userId for purpose,
+// replacing the tokens userId had for the same purpose.
+func (s *Storage) SaveUserToken(userId int64, purpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?", userId, purpose); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		tokenHash, userId, purpose, expiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeUserToken deletes the token with tokenHash and returns its user if
+// the token was issued for purpose and is not expired at now.
+func (s *Storage) ConsumeUserToken(purpose, tokenHash string, now time.Time) (int64, error) {
+	var userId int64
+	var expiresAt time.Time
+	err := s.db.QueryRow("DELETE FROM user_tokens WHERE token_hash = ? AND purpose = ? RETURNING user_id, expires_at",
+		tokenHash, purpose).Scan(&userId, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrTokenNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !now.Before(expiresAt) {
+		return 0, storage.ErrTokenNotFound
+	}
+	return userId, nil
+}
@@ -0,0 +1,43 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestUserTokens(t *testing.T) {
+	s := newStorage(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	userId, err := s.SaveUser(models.User{Email: "user@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+	user, err := s.GetUserByID(userId)
+	require.NoError(t, err)
+	require.False(t, user.EmailVerified)
+
+	require.NoError(t, s.SaveUserToken(userId, models.TokenResetPassword, "old", now.Add(time.Hour)))
+	require.NoError(t, s.SaveUserToken(userId, models.TokenResetPassword, "new", now.Add(time.Hour)))
+	require.NoError(t, s.SaveUserToken(userId, models.TokenVerifyEmail, "verify", now.Add(time.Hour)))
+
+	_, err = s.ConsumeUserToken(models.TokenResetPassword, "old", now)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound, "a new token replaces the old one")
+	_, err = s.ConsumeUserToken(models.TokenResetPassword, "verify", now)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound, "tokens only work for their purpose")
+
+	id, err := s.ConsumeUserToken(models.TokenResetPassword, "new", now)
+	require.NoError(t, err)
+	require.Equal(t, userId, id)
+	_, err = s.ConsumeUserToken(models.TokenResetPassword, "new", now)
+	require.ErrorIs(t, err, storage.ErrTokenNotFound, "tokens are single-use")
+
+	_, err = s.ConsumeUserToken(models.TokenVerifyEmail, "verify", now.Add(time.Hour))
+	require.ErrorIs(t, err, storage.ErrTokenNotFound, "tokens expire")
+
+	require.NoError(t, s.SetEmailVerified(userId))
+	user, err = s.GetUserByID(userId)
+	require.NoError(t, err)
+	require.True(t, user.EmailVerified)
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import "url-shortener/internal/storage"
+
+// SetTOTPSecret stores the secret of a 2FA setup. The secret is only used
+// for logins once EnableTOTP is called.
+func (s *Storage) SetTOTPSecret(userId int64, secret string) error {
+	return s.updateUser("UPDATE users SET totp_secret = ? WHERE id = ?", secret, userId)
+}
+
+// EnableTOTP turns on 2FA for userId with the hashes of new recovery codes,
+// replacing any previous ones. step is the step of the code that confirmed
+// the setup.
+func (s *Storage) EnableTOTP(userId int64, step int64, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET totp_enabled = 1, totp_last_step = ? WHERE id = ? AND totp_secret != ''", step, userId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = storage.ErrUserNotFound
+		}
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userId, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as the step of the last accepted code of userId.
+// It returns false if a code of step or a later one was accepted before.
+func (s *Storage) UseTOTPStep(userId int64, step int64) (bool, error) {
+	res, err := s.db.Exec("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, userId, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ConsumeRecoveryCode deletes the recovery code of userId with codeHash.
+func (s *Storage) ConsumeRecoveryCode(userId int64, codeHash string) error {
+	res, err := s.db.Exec("DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?", userId, codeHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrRecoveryCodeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestTwoFactor(t *testing.T) {
+	s := newStorage(t)
+
+	userId, err := s.SaveUser(models.User{Email: "user@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, s.EnableTOTP(userId, 1, nil), storage.ErrUserNotFound, "2FA needs a secret")
+
+	require.NoError(t, s.SetTOTPSecret(userId, "JBSWY3DPEHPK3PXP"))
+	user, err := s.GetUserByID(userId)
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", user.TOTPSecret)
+	require.False(t, user.TOTPEnabled)
+
+	require.NoError(t, s.EnableTOTP(userId, 100, []string{"old"}))
+	require.NoError(t, s.EnableTOTP(userId, 100, []string{"a", "b"}))
+	user, err = s.GetUserByEmail("user@example.com")
+	require.NoError(t, err)
+	require.True(t, user.TOTPEnabled)
+	require.Equal(t, int64(100), user.TOTPLastStep)
+
+	ok, err := s.UseTOTPStep(userId, 100)
+	require.NoError(t, err)
+	require.False(t, ok, "codes cannot be replayed")
+	ok, err = s.UseTOTPStep(userId, 101)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.UseTOTPStep(userId, 99)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.ErrorIs(t, s.ConsumeRecoveryCode(userId, "old"), storage.ErrRecoveryCodeNotFound, "new codes replace the old ones")
+	require.NoError(t, s.ConsumeRecoveryCode(userId, "a"))
+	require.ErrorIs(t, s.ConsumeRecoveryCode(userId, "a"), storage.ErrRecoveryCodeNotFound, "codes are single-use")
+	require.NoError(t, s.ConsumeRecoveryCode(userId, "b"))
+}
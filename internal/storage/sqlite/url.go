@@ -4,17 +4,29 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/mattn/go-sqlite3"
+	"time"
 	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
 func (s *Storage) SaveURL(urlShortener models.UrlShortener) (int64, error) {
-	stmt, err := s.db.Prepare("INSERT INTO url (alias, url) VALUES (?, ?)")
+	tx, err := s.db.Begin()
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(urlShortener.Alias, urlShortener.Url)
+	createdAt := urlShortener.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	utm := urlShortener.UTM
+	res, err := tx.Exec(`INSERT INTO url (alias, url, url_hash, user_id, created_at, clicks,
+		utm_source, utm_medium, utm_campaign, utm_term, utm_content, forward_query, sticky_variants, title, preview, expires_at, workspace_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		urlShortener.Alias, urlShortener.Url, nullString(urlShortener.UrlHash), nullID(urlShortener.UserId), createdAt.UTC(), urlShortener.Clicks,
+		utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content, urlShortener.ForwardQuery, urlShortener.StickyVariants,
+		urlShortener.Title, urlShortener.Preview, nullTime(urlShortener.ExpiresAt), nullID(urlShortener.WorkspaceId))
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
 			return 0, storage.ErrUrlExists
@@ -25,7 +37,13 @@ func (s *Storage) SaveURL(urlShortener models.UrlShortener) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return id, err
+	if err := saveRules(tx, id, urlShortener.Rules); err != nil {
+		return 0, err
+	}
+	if err := saveVariants(tx, id, urlShortener.Variants); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
 func (s *Storage) GetURL(alias string) (string, error) {
@@ -50,12 +68,298 @@ func (s *Storage) DeleteURL(alias string) error {
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(alias)
+	res, err := stmt.Exec(alias)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrUrlNotFound
+	}
+	return nil
+}
+
+const linkColumns = "url.id, url.alias, url.url, COALESCE(url.url_hash, ''), COALESCE(url.user_id, 0), url.created_at, url.clicks, " +
+	"url.utm_source, url.utm_medium, url.utm_campaign, url.utm_term, url.utm_content, url.forward_query, url.sticky_variants, " +
+	"url.title, url.preview, url.expires_at, COALESCE(url.workspace_id, 0), " +
+	"COALESCE(m.page_title, ''), COALESCE(m.description, ''), COALESCE(m.image_url, ''), COALESCE(m.favicon_url, ''), " +
+	"COALESCE(m.final_url, ''), COALESCE(m.status_code, 0), COALESCE(m.fetch_error, ''), m.fetched_at, " +
+	"COALESCE(h.status_code, 0), COALESCE(h.latency_ms, 0), COALESCE(h.check_error, ''), COALESCE(h.failures, 0), h.checked_at"
+
+// linkTables joins the fetched metadata and the latest health check to links
+// selected with linkColumns.
+const linkTables = "url LEFT JOIN url_metadata m ON m.url_id = url.id LEFT JOIN link_health h ON h.url_id = url.id"
+
+func (s *Storage) GetLink(alias string) (*models.UrlShortener, error) {
+	stmt, err := s.db.Prepare("SELECT " + linkColumns + " FROM " + linkTables + " WHERE alias = ?")
+	if err != nil {
+		return nil, err
+	}
+	link, err := scanLink(stmt.QueryRow(alias))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadTargets(link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// loadTargets reads the rules and variants of link.
+func (s *Storage) loadTargets(link *models.UrlShortener) error {
+	var err error
+	if link.Rules, err = s.getRules(link.Id); err != nil {
+		return err
+	}
+	link.Variants, err = s.getVariants(link.Id)
+	return err
+}
+
+func (s *Storage) ListURLs(userId int64) ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT "+linkColumns+" FROM "+linkTables+" WHERE user_id = ? ORDER BY id", userId)
+}
+
+// ListWorkspaceURLs returns the links owned by workspaceId.
+func (s *Storage) ListWorkspaceURLs(workspaceId int64) ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT "+linkColumns+" FROM "+linkTables+" WHERE workspace_id = ? ORDER BY id", workspaceId)
+}
+
+func (s *Storage) queryLinks(query string, args ...any) ([]models.UrlShortener, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.UrlShortener
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for i := range links {
+		if err := s.loadTargets(&links[i]); err != nil {
+			return nil, err
+		}
+	}
+	return links, nil
+}
+
+// WalkURLs calls fn for every link of userId without loading them all into
+// memory.
+func (s *Storage) WalkURLs(userId int64, fn func(models.UrlShortener) error) error {
+	rows, err := s.db.Query("SELECT "+linkColumns+" FROM "+linkTables+" WHERE user_id = ? ORDER BY id", userId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return err
+		}
+		if err := s.loadTargets(link); err != nil {
+			return err
+		}
+		if err := fn(*link); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// FindDuplicateURL returns the oldest link that redirects like link would:
+// the same owner, normalized URL hash, workspace, UTM parameters, query
+// forwarding and preview, without expiry, redirect rules or A/B variants.
+func (s *Storage) FindDuplicateURL(link models.UrlShortener) (*models.UrlShortener, error) {
+	utm := link.UTM
+	row := s.db.QueryRow("SELECT "+linkColumns+" FROM "+linkTables+" WHERE url.user_id = ? AND url.url_hash = ?"+
+		" AND COALESCE(url.workspace_id, 0) = ?"+
+		" AND url.utm_source = ? AND url.utm_medium = ? AND url.utm_campaign = ? AND url.utm_term = ? AND url.utm_content = ?"+
+		" AND url.forward_query = ? AND url.preview = ? AND url.expires_at IS NULL"+
+		" AND NOT EXISTS (SELECT 1 FROM link_rules r WHERE r.url_id = url.id)"+
+		" AND NOT EXISTS (SELECT 1 FROM link_variants v WHERE v.url_id = url.id)"+
+		" ORDER BY url.id LIMIT 1",
+		link.UserId, link.UrlHash, link.WorkspaceId, utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content,
+		link.ForwardQuery, link.Preview)
+	found, err := scanLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// OverwriteURL replaces the destination, workspace, UTM parameters, query
+// forwarding, title, preview, expiry, redirect rules and A/B variants of the
+// link with the alias of link. Owner, clicks and creation time are kept, the
+// metadata and health of the old destination are cleared.
+func (s *Storage) OverwriteURL(link models.UrlShortener) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	utm := link.UTM
+	var id int64
+	err = tx.QueryRow(`UPDATE url SET url = ?, url_hash = ?, workspace_id = ?,
+		utm_source = ?, utm_medium = ?, utm_campaign = ?, utm_term = ?, utm_content = ?, forward_query = ?, sticky_variants = ?,
+		title = ?, preview = ?, expires_at = ?, expiry_notified = 0
+		WHERE alias = ? RETURNING id`,
+		link.Url, nullString(link.UrlHash), nullID(link.WorkspaceId),
+		utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content, link.ForwardQuery, link.StickyVariants,
+		link.Title, link.Preview, nullTime(link.ExpiresAt), link.Alias).Scan(&id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return storage.ErrUrlNotFound
 	}
 	if err != nil {
 		return err
 	}
+	for _, table := range []string{"link_rules", "link_variants", "url_metadata", "link_health"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE url_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if err := saveRules(tx, id, link.Rules); err != nil {
+		return err
+	}
+	if err := saveVariants(tx, id, link.Variants); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAllURLs returns every link regardless of owner.
+func (s *Storage) ListAllURLs() ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT " + linkColumns + " FROM " + linkTables + " ORDER BY id")
+}
+
+// ListURLsByPattern returns the links whose alias matches a GLOB pattern.
+func (s *Storage) ListURLsByPattern(pattern string) ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT "+linkColumns+" FROM "+linkTables+" WHERE alias GLOB ? ORDER BY id", pattern)
+}
+
+// DeleteURLsByPattern removes the links whose alias matches a GLOB pattern.
+func (s *Storage) DeleteURLsByPattern(pattern string) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM url WHERE alias GLOB ?", pattern)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// TransferURL makes userId the owner of alias.
+func (s *Storage) TransferURL(alias string, userId int64) error {
+	res, err := s.db.Exec("UPDATE url SET user_id = ? WHERE alias = ?", userId, alias)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrUrlNotFound
+	}
 	return nil
 }
+
+// TransferAllURLs moves every link of fromUserId to toUserId.
+func (s *Storage) TransferAllURLs(fromUserId, toUserId int64) (int64, error) {
+	res, err := s.db.Exec("UPDATE url SET user_id = ? WHERE user_id = ?", toUserId, fromUserId)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// NextAliasSequence returns the next number for sequential alias generators.
+func (s *Storage) NextAliasSequence() (int64, error) {
+	var value int64
+	err := s.db.QueryRow("UPDATE alias_sequence SET value = value + 1 WHERE id = 1 RETURNING value").Scan(&value)
+	return value, err
+}
+
+// IncrementClicks counts a click of alias and returns the new click count.
+func (s *Storage) IncrementClicks(alias string) (int64, error) {
+	var clicks int64
+	err := s.db.QueryRow("UPDATE url SET clicks = clicks + 1 WHERE alias = ? RETURNING clicks", alias).Scan(&clicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrUrlNotFound
+	}
+	return clicks, err
+}
+
+// ListExpiredURLs returns the links that expired at or before now and whose
+// expiry has not been marked as notified yet.
+func (s *Storage) ListExpiredURLs(now time.Time) ([]models.UrlShortener, error) {
+	return s.queryLinks("SELECT "+linkColumns+" FROM "+linkTables+
+		" WHERE url.expires_at IS NOT NULL AND url.expires_at <= ? AND url.expiry_notified = 0 ORDER BY id", now.UTC())
+}
+
+// MarkExpiryNotified keeps the link with urlId out of ListExpiredURLs.
+func (s *Storage) MarkExpiryNotified(urlId int64) error {
+	_, err := s.db.Exec("UPDATE url SET expiry_notified = 1 WHERE id = ?", urlId)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row scanner) (*models.UrlShortener, error) {
+	var link models.UrlShortener
+	var createdAt, expiresAt, fetchedAt, checkedAt sql.NullTime
+	var meta models.Metadata
+	var health models.Health
+	var latencyMs int64
+	utm := &link.UTM
+	if err := row.Scan(&link.Id, &link.Alias, &link.Url, &link.UrlHash, &link.UserId, &createdAt, &link.Clicks,
+		&utm.Source, &utm.Medium, &utm.Campaign, &utm.Term, &utm.Content, &link.ForwardQuery, &link.StickyVariants,
+		&link.Title, &link.Preview, &expiresAt, &link.WorkspaceId,
+		&meta.Title, &meta.Description, &meta.ImageURL, &meta.FaviconURL,
+		&meta.FinalURL, &meta.StatusCode, &meta.Error, &fetchedAt,
+		&health.StatusCode, &latencyMs, &health.Error, &health.Failures, &checkedAt); err != nil {
+		return nil, err
+	}
+	link.CreatedAt = createdAt.Time
+	link.ExpiresAt = expiresAt.Time
+	if fetchedAt.Valid {
+		meta.FetchedAt = fetchedAt.Time
+		link.Metadata = &meta
+	}
+	if checkedAt.Valid {
+		health.Latency = time.Duration(latencyMs) * time.Millisecond
+		health.CheckedAt = checkedAt.Time
+		link.Health = &health
+	}
+	return &link, nil
+}
+
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
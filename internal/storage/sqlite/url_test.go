@@ -0,0 +1,319 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+	s, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+	return s
+}
+
+func TestLinkRules(t *testing.T) {
+	s := newStorage(t)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rules := []models.Rule{
+		{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"},
+		{Countries: []string{"DE"}, CIDRs: []string{"192.0.2.0/24"}, Start: start, Target: "https://example.de"},
+	}
+
+	id, err := s.SaveURL(models.UrlShortener{Alias: "app", Url: "https://example.com", Rules: rules})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("app")
+	require.NoError(t, err)
+	require.Equal(t, id, link.Id)
+	require.Equal(t, rules, link.Rules)
+
+	require.NoError(t, s.DeleteURL("app"))
+	_, err = s.SaveURL(models.UrlShortener{Alias: "app", Url: "https://example.com"})
+	require.NoError(t, err)
+	link, err = s.GetLink("app")
+	require.NoError(t, err)
+	require.Empty(t, link.Rules, "rules of deleted links must be removed")
+}
+
+func TestFindDuplicateURL(t *testing.T) {
+	s := newStorage(t)
+	query := models.UrlShortener{UrlHash: "hash", UserId: 1}
+
+	_, err := s.SaveURL(models.UrlShortener{
+		Alias:   "rules",
+		Url:     "https://example.com",
+		UrlHash: "hash",
+		UserId:  1,
+		Rules:   []models.Rule{{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"}},
+	})
+	require.NoError(t, err)
+	_, err = s.FindDuplicateURL(query)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound, "links with rules are no duplicates")
+
+	_, err = s.SaveURL(models.UrlShortener{
+		Alias:    "variants",
+		Url:      "https://example.com",
+		UrlHash:  "hash",
+		UserId:   1,
+		Variants: []models.Variant{{Target: "https://example.com/a", Weight: 1}},
+	})
+	require.NoError(t, err)
+	_, err = s.FindDuplicateURL(query)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound, "links with variants are no duplicates")
+
+	// the older links differ from the query, the newer ones match exactly
+	for _, link := range []models.UrlShortener{
+		{Alias: "utm", UTM: models.UTM{Source: "mail"}},
+		{Alias: "preview", Preview: true},
+		{Alias: "expiring", ExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Alias: "plain"},
+	} {
+		link.Url, link.UrlHash, link.UserId = "https://example.com", "hash", 1
+		_, err = s.SaveURL(link)
+		require.NoError(t, err)
+	}
+	link, err := s.FindDuplicateURL(query)
+	require.NoError(t, err)
+	require.Equal(t, "plain", link.Alias)
+
+	link, err = s.FindDuplicateURL(models.UrlShortener{UrlHash: "hash", UserId: 1, UTM: models.UTM{Source: "mail"}})
+	require.NoError(t, err)
+	require.Equal(t, "utm", link.Alias)
+
+	link, err = s.FindDuplicateURL(models.UrlShortener{UrlHash: "hash", UserId: 1, Preview: true})
+	require.NoError(t, err)
+	require.Equal(t, "preview", link.Alias)
+
+	_, err = s.FindDuplicateURL(models.UrlShortener{UrlHash: "hash", UserId: 1, ForwardQuery: true})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+	_, err = s.FindDuplicateURL(models.UrlShortener{UrlHash: "hash", UserId: 2})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func TestOverwriteURL(t *testing.T) {
+	s := newStorage(t)
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	id, err := s.SaveURL(models.UrlShortener{
+		Alias:    "link",
+		Url:      "https://old.com",
+		UserId:   1,
+		Clicks:   5,
+		Rules:    []models.Rule{{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"}},
+		Variants: []models.Variant{{Target: "https://old.com/a", Weight: 1}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.SaveMetadata(id, models.Metadata{Title: "Old", FetchedAt: time.Now()}))
+
+	rules := []models.Rule{{Countries: []string{"DE"}, Target: "https://example.de"}}
+	require.NoError(t, s.OverwriteURL(models.UrlShortener{
+		Alias:        "link",
+		Url:          "https://new.com",
+		UrlHash:      "hash",
+		UTM:          models.UTM{Source: "import"},
+		ForwardQuery: true,
+		Rules:        rules,
+		Variants:     []models.Variant{{Target: "https://new.com/b", Weight: 2, Clicks: 3}},
+		Title:        "New",
+		Preview:      true,
+		ExpiresAt:    expires,
+	}))
+	link, err := s.GetLink("link")
+	require.NoError(t, err)
+	require.Equal(t, id, link.Id)
+	require.Equal(t, "https://new.com", link.Url)
+	require.Equal(t, "hash", link.UrlHash)
+	require.Equal(t, models.UTM{Source: "import"}, link.UTM)
+	require.True(t, link.ForwardQuery)
+	require.Equal(t, rules, link.Rules)
+	require.Len(t, link.Variants, 1)
+	require.Equal(t, "https://new.com/b", link.Variants[0].Target)
+	require.Equal(t, int64(3), link.Variants[0].Clicks)
+	require.Equal(t, "New", link.Title)
+	require.True(t, link.Preview)
+	require.Nil(t, link.Metadata, "metadata of the old destination is cleared")
+	require.True(t, expires.Equal(link.ExpiresAt))
+	require.Equal(t, int64(1), link.UserId, "the owner is kept")
+	require.Equal(t, int64(5), link.Clicks, "clicks are kept")
+
+	require.ErrorIs(t, s.OverwriteURL(models.UrlShortener{Alias: "missing", Url: "https://new.com"}), storage.ErrUrlNotFound)
+}
+
+func TestLinkVariants(t *testing.T) {
+	s := newStorage(t)
+
+	id, err := s.SaveURL(models.UrlShortener{
+		Alias:          "landing",
+		Url:            "https://example.com",
+		StickyVariants: true,
+		Variants: []models.Variant{
+			{Target: "https://example.com/a", Weight: 1},
+			{Target: "https://example.com/b", Weight: 3},
+		},
+	})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("landing")
+	require.NoError(t, err)
+	require.True(t, link.StickyVariants)
+	require.Len(t, link.Variants, 2)
+	a, b := link.Variants[0], link.Variants[1]
+
+	require.NoError(t, s.IncrementVariantClicks(b.Id))
+	require.NoError(t, s.SetVariantWeights(id, map[int64]int{a.Id: 5}))
+	require.ErrorIs(t, s.SetVariantWeights(id, map[int64]int{a.Id: 1, b.Id + 100: 1}), storage.ErrVariantNotFound)
+
+	link, err = s.GetLink("landing")
+	require.NoError(t, err)
+	require.Equal(t, []models.Variant{
+		{Id: a.Id, Target: a.Target, Weight: 5},
+		{Id: b.Id, Target: b.Target, Weight: 3, Clicks: 1},
+	}, link.Variants, "failed updates must not be applied partially")
+}
+
+func TestListedLinksCarryTargets(t *testing.T) {
+	s := newStorage(t)
+	rules := []models.Rule{{OS: []string{"ios"}, Target: "https://apps.apple.com/app/id1"}}
+
+	_, err := s.SaveURL(models.UrlShortener{
+		Alias:    "landing",
+		Url:      "https://example.com",
+		UserId:   1,
+		Rules:    rules,
+		Variants: []models.Variant{{Target: "https://example.com/a", Weight: 1}},
+	})
+	require.NoError(t, err)
+	want, err := s.GetLink("landing")
+	require.NoError(t, err)
+	require.Len(t, want.Variants, 1)
+
+	listed, err := s.ListAllURLs()
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, rules, listed[0].Rules)
+	require.Equal(t, want.Variants, listed[0].Variants)
+
+	var walked []models.UrlShortener
+	require.NoError(t, s.WalkURLs(1, func(link models.UrlShortener) error {
+		walked = append(walked, link)
+		return nil
+	}))
+	require.Len(t, walked, 1)
+	require.Equal(t, rules, walked[0].Rules)
+	require.Equal(t, want.Variants, walked[0].Variants)
+}
+
+func TestLinkMetadata(t *testing.T) {
+	s := newStorage(t)
+
+	id, err := s.SaveURL(models.UrlShortener{Alias: "docs", Url: "https://example.com", UserId: 1})
+	require.NoError(t, err)
+
+	links, err := s.ListURLs(1)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Nil(t, links[0].Metadata)
+
+	fetchedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveMetadata(id, models.Metadata{Title: "Old", FetchedAt: fetchedAt}))
+	meta := models.Metadata{
+		Title:      "Example",
+		ImageURL:   "https://example.com/cover.png",
+		FinalURL:   "https://example.com/",
+		StatusCode: 200,
+		FetchedAt:  fetchedAt.Add(time.Hour),
+	}
+	require.NoError(t, s.SaveMetadata(id, meta))
+
+	links, err = s.ListURLs(1)
+	require.NoError(t, err)
+	require.Equal(t, &meta, links[0].Metadata)
+}
+
+func TestLinkHealth(t *testing.T) {
+	s := newStorage(t)
+
+	id, err := s.SaveURL(models.UrlShortener{Alias: "docs", Url: "https://example.com", UserId: 1})
+	require.NoError(t, err)
+	_, err = s.SaveURL(models.UrlShortener{Alias: "blog", Url: "https://example.org", UserId: 1})
+	require.NoError(t, err)
+
+	checkedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	checks := []struct {
+		health   models.Health
+		failures int
+	}{
+		{models.Health{StatusCode: 500}, 1},
+		{models.Health{Error: "connection refused"}, 2},
+		{models.Health{StatusCode: 200}, 0},
+		{models.Health{StatusCode: 404}, 1},
+		{models.Health{StatusCode: 404, Latency: 120 * time.Millisecond}, 2},
+	}
+	for i, c := range checks {
+		c.health.CheckedAt = checkedAt.Add(time.Duration(i) * time.Hour)
+		failures, err := s.SaveHealth(id, c.health)
+		require.NoError(t, err)
+		require.Equal(t, c.failures, failures, "check %d", i+1)
+	}
+
+	broken, err := s.ListBrokenURLs(1, 2)
+	require.NoError(t, err)
+	require.Len(t, broken, 1)
+	require.Equal(t, "docs", broken[0].Alias)
+	require.Equal(t, &models.Health{
+		StatusCode: 404,
+		Latency:    120 * time.Millisecond,
+		Failures:   2,
+		CheckedAt:  checkedAt.Add(4 * time.Hour),
+	}, broken[0].Health)
+
+	broken, err = s.ListBrokenURLs(1, 3)
+	require.NoError(t, err)
+	require.Empty(t, broken)
+
+	link, err := s.GetLink("blog")
+	require.NoError(t, err)
+	require.Nil(t, link.Health)
+}
+
+func TestLinkExpiry(t *testing.T) {
+	s := newStorage(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	id, err := s.SaveURL(models.UrlShortener{Alias: "sale", Url: "https://example.com", UserId: 1, ExpiresAt: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = s.SaveURL(models.UrlShortener{Alias: "later", Url: "https://example.com", UserId: 1, ExpiresAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = s.SaveURL(models.UrlShortener{Alias: "forever", Url: "https://example.com", UserId: 1})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("sale")
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-time.Minute), link.ExpiresAt)
+	require.True(t, link.Expired(now))
+
+	expired, err := s.ListExpiredURLs(now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	require.Equal(t, "sale", expired[0].Alias)
+
+	require.NoError(t, s.MarkExpiryNotified(id))
+	expired, err = s.ListExpiredURLs(now)
+	require.NoError(t, err)
+	require.Empty(t, expired)
+
+	clicks, err := s.IncrementClicks("forever")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), clicks)
+	_, err = s.IncrementClicks("missing")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
@@ -8,14 +8,21 @@ import (
 	"url-shortener/internal/storage"
 )
 
+const userColumns = "id, email, password, disabled, role, email_verified, totp_secret, totp_enabled, totp_last_step"
+
+// SaveUser stores a new user, as a member unless user has a role.
 func (s *Storage) SaveUser(user models.User) (int64, error) {
-	stmt, err := s.db.Prepare("INSERT INTO users(email, password) VALUES (?, ?)")
+	stmt, err := s.db.Prepare("INSERT INTO users(email, password, disabled, role, email_verified) VALUES (?, ?, ?, ?, ?)")
 
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := stmt.Exec(user.Email, user.Password)
+	role := user.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	res, err := stmt.Exec(user.Email, user.Password, user.Disabled, role, user.EmailVerified)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
@@ -29,17 +36,90 @@ func (s *Storage) SaveUser(user models.User) (int64, error) {
 }
 
 func (s *Storage) GetUserByEmail(email string) (*models.User, error) {
-	stmt, err := s.db.Prepare("SELECT * FROM users WHERE email = ?")
+	stmt, err := s.db.Prepare("SELECT " + userColumns + " FROM users WHERE email = ?")
 	if err != nil {
 		return nil, err
 	}
-	var user models.User
-	err = stmt.QueryRow(email).Scan(&user.Id, &user.Email, &user.Password)
+	user, err := scanUser(stmt.QueryRow(email))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, storage.ErrUserNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	return user, nil
+}
+
+func (s *Storage) GetUserByID(id int64) (*models.User, error) {
+	stmt, err := s.db.Prepare("SELECT " + userColumns + " FROM users WHERE id = ?")
+	if err != nil {
+		return nil, err
+	}
+	user, err := scanUser(stmt.QueryRow(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers returns every user ordered by id.
+func (s *Storage) ListUsers() ([]models.User, error) {
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (s *Storage) SetUserRole(userId int64, role string) error {
+	return s.updateUser("UPDATE users SET role = ? WHERE id = ?", role, userId)
+}
+
+func (s *Storage) SetUserDisabled(userId int64, disabled bool) error {
+	return s.updateUser("UPDATE users SET disabled = ? WHERE id = ?", disabled, userId)
+}
+
+func (s *Storage) SetEmailVerified(userId int64) error {
+	return s.updateUser("UPDATE users SET email_verified = 1 WHERE id = ?", userId)
+}
+
+func (s *Storage) UpdatePassword(userId int64, password []byte) error {
+	return s.updateUser("UPDATE users SET password = ? WHERE id = ?", password, userId)
+}
+
+func (s *Storage) updateUser(query string, args ...any) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.Id, &user.Email, &user.Password, &user.Disabled, &user.Role, &user.EmailVerified,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.TOTPLastStep); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
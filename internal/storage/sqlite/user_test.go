@@ -0,0 +1,36 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestUserRoles(t *testing.T) {
+	s := newStorage(t)
+
+	memberId, err := s.SaveUser(models.User{Email: "member@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+	adminId, err := s.SaveUser(models.User{Email: "admin@example.com", Password: []byte("hash"), Role: models.RoleAdmin})
+	require.NoError(t, err)
+
+	user, err := s.GetUserByID(memberId)
+	require.NoError(t, err)
+	require.Equal(t, models.RoleMember, user.Role)
+
+	require.NoError(t, s.SetUserRole(memberId, models.RoleReadOnly))
+	require.NoError(t, s.SetUserDisabled(memberId, true))
+	user, err = s.GetUserByEmail("member@example.com")
+	require.NoError(t, err)
+	require.Equal(t, models.RoleReadOnly, user.Role)
+	require.True(t, user.Disabled)
+
+	users, err := s.ListUsers()
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, adminId, users[1].Id)
+	require.Equal(t, models.RoleAdmin, users[1].Role)
+
+	require.ErrorIs(t, s.SetUserRole(99, models.RoleAdmin), storage.ErrUserNotFound)
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"database/sql"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func saveVariants(tx *sql.Tx, urlId int64, variants []models.Variant) error {
+	for i, v := range variants {
+		_, err := tx.Exec("INSERT INTO link_variants (url_id, position, target, weight, clicks) VALUES (?, ?, ?, ?, ?)",
+			urlId, i, v.Target, v.Weight, v.Clicks)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) getVariants(urlId int64) ([]models.Variant, error) {
+	rows, err := s.db.Query("SELECT id, target, weight, clicks FROM link_variants WHERE url_id = ? ORDER BY position", urlId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []models.Variant
+	for rows.Next() {
+		var v models.Variant
+		if err := rows.Scan(&v.Id, &v.Target, &v.Weight, &v.Clicks); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// IncrementVariantClicks counts a redirect to the variant with id.
+func (s *Storage) IncrementVariantClicks(id int64) error {
+	_, err := s.db.Exec("UPDATE link_variants SET clicks = clicks + 1 WHERE id = ?", id)
+	return err
+}
+
+// SetVariantWeights changes the weights of variants of the link with urlId,
+// keyed by variant id, in one transaction. It returns
+// storage.ErrVariantNotFound when an id is not a variant of the link.
+func (s *Storage) SetVariantWeights(urlId int64, weights map[int64]int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, weight := range weights {
+		res, err := tx.Exec("UPDATE link_variants SET weight = ? WHERE id = ? AND url_id = ?", weight, id, urlId)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return storage.ErrVariantNotFound
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func (s *Storage) SaveWebhook(w models.Webhook) (int64, error) {
+	createdAt := w.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	res, err := s.db.Exec("INSERT INTO webhooks (user_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)",
+		w.UserId, w.URL, w.Secret, strings.Join(w.Events, ","), createdAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListWebhooks returns the webhooks of userId, secrets included.
+func (s *Storage) ListWebhooks(userId int64) ([]models.Webhook, error) {
+	rows, err := s.db.Query("SELECT id, user_id, url, secret, events, created_at FROM webhooks WHERE user_id = ? ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var w models.Webhook
+		var events string
+		if err := rows.Scan(&w.Id, &w.UserId, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		if events != "" {
+			w.Events = strings.Split(events, ",")
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes the webhook id of userId together with its pending
+// deliveries.
+func (s *Storage) DeleteWebhook(userId, id int64) error {
+	res, err := s.db.Exec("DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookEvent queues payload for every webhook of userId subscribed
+// to event, to be sent at or after at. It returns the number of deliveries.
+func (s *Storage) EnqueueWebhookEvent(userId int64, event string, payload []byte, at time.Time) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ? FROM webhooks
+		WHERE user_id = ? AND (events = '' OR instr(',' || events || ',', ',' || ? || ',') > 0)`,
+		event, string(payload), models.DeliveryPending, at.UTC(), at.UTC(), userId, event)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries whose next
+// attempt is due at now, oldest first.
+func (s *Storage) DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(`SELECT d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts,
+		d.next_attempt_at, d.last_error, d.created_at, d.delivered_at
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?`,
+		models.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.Id, &d.WebhookId, &d.URL, &d.Secret, &d.Event, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.DeliveredAt = deliveredAt.Time
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt.
+func (s *Storage) UpdateWebhookDelivery(d models.WebhookDelivery) error {
+	_, err := s.db.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?,
+		delivered_at = ? WHERE id = ?`,
+		d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.LastError, nullTime(d.DeliveredAt), d.Id)
+	return err
+}
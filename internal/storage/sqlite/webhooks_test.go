@@ -0,0 +1,73 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestWebhooks(t *testing.T) {
+	s := newStorage(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	all, err := s.SaveWebhook(models.Webhook{UserId: 1, URL: "https://cms.example.com/hook", Secret: "s1"})
+	require.NoError(t, err)
+	created, err := s.SaveWebhook(models.Webhook{UserId: 1, URL: "https://example.com/created", Secret: "s2",
+		Events: []string{"link.created", "link.updated"}})
+	require.NoError(t, err)
+	_, err = s.SaveWebhook(models.Webhook{UserId: 2, URL: "https://example.org/hook", Secret: "s3"})
+	require.NoError(t, err)
+
+	webhooks, err := s.ListWebhooks(1)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	require.Nil(t, webhooks[0].Events)
+	require.Equal(t, []string{"link.created", "link.updated"}, webhooks[1].Events)
+
+	n, err := s.EnqueueWebhookEvent(1, "link.created", []byte(`{"type":"link.created"}`), now)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	n, err = s.EnqueueWebhookEvent(1, "link.deleted", []byte(`{"type":"link.deleted"}`), now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	due, err := s.DueWebhookDeliveries(now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	require.Equal(t, "https://cms.example.com/hook", due[0].URL)
+	require.Equal(t, "s1", due[0].Secret)
+	require.Equal(t, `{"type":"link.created"}`, string(due[0].Payload))
+	require.Equal(t, models.DeliveryPending, due[0].Status)
+
+	delivered := due[0]
+	delivered.Status = models.DeliveryDelivered
+	delivered.Attempts = 1
+	delivered.DeliveredAt = now
+	require.NoError(t, s.UpdateWebhookDelivery(delivered))
+	retry := due[1]
+	retry.Attempts = 1
+	retry.LastError = "status 500"
+	retry.NextAttemptAt = now.Add(time.Hour)
+	require.NoError(t, s.UpdateWebhookDelivery(retry))
+
+	due, err = s.DueWebhookDeliveries(now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, "link.deleted", due[0].Event)
+	require.Equal(t, all, due[0].WebhookId)
+
+	due, err = s.DueWebhookDeliveries(now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	require.Equal(t, created, due[1].WebhookId)
+	require.Equal(t, 1, due[1].Attempts)
+	require.Equal(t, "status 500", due[1].LastError)
+
+	require.ErrorIs(t, s.DeleteWebhook(2, created), storage.ErrWebhookNotFound)
+	require.NoError(t, s.DeleteWebhook(1, created))
+	due, err = s.DueWebhookDeliveries(now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+}
@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"time"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// CreateWorkspace creates a workspace with ownerId as its first admin.
+func (s *Storage) CreateWorkspace(name string, ownerId int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	res, err := tx.Exec("INSERT INTO workspaces (name, created_at) VALUES (?, ?)", name, now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		id, ownerId, models.WorkspaceRoleAdmin, now)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ListWorkspaces returns the workspaces userId is a member of together with
+// the role of userId.
+func (s *Storage) ListWorkspaces(userId int64) ([]models.Workspace, error) {
+	rows, err := s.db.Query(`SELECT w.id, w.name, w.created_at, m.role FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = ? ORDER BY w.id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []models.Workspace
+	for rows.Next() {
+		var w models.Workspace
+		if err := rows.Scan(&w.Id, &w.Name, &w.CreatedAt, &w.Role); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, rows.Err()
+}
+
+// WorkspaceRole returns the role of userId in workspaceId.
+func (s *Storage) WorkspaceRole(workspaceId, userId int64) (string, error) {
+	var role string
+	err := s.db.QueryRow("SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?",
+		workspaceId, userId).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNotMember
+	}
+	return role, err
+}
+
+// ListMembers returns the members of workspaceId ordered by the time they
+// joined.
+func (s *Storage) ListMembers(workspaceId int64) ([]models.WorkspaceMember, error) {
+	rows, err := s.db.Query(`SELECT m.workspace_id, m.user_id, u.email, m.role, m.created_at FROM workspace_members m
+		JOIN users u ON u.id = m.user_id WHERE m.workspace_id = ? ORDER BY m.created_at, m.user_id`, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.WorkspaceMember
+	for rows.Next() {
+		var m models.WorkspaceMember
+		if err := rows.Scan(&m.WorkspaceId, &m.UserId, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// RemoveMember removes userId from workspaceId. The links userId created
+// stay in the workspace.
+func (s *Storage) RemoveMember(workspaceId, userId int64) error {
+	res, err := s.db.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceId, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotMember
+	}
+	return nil
+}
+
+// SaveInvite invites the email of invite to its workspace, replacing the
+// role of a pending invite for the same email.
+func (s *Storage) SaveInvite(invite models.WorkspaceInvite) error {
+	var member int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = ? AND u.email = ?`, invite.WorkspaceId, invite.Email).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if member > 0 {
+		return storage.ErrMemberExists
+	}
+	createdAt := invite.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err = s.db.Exec(`INSERT INTO workspace_invites (workspace_id, email, role, invited_by, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (workspace_id, email) DO UPDATE SET role = excluded.role, invited_by = excluded.invited_by, created_at = excluded.created_at`,
+		invite.WorkspaceId, invite.Email, invite.Role, invite.InvitedBy, createdAt.UTC())
+	return err
+}
+
+// ListInvites returns the pending invites for email.
+func (s *Storage) ListInvites(email string) ([]models.WorkspaceInvite, error) {
+	rows, err := s.db.Query(`SELECT workspace_id, email, role, invited_by, created_at FROM workspace_invites
+		WHERE email = ? ORDER BY created_at, workspace_id`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []models.WorkspaceInvite
+	for rows.Next() {
+		var i models.WorkspaceInvite
+		if err := rows.Scan(&i.WorkspaceId, &i.Email, &i.Role, &i.InvitedBy, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+// AcceptInvite makes userId a member of workspaceId with the role of the
+// invite for email and returns the role.
+func (s *Storage) AcceptInvite(workspaceId, userId int64, email string) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow("DELETE FROM workspace_invites WHERE workspace_id = ? AND email = ? RETURNING role",
+		workspaceId, email).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrInviteNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec("INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		workspaceId, userId, role, time.Now().UTC())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) {
+			return "", storage.ErrMemberExists
+		}
+		return "", err
+	}
+	return role, tx.Commit()
+}
@@ -0,0 +1,75 @@
+package sqlite_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestWorkspaces(t *testing.T) {
+	s := newStorage(t)
+
+	ownerId, err := s.SaveUser(models.User{Email: "owner@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+	memberId, err := s.SaveUser(models.User{Email: "member@example.com", Password: []byte("hash")})
+	require.NoError(t, err)
+
+	id, err := s.CreateWorkspace("Marketing", ownerId)
+	require.NoError(t, err)
+
+	role, err := s.WorkspaceRole(id, ownerId)
+	require.NoError(t, err)
+	require.Equal(t, models.WorkspaceRoleAdmin, role)
+	_, err = s.WorkspaceRole(id, memberId)
+	require.ErrorIs(t, err, storage.ErrNotMember)
+
+	invite := models.WorkspaceInvite{WorkspaceId: id, Email: "member@example.com", Role: models.WorkspaceRoleAdmin, InvitedBy: ownerId}
+	require.NoError(t, s.SaveInvite(invite))
+	invite.Role = models.WorkspaceRoleMember
+	require.NoError(t, s.SaveInvite(invite), "inviting again replaces the role")
+	require.ErrorIs(t, s.SaveInvite(models.WorkspaceInvite{WorkspaceId: id, Email: "owner@example.com",
+		Role: models.WorkspaceRoleMember, InvitedBy: ownerId}), storage.ErrMemberExists)
+
+	invites, err := s.ListInvites("member@example.com")
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	require.Equal(t, models.WorkspaceRoleMember, invites[0].Role)
+
+	_, err = s.AcceptInvite(id, ownerId, "owner@example.com")
+	require.ErrorIs(t, err, storage.ErrInviteNotFound)
+	role, err = s.AcceptInvite(id, memberId, "member@example.com")
+	require.NoError(t, err)
+	require.Equal(t, models.WorkspaceRoleMember, role)
+	_, err = s.AcceptInvite(id, memberId, "member@example.com")
+	require.ErrorIs(t, err, storage.ErrInviteNotFound, "invites are used up")
+
+	workspaces, err := s.ListWorkspaces(memberId)
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	require.Equal(t, "Marketing", workspaces[0].Name)
+	require.Equal(t, models.WorkspaceRoleMember, workspaces[0].Role)
+
+	members, err := s.ListMembers(id)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, "owner@example.com", members[0].Email)
+	require.Equal(t, "member@example.com", members[1].Email)
+
+	_, err = s.SaveURL(models.UrlShortener{Alias: "shared", Url: "https://example.com", UserId: memberId, WorkspaceId: id})
+	require.NoError(t, err)
+	_, err = s.SaveURL(models.UrlShortener{Alias: "personal", Url: "https://example.com", UserId: memberId})
+	require.NoError(t, err)
+
+	links, err := s.ListWorkspaceURLs(id)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, "shared", links[0].Alias)
+	require.Equal(t, id, links[0].WorkspaceId)
+
+	require.NoError(t, s.RemoveMember(id, memberId))
+	require.ErrorIs(t, s.RemoveMember(id, memberId), storage.ErrNotMember)
+	link, err := s.GetLink("shared")
+	require.NoError(t, err)
+	require.Equal(t, id, link.WorkspaceId, "links stay in the workspace")
+}
@@ -3,8 +3,19 @@ package storage
 import "errors"
 
 var (
-	ErrUrlNotFound  = errors.New("url not found")
-	ErrUrlExists    = errors.New("url already exists")
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
+	ErrUrlNotFound     = errors.New("url not found")
+	ErrUrlExists       = errors.New("url already exists")
+	ErrUserExists      = errors.New("user already exists")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrNotMember is returned for workspaces the user is not a member of,
+	// existing or not.
+	ErrNotMember      = errors.New("not a workspace member")
+	ErrMemberExists   = errors.New("already a workspace member")
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrTokenNotFound is returned for unknown, used and expired tokens.
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrRecoveryCodeNotFound is returned for unknown and used recovery codes.
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
 )
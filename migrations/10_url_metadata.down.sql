@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_url_delete_metadata;
+DROP TABLE IF EXISTS url_metadata;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS url_metadata (
+    url_id INTEGER PRIMARY KEY REFERENCES url(id),
+    page_title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    favicon_url TEXT NOT NULL DEFAULT '',
+    final_url TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    fetch_error TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP NOT NULL
+);
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_metadata AFTER DELETE ON url
+BEGIN
+    DELETE FROM url_metadata WHERE url_id = OLD.id;
+END;
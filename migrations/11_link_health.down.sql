@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_url_delete_health;
+DROP INDEX IF EXISTS idx_link_health_failures;
+DROP TABLE IF EXISTS link_health;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS link_health (
+    url_id INTEGER PRIMARY KEY REFERENCES url(id),
+    status_code INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    check_error TEXT NOT NULL DEFAULT '',
+    failures INTEGER NOT NULL DEFAULT 0,
+    checked_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_link_health_failures ON link_health(failures);
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_health AFTER DELETE ON url
+BEGIN
+    DELETE FROM link_health WHERE url_id = OLD.id;
+END;
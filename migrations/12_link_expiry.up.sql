@@ -0,0 +1,3 @@
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE url ADD COLUMN expiry_notified INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_url_expires_at ON url(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_url_workspace_id;
+ALTER TABLE url DROP COLUMN workspace_id;
+DROP TABLE IF EXISTS workspace_invites;
+DROP INDEX IF EXISTS idx_workspace_members_user;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
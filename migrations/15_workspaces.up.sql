@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    role TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members(user_id);
+CREATE TABLE IF NOT EXISTS workspace_invites (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id),
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    invited_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (workspace_id, email)
+);
+ALTER TABLE url ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id);
+CREATE INDEX IF NOT EXISTS idx_url_workspace_id ON url(workspace_id) WHERE workspace_id IS NOT NULL;
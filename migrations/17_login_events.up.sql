@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id),
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    result TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_login_events_email ON login_events(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_ip ON login_events(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at);
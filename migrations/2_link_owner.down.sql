@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_url_user_id;
+ALTER TABLE url DROP COLUMN clicks;
+ALTER TABLE url DROP COLUMN created_at;
+ALTER TABLE url DROP COLUMN user_id;
@@ -0,0 +1,5 @@
+ALTER TABLE url ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE url ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE url ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0;
+UPDATE url SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_url_user_id ON url(user_id);
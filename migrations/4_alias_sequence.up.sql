@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS alias_sequence (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+);
+INSERT INTO alias_sequence (id, value) SELECT 1, COALESCE(MAX(id), 0) FROM url;
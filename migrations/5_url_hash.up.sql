@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN url_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_url_user_hash ON url(user_id, url_hash);
@@ -0,0 +1,6 @@
+ALTER TABLE url DROP COLUMN forward_query;
+ALTER TABLE url DROP COLUMN utm_content;
+ALTER TABLE url DROP COLUMN utm_term;
+ALTER TABLE url DROP COLUMN utm_campaign;
+ALTER TABLE url DROP COLUMN utm_medium;
+ALTER TABLE url DROP COLUMN utm_source;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_url_delete_rules;
+DROP TABLE IF EXISTS link_rules;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS link_rules (
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id),
+    position INTEGER NOT NULL,
+    conditions TEXT NOT NULL,
+    target TEXT NOT NULL,
+    UNIQUE (url_id, position)
+);
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_rules AFTER DELETE ON url
+BEGIN
+    DELETE FROM link_rules WHERE url_id = OLD.id;
+END;
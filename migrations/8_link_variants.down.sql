@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_url_delete_variants;
+DROP TABLE IF EXISTS link_variants;
+ALTER TABLE url DROP COLUMN sticky_variants;
@@ -0,0 +1,14 @@
+ALTER TABLE url ADD COLUMN sticky_variants INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS link_variants (
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id),
+    position INTEGER NOT NULL,
+    target TEXT NOT NULL,
+    weight INTEGER NOT NULL,
+    clicks INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (url_id, position)
+);
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_variants AFTER DELETE ON url
+BEGIN
+    DELETE FROM link_variants WHERE url_id = OLD.id;
+END;
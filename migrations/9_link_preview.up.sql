@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN preview INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,229 @@
+// Package client is a Go client for the URL shortener HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"url-shortener/internal/http-server/handlers/auth"
+	"url-shortener/internal/http-server/handlers/url"
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// ErrTwoFactorRequired is returned by Login for accounts with 2FA, which
+// the client does not support. Log in with POST /login/2fa and use
+// WithToken instead.
+var ErrTwoFactorRequired = errors.New("two-factor authentication required")
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	token    string
+	email    string
+	password string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.httpClient = c
+	}
+}
+
+// WithToken starts the client with a previously issued token.
+func WithToken(token string) Option {
+	return func(cl *Client) {
+		cl.token = token
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the token currently used for authenticated requests.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken replaces the token used for authenticated requests.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Register creates a user and stores the returned token.
+func (c *Client) Register(ctx context.Context, email, password string) error {
+	return c.authenticate(ctx, "/register", email, password)
+}
+
+// Login stores the returned token together with the credentials, which are
+// used to log in again when the token is rejected.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	return c.authenticate(ctx, "/login", email, password)
+}
+
+// Shorten saves target under alias, or under a generated alias when alias is
+// empty, and returns the alias.
+func (c *Client) Shorten(ctx context.Context, target, alias string) (string, error) {
+	var out url.Response
+	err := c.doAuthorized(ctx, http.MethodPost, "/url", url.Request{URL: target, Alias: alias}, &out)
+	if err != nil {
+		return "", err
+	}
+	return out.Alias, nil
+}
+
+// Resolve returns the URL behind alias without following the redirect.
+func (c *Client) Resolve(ctx context.Context, alias string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/"+neturl.PathEscape(alias), nil)
+	if err != nil {
+		return "", err
+	}
+	noRedirect := *c.httpClient
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	res, err := noRedirect.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 && res.StatusCode < 400 {
+		return res.Header.Get("Location"), nil
+	}
+	return "", decodeError(res)
+}
+
+// List returns the links owned by the logged in user.
+func (c *Client) List(ctx context.Context) ([]url.Link, error) {
+	var out url.ListResponse
+	if err := c.doAuthorized(ctx, http.MethodGet, "/url", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Links, nil
+}
+
+// Stats returns the statistics of a link owned by the logged in user.
+func (c *Client) Stats(ctx context.Context, alias string) (*url.Link, error) {
+	var out url.StatsResponse
+	if err := c.doAuthorized(ctx, http.MethodGet, "/url/"+neturl.PathEscape(alias)+"/stats", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Link, nil
+}
+
+// Delete removes alias.
+func (c *Client) Delete(ctx context.Context, alias string) error {
+	return c.doAuthorized(ctx, http.MethodDelete, "/"+neturl.PathEscape(alias), nil, nil)
+}
+
+func (c *Client) authenticate(ctx context.Context, path, email, password string) error {
+	var out auth.Response
+	if err := c.do(ctx, http.MethodPost, path, "", auth.Request{Email: email, Password: password}, &out); err != nil {
+		return err
+	}
+	if out.Token == "" && out.ChallengeToken != "" {
+		return ErrTwoFactorRequired
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = out.Token
+	c.email = email
+	c.password = password
+	return nil
+}
+
+// doAuthorized sends an authenticated request. A 401 response triggers one
+// login with the stored credentials and a retry.
+func (c *Client) doAuthorized(ctx context.Context, method, path string, in, out any) error {
+	err := c.do(ctx, method, path, c.Token(), in, out)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+
+	c.mu.Lock()
+	email, password := c.email, c.password
+	c.mu.Unlock()
+	if email == "" {
+		return err
+	}
+	if err := c.Login(ctx, email, password); err != nil {
+		return fmt.Errorf("refresh token: %w", err)
+	}
+	return c.do(ctx, method, path, c.Token(), in, out)
+}
+
+func (c *Client) do(ctx context.Context, method, path, token string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return decodeError(res)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+func decodeError(res *http.Response) error {
+	var out resp.Response
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil || out.Error == "" {
+		return newAPIError(res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	return newAPIError(res.StatusCode, out.Error)
+}
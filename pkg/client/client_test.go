@@ -0,0 +1,105 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"url-shortener/internal/config"
+	http_server "url-shortener/internal/http-server"
+	custom_mocks "url-shortener/internal/lib/custom-mocks"
+	"url-shortener/internal/storage/sqlite"
+	"url-shortener/pkg/client"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, sqlite.Migrate(storagePath, "migrations"))
+
+	repo, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	cfg := &config.Config{JwtSecret: "test-secret"}
+	handler, err := http_server.New(slog.New(custom_mocks.NewMockLogger()), cfg, repo)
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	c := client.New(srv.URL)
+
+	require.NoError(t, c.Register(ctx, "user@example.com", "correct horse"))
+	require.NotEmpty(t, c.Token())
+	require.ErrorIs(t, c.Register(ctx, "user@example.com", "correct horse"), client.ErrUserExists)
+
+	alias, err := c.Shorten(ctx, "https://example.com/page", "example")
+	require.NoError(t, err)
+	require.Equal(t, "example", alias)
+
+	_, err = c.Shorten(ctx, "https://example.com/other", "example")
+	require.ErrorIs(t, err, client.ErrUrlExists)
+
+	generated, err := c.Shorten(ctx, "https://example.com/generated", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, generated)
+
+	target, err := c.Resolve(ctx, "example")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/page", target)
+
+	links, err := c.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	stats, err := c.Stats(ctx, "example")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Clicks)
+
+	require.NoError(t, c.Delete(ctx, "example"))
+	require.ErrorIs(t, c.Delete(ctx, "example"), client.ErrUrlNotFound)
+
+	_, err = c.Resolve(ctx, "example")
+	require.ErrorIs(t, err, client.ErrUrlNotFound)
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+func TestClientBadCredentials(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.New(srv.URL).Register(ctx, "user@example.com", "correct horse"))
+
+	err := client.New(srv.URL).Login(ctx, "user@example.com", "wrong")
+	require.ErrorIs(t, err, client.ErrBadCredentials)
+}
+
+func TestClientRefreshesToken(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.New(srv.URL).Register(ctx, "user@example.com", "correct horse"))
+
+	c := client.New(srv.URL)
+	require.NoError(t, c.Login(ctx, "user@example.com", "correct horse"))
+	c.SetToken("expired")
+
+	_, err := c.Shorten(ctx, "https://example.com", "refreshed")
+	require.NoError(t, err)
+	require.NotEqual(t, "expired", c.Token())
+
+	_, err = client.New(srv.URL, client.WithToken("expired")).Shorten(ctx, "https://example.com", "other")
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+}
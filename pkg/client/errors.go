@@ -0,0 +1,49 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"url-shortener/internal/storage"
+)
+
+var (
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrBadCredentials  = errors.New("bad credentials")
+	ErrAccountDisabled = errors.New("account disabled")
+	// ErrUrlExists, ErrUrlNotFound and ErrUserExists are the errors of the
+	// storage, so server and client code can check for the same values.
+	ErrUrlExists   = storage.ErrUrlExists
+	ErrUrlNotFound = storage.ErrUrlNotFound
+	ErrUserExists  = storage.ErrUserExists
+)
+
+// knownErrors maps the error messages returned by the API to typed errors.
+var knownErrors = map[string]error{
+	"url already exists":  ErrUrlExists,
+	"url not found":       ErrUrlNotFound,
+	"user already exists": ErrUserExists,
+	"bad credentials":     ErrBadCredentials,
+	"unauthorized":        ErrUnauthorized,
+	"account disabled":    ErrAccountDisabled,
+}
+
+// APIError is returned for every non-successful response. It unwraps to one of
+// the typed errors above when the message is known, so callers can use
+// errors.Is(err, client.ErrUrlExists).
+type APIError struct {
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func newAPIError(status int, msg string) *APIError {
+	return &APIError{StatusCode: status, Message: msg, Err: knownErrors[msg]}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}